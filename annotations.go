@@ -1,24 +1,30 @@
 package devstats
 
 import (
+	"database/sql"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Annotations contain list of annotations
 type Annotations struct {
-	Annotations []Annotation
+	Annotations []Annotation `yaml:"annotations"`
 }
 
 // Annotation contain each annotation data
+// EndDate is optional, only custom annotations from annotations.yaml can have it (for example conferences)
 type Annotation struct {
-	Name        string
-	Description string
-	Date        time.Time
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Date        time.Time  `yaml:"date"`
+	EndDate     *time.Time `yaml:"end_date"`
 }
 
 // AnnotationsByDate annotations Sort interface
@@ -73,90 +79,167 @@ func GetAnnotations(ctx *Ctx, orgRepo, annoRegexp string) (annotations Annotatio
 		re = regexp.MustCompile(annoRegexp)
 	}
 
-	// Local or cron mode?
-	cmdPrefix := ""
-	if ctx.Local {
-		cmdPrefix = LocalGitScripts
-	}
-
-	// We need this to capture 'git_tags.sh' output.
-	ctx.ExecOutput = true
-
-	// Get tags is using shell script that does 'chdir'
-	// We cannot chdir because this is a multithreaded app
-	// And all threads share CWD (current working directory)
 	if ctx.Debug > 0 {
 		Printf("Getting tags for repo %s\n", orgRepo)
 	}
 	dtStart := time.Now()
 	rwd := ctx.ReposDir + orgRepo
-	tagsStr, err := ExecCommand(
-		ctx,
-		[]string{cmdPrefix + "git_tags.sh", rwd},
-		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-	)
+	tags, err := GitTags(ctx, rwd)
 	dtEnd := time.Now()
 	FatalOnError(err)
 
-	tags := strings.Split(tagsStr, "\n")
 	nTags := 0
-
-	for _, tagData := range tags {
-		data := strings.TrimSpace(tagData)
-		if data == "" {
+	for _, tag := range tags {
+		if re != nil && !re.MatchString(tag.Name) {
 			continue
 		}
-		// Use '♂♀' separator to avoid any character that can appear inside tag name or description
-		tagDataAry := strings.Split(data, "♂♀")
-		if len(tagDataAry) != 3 {
-			Fatalf("invalid tagData returned for repo: %s: '%s'", orgRepo, data)
-		}
-		tagName := tagDataAry[0]
+		annotations.Annotations = append(
+			annotations.Annotations,
+			Annotation{
+				Name:        tag.Name,
+				Description: annotationMessage(tag.Subject),
+				Date:        tag.Date,
+			},
+		)
+		nTags++
+	}
+
+	if ctx.Debug > 0 {
+		Printf("Got %d tags for %s, took %v\n", nTags, orgRepo, dtEnd.Sub(dtStart))
+	}
+
+	return
+}
+
+// annotationMessage - returns tag/release message shortened to 40 characters with no new lines
+func annotationMessage(message string) string {
+	if len(message) > 40 {
+		message = message[0:40]
+	}
+	return strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(message)
+}
+
+// GetRepoAnnotations - returns tags matching `annoRegexp` from all given repos
+// Tags from the first repo (main repo) are returned first
+func GetRepoAnnotations(ctx *Ctx, orgRepos []string, annoRegexp string) (annotations Annotations) {
+	for _, orgRepo := range orgRepos {
+		repoAnnotations := GetAnnotations(ctx, orgRepo, annoRegexp)
+		annotations.Annotations = append(annotations.Annotations, repoAnnotations.Annotations...)
+	}
+	return
+}
+
+// GetReleasesAnnotations - returns GitHub releases of given repos stored in `gha_releases` with tag names matching `annoRegexp`
+// Draft releases are skipped, release date is its publish date (or create date when not published)
+// Release is returned once even if multiple events refer to it, using its earliest date
+func GetReleasesAnnotations(ctx *Ctx, con *sql.DB, orgRepos []string, annoRegexp string) (annotations Annotations) {
+	if len(orgRepos) == 0 {
+		return
+	}
+
+	// Compile annotation regexp if present, if no regexp then return all releases
+	var re *regexp.Regexp
+	if annoRegexp != "" {
+		re = regexp.MustCompile(annoRegexp)
+	}
+
+	dtStart := time.Now()
+	args := []interface{}{}
+	params := []string{}
+	for i, orgRepo := range orgRepos {
+		args = append(args, orgRepo)
+		params = append(params, NValue(i+1))
+	}
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select distinct on (tag_name) tag_name, coalesce(name, ''), coalesce(published_at, created_at) "+
+			"from gha_releases where not draft and dup_repo_name in ("+strings.Join(params, ", ")+") "+
+			"order by tag_name, coalesce(published_at, created_at)",
+		args...,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		tagName string
+		name    string
+		dt      time.Time
+	)
+	nReleases := 0
+	for rows.Next() {
+		FatalOnError(rows.Scan(&tagName, &name, &dt))
 		if re != nil && !re.MatchString(tagName) {
 			continue
 		}
-		unixTimeStamp, err := strconv.ParseInt(tagDataAry[1], 10, 64)
-		if err != nil {
-			Printf("Invalid time returned for repo: %s, tag: %s: '%s'\n", orgRepo, tagName, data)
-		}
-		FatalOnError(err)
-		creatorDate := time.Unix(unixTimeStamp, 0)
-		message := tagDataAry[2]
-		if len(message) > 40 {
-			message = message[0:40]
+		if name == "" {
+			name = tagName
 		}
-		replacer := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ")
-		message = replacer.Replace(message)
-
 		annotations.Annotations = append(
 			annotations.Annotations,
 			Annotation{
 				Name:        tagName,
-				Description: message,
-				Date:        creatorDate,
+				Description: annotationMessage(name),
+				Date:        dt,
 			},
 		)
-		nTags++
+		nReleases++
 	}
+	FatalOnError(rows.Err())
 
 	if ctx.Debug > 0 {
-		Printf("Got %d tags for %s, took %v\n", nTags, orgRepo, dtEnd.Sub(dtStart))
+		Printf("Got %d releases for %v, took %v\n", nReleases, orgRepos, time.Now().Sub(dtStart))
 	}
+	return
+}
 
+// GetYamlAnnotations - returns custom annotations from `path` YAML file, no annotations if file doesn't exist
+// Unlike other YAML files there is no fallback to "metrics/shared/", those events are project specific
+func GetYamlAnnotations(ctx *Ctx, path string) (annotations Annotations) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if ctx.Debug > 0 {
+			Printf("No custom annotations file %s\n", path)
+		}
+		return
+	}
+	FatalOnError(err)
+	FatalOnError(yaml.Unmarshal(data, &annotations))
+	for _, annotation := range annotations.Annotations {
+		if annotation.Name == "" || annotation.Date.IsZero() {
+			Fatalf("%s: annotation must have name and date: %+v", path, annotation)
+		}
+		if annotation.EndDate != nil && !annotation.EndDate.After(annotation.Date) {
+			Fatalf("%s: annotation '%s' end date must be after its date", path, annotation.Name)
+		}
+	}
+	if ctx.Debug > 0 {
+		Printf("Got %d custom annotations from %s\n", len(annotations.Annotations), path)
+	}
 	return
 }
 
-// ProcessAnnotations Creates IfluxDB annotations and quick_series
-func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate *time.Time) {
-	// Connect to InfluxDB
-	ic := IDBConn(ctx)
-	defer func() { FatalOnError(ic.Close()) }()
+// MergeAnnotations - merges annotations from multiple sources and removes duplicates
+// Annotations with the same name are the same event (for example git tag and GitHub release),
+// the first one is used, so sources should be passed in priority order
+func MergeAnnotations(sources ...Annotations) (annotations Annotations) {
+	names := make(map[string]struct{})
+	for _, source := range sources {
+		for _, annotation := range source.Annotations {
+			if _, ok := names[annotation.Name]; ok {
+				continue
+			}
+			names[annotation.Name] = struct{}{}
+			annotations.Annotations = append(annotations.Annotations, annotation)
+		}
+	}
+	return
+}
 
-	// Get BatchPoints
-	var pts IDBBatchPointsN
-	bp := IDBBatchPoints(ctx, &ic)
-	pts.NPoints = 0
-	pts.Points = &bp
+// ProcessAnnotations Creates annotations and quick_series
+// Quick ranges are given by `quickRanges` (DefaultQuickRanges when nil), `startDate` and `joinDate` are used by ranges referring to them
+func ProcessAnnotations(ctx *Ctx, annotations *Annotations, quickRanges []QuickRange, startDate, joinDate *time.Time) {
+	// Connect to time series outputs
+	sink := NewSeriesSink(ctx)
+	defer sink.Close()
 
 	// Annotations must be sorted to create quick ranges
 	sort.Sort(AnnotationsByDate(annotations.Annotations))
@@ -167,6 +250,9 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 			"title":       annotation.Name,
 			"description": annotation.Description,
 		}
+		if annotation.EndDate != nil {
+			fields["time_end"] = ToYMDHMSDate(*annotation.EndDate)
+		}
 		// Add batch point
 		if ctx.Debug > 0 {
 			Printf(
@@ -177,8 +263,7 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 				annotation.Description,
 			)
 		}
-		pt := IDBNewPointWithErr(ctx, "annotations", nil, fields, annotation.Date)
-		IDBAddPointN(ctx, &ic, &pts, pt)
+		sink.AddPoint("annotations", nil, fields, annotation.Date)
 	}
 
 	// If both start and join dates are present then join date must be after start date
@@ -198,8 +283,7 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 					fields["description"],
 				)
 			}
-			pt := IDBNewPointWithErr(ctx, "annotations", nil, fields, *startDate)
-			IDBAddPointN(ctx, &ic, &pts, pt)
+			sink.AddPoint("annotations", nil, fields, *startDate)
 		}
 
 		// Join CNCF (additional annotation not used in quick ranges)
@@ -217,42 +301,34 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 					fields["description"],
 				)
 			}
-			pt := IDBNewPointWithErr(ctx, "annotations", nil, fields, *joinDate)
-			IDBAddPointN(ctx, &ic, &pts, pt)
+			sink.AddPoint("annotations", nil, fields, *joinDate)
 		}
 	}
 
-	// Special ranges
-	periods := [][3]string{
-		{"d", "Last day", "1 day"},
-		{"w", "Last week", "1 week"},
-		{"d10", "Last 10 days", "10 days"},
-		{"m", "Last month", "1 month"},
-		{"q", "Last quarter", "3 months"},
-		{"y", "Last year", "1 year"},
-		{"y10", "Last decade", "10 years"},
+	// Quick ranges
+	if quickRanges == nil {
+		quickRanges = DefaultQuickRanges
 	}
 
 	// tags:
 	// suffix: will be used as InfluxDB series name suffix and Grafana drop-down value (non-dsplayed)
 	// name: will be used as Grafana drop-down value name
 	// data: is suffix;period;from;to
-	// period: only for special values listed here, last ... week, day, quarter, devade etc - will be passed to Postgres
-	// from: only filled when using annotations range - exact date from
-	// to: only filled when using annotations range - exact date to
+	// period: only for "last ..." ranges, last ... week, day, quarter, devade etc - will be passed to Postgres
+	// from: only filled when using dates range (annotations, calendar aligned, fixed dates) - exact date from
+	// to: only filled when using dates range (annotations, calendar aligned, fixed dates) - exact date to
 	tags := make(map[string]string)
 	// No fields value needed
 	fields := map[string]interface{}{"value": 0.0}
-
-	// Add special periods
 	tagName := "quick_ranges"
 	tm := TimeParseAny("2014-01-01")
+	now := time.Now()
 
-	// Last "..." periods
-	for _, period := range periods {
-		tags[tagName+"_suffix"] = period[0]
-		tags[tagName+"_name"] = period[1]
-		tags[tagName+"_data"] = period[0] + ";" + period[2] + ";;"
+	// Add single quick range
+	addRange := func(sfx, name, data string) {
+		tags[tagName+"_suffix"] = sfx
+		tags[tagName+"_name"] = name
+		tags[tagName+"_data"] = data
 		if ctx.Debug > 0 {
 			Printf(
 				"Series: %v: %+v\n",
@@ -261,92 +337,81 @@ func ProcessAnnotations(ctx *Ctx, annotations *Annotations, startDate, joinDate
 			)
 		}
 		// Add batch point
-		pt := IDBNewPointWithErr(ctx, tagName, tags, fields, tm)
-		IDBAddPointN(ctx, &ic, &pts, pt)
+		sink.AddPoint(tagName, tags, fields, tm)
 		tm = tm.Add(time.Hour)
 	}
 
-	// Add '(i) - (i+1)' annotation ranges
-	lastIndex := len(annotations.Annotations) - 1
-	for index, annotation := range annotations.Annotations {
-		if index == lastIndex {
-			sfx := fmt.Sprintf("anno_%d_now", index)
-			tags[tagName+"_suffix"] = sfx
-			tags[tagName+"_name"] = fmt.Sprintf("%s - now", annotation.Name)
-			tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(NextDayStart(time.Now())))
-			if ctx.Debug > 0 {
-				Printf(
-					"Series: %v: %+v\n",
-					tagName,
-					tags,
+	// Annotations with end date (events like conferences) have their own ranges, other annotations are points
+	points := []Annotation{}
+	for _, annotation := range annotations.Annotations {
+		if annotation.EndDate == nil {
+			points = append(points, annotation)
+		}
+	}
+
+	// Add annotations ranges
+	addAnnotationsRanges := func() {
+		// Add '(i) - (i+1)' annotation ranges
+		lastIndex := len(points) - 1
+		for index, annotation := range points {
+			if index == lastIndex {
+				sfx := fmt.Sprintf("anno_%d_now", index)
+				addRange(
+					sfx,
+					fmt.Sprintf("%s - now", annotation.Name),
+					fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(NextDayStart(now))),
 				)
+				break
 			}
-			// Add batch point
-			pt := IDBNewPointWithErr(ctx, tagName, tags, fields, tm)
-			IDBAddPointN(ctx, &ic, &pts, pt)
-			tm = tm.Add(time.Hour)
-			break
-		}
-		nextAnnotation := annotations.Annotations[index+1]
-		sfx := fmt.Sprintf("anno_%d_%d", index, index+1)
-		tags[tagName+"_suffix"] = sfx
-		tags[tagName+"_name"] = fmt.Sprintf("%s - %s", annotation.Name, nextAnnotation.Name)
-		tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(nextAnnotation.Date))
-		if ctx.Debug > 0 {
-			Printf(
-				"Series: %v: %+v\n",
-				tagName,
-				tags,
+			nextAnnotation := points[index+1]
+			sfx := fmt.Sprintf("anno_%d_%d", index, index+1)
+			addRange(
+				sfx,
+				fmt.Sprintf("%s - %s", annotation.Name, nextAnnotation.Name),
+				fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(nextAnnotation.Date)),
 			)
 		}
-		// Add batch point
-		pt := IDBNewPointWithErr(ctx, tagName, tags, fields, tm)
-		IDBAddPointN(ctx, &ic, &pts, pt)
-		tm = tm.Add(time.Hour)
-	}
 
-	// 2 special periods: before and after joining CNCF
-	if startDate != nil && joinDate != nil && joinDate.After(*startDate) {
-		// From project start to CNCF join date
-		sfx := "cncf_before"
-		tags[tagName+"_suffix"] = sfx
-		tags[tagName+"_name"] = "Before joining CNCF"
-		tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(*startDate), ToYMDHMSDate(*joinDate))
-		if ctx.Debug > 0 {
-			Printf(
-				"Series: %v: %+v\n",
-				tagName,
-				tags,
+		// Add annotations with end date ranges
+		for index, annotation := range annotations.Annotations {
+			if annotation.EndDate == nil {
+				continue
+			}
+			sfx := fmt.Sprintf("anno_event_%d", index)
+			addRange(
+				sfx,
+				annotation.Name,
+				fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(annotation.Date), ToYMDHMSDate(*annotation.EndDate)),
 			)
 		}
-		// Add batch point
-		pt := IDBNewPointWithErr(ctx, tagName, tags, fields, tm)
-		IDBAddPointN(ctx, &ic, &pts, pt)
-		tm = tm.Add(time.Hour)
+	}
 
-		// From CNCF join date till now
-		sfx = "cncf_now"
-		tags[tagName+"_suffix"] = sfx
-		tags[tagName+"_name"] = "Since joining CNCF"
-		tags[tagName+"_data"] = fmt.Sprintf("%s;;%s;%s", sfx, ToYMDHMSDate(*joinDate), ToYMDHMSDate(NextDayStart(time.Now())))
-		if ctx.Debug > 0 {
-			Printf(
-				"Series: %v: %+v\n",
-				tagName,
-				tags,
-			)
+	// Configured quick ranges, ranges that cannot be computed (like "Before joining CNCF" without join date) are skipped
+	annotationsAdded := false
+	for _, qr := range quickRanges {
+		if qr.Annotations {
+			addAnnotationsRanges()
+			annotationsAdded = true
+			continue
 		}
-		// Add batch point
-		pt = IDBNewPointWithErr(ctx, tagName, tags, fields, tm)
-		IDBAddPointN(ctx, &ic, &pts, pt)
-		tm = tm.Add(time.Hour)
+		data, ok := qr.Data(startDate, joinDate, now)
+		if !ok {
+			if ctx.Debug > 0 {
+				Printf("Skipping quick range %s: %s\n", qr.Suffix, qr.Name)
+			}
+			continue
+		}
+		addRange(qr.Suffix, qr.Name, data)
+	}
+	if !annotationsAdded {
+		addAnnotationsRanges()
 	}
 
 	// Write the batch
 	if !ctx.SkipIDB {
 		//if ctx.IDBDrop
-		QueryIDB(ic, ctx, `delete from "quick_ranges" where quick_ranges_suffix =~ /_now$/`)
-		FatalOnError(IDBWritePointsN(ctx, &ic, &pts))
+		sink.DeleteSeries("quick_ranges", map[string]string{"quick_ranges_suffix": "_now"})
+		FatalOnError(sink.Write())
 	} else if ctx.Debug > 0 {
 		Printf("Skipping annotations series write\n")
 	}
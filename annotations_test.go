@@ -3,6 +3,8 @@ package devstats
 import (
 	lib "devstats"
 	testlib "devstats/test"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -69,3 +71,88 @@ func TestGetFakeAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeAnnotations(t *testing.T) {
+	// Example data
+	ft := testlib.YMDHMS
+	anno := func(name, desc string, dt time.Time) lib.Annotation {
+		return lib.Annotation{Name: name, Description: desc, Date: dt}
+	}
+	custom := lib.Annotations{Annotations: []lib.Annotation{anno("v1.0", "Custom 1.0", ft(2017, 1)), anno("Graduated", "Graduation", ft(2018, 3))}}
+	tags := lib.Annotations{Annotations: []lib.Annotation{anno("v1.0", "Tag 1.0", ft(2017, 1, 2)), anno("v1.1", "Tag 1.1", ft(2017, 6))}}
+	releases := lib.Annotations{Annotations: []lib.Annotation{anno("v1.1", "Release 1.1", ft(2017, 6, 3)), anno("v1.2", "Release 1.2", ft(2018))}}
+
+	// Test cases
+	var testCases = []struct {
+		sources  []lib.Annotations
+		expected []lib.Annotation
+	}{
+		{sources: []lib.Annotations{}, expected: nil},
+		{sources: []lib.Annotations{{}, tags}, expected: tags.Annotations},
+		{
+			sources: []lib.Annotations{tags, releases},
+			expected: []lib.Annotation{
+				anno("v1.0", "Tag 1.0", ft(2017, 1, 2)),
+				anno("v1.1", "Tag 1.1", ft(2017, 6)),
+				anno("v1.2", "Release 1.2", ft(2018)),
+			},
+		},
+		{
+			sources: []lib.Annotations{custom, tags, releases},
+			expected: []lib.Annotation{
+				anno("v1.0", "Custom 1.0", ft(2017, 1)),
+				anno("Graduated", "Graduation", ft(2018, 3)),
+				anno("v1.1", "Tag 1.1", ft(2017, 6)),
+				anno("v1.2", "Release 1.2", ft(2018)),
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.MergeAnnotations(test.sources...)
+		if !reflect.DeepEqual(test.expected, got.Annotations) {
+			t.Errorf("test number %d, expected:\n%+v\n%+v\n got", index+1, test.expected, got.Annotations)
+		}
+	}
+}
+
+func TestGetYamlAnnotations(t *testing.T) {
+	var ctx lib.Ctx
+	ft := testlib.YMDHMS
+
+	// Missing file means no custom annotations
+	got := lib.GetYamlAnnotations(&ctx, "/nonexistent/annotations.yaml")
+	if len(got.Annotations) > 0 {
+		t.Errorf("expected no annotations, got %+v", got)
+	}
+
+	// Annotations with and without end date
+	file, err := ioutil.TempFile("", "annotations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	data := "annotations:\n" +
+		"  - name: Graduated\n" +
+		"    description: Project graduated\n" +
+		"    date: 2018-03-06\n" +
+		"  - name: KubeCon EU 2018\n" +
+		"    description: Copenhagen\n" +
+		"    date: 2018-05-02T00:00:00Z\n" +
+		"    end_date: 2018-05-05T00:00:00Z\n"
+	if _, err = file.WriteString(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	endDate := ft(2018, 5, 5)
+	expected := []lib.Annotation{
+		{Name: "Graduated", Description: "Project graduated", Date: ft(2018, 3, 6)},
+		{Name: "KubeCon EU 2018", Description: "Copenhagen", Date: ft(2018, 5, 2), EndDate: &endDate},
+	}
+	got = lib.GetYamlAnnotations(&ctx, file.Name())
+	if !reflect.DeepEqual(expected, got.Annotations) {
+		t.Errorf("expected:\n%+v\n%+v\n got", expected, got.Annotations)
+	}
+}
@@ -0,0 +1,77 @@
+package devstats
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetBackfillCursor - returns last ID processed by `source` backfill, saved in `gha_backfill_cursors` table
+// Returns 0 when backfill was never started or its last pass was completed (so the next pass starts from the beginning)
+func GetBackfillCursor(con *sql.DB, ctx *Ctx, source string) int64 {
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select last_id, completed_at from gha_backfill_cursors where source = $1",
+		source,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		lastID      int64
+		completedAt *time.Time
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&lastID, &completedAt))
+	}
+	FatalOnError(rows.Err())
+	if completedAt != nil {
+		return 0
+	}
+	return lastID
+}
+
+// SaveBackfillCursor - saves last ID processed by `source` backfill, next run continues after it
+func SaveBackfillCursor(con *sql.DB, ctx *Ctx, source string, lastID int64) {
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_backfill_cursors(source, last_id, updated_at, completed_at) "+NValues(4)+
+			" on conflict(source) do update set last_id = excluded.last_id, "+
+			"updated_at = excluded.updated_at, completed_at = excluded.completed_at",
+		source,
+		lastID,
+		time.Now(),
+		nil,
+	)
+}
+
+// CompleteBackfill - marks `source` backfill pass as completed, next run starts a new pass from the beginning
+func CompleteBackfill(con *sql.DB, ctx *Ctx, source string, lastID int64) {
+	now := time.Now()
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_backfill_cursors(source, last_id, updated_at, completed_at) "+NValues(4)+
+			" on conflict(source) do update set last_id = excluded.last_id, "+
+			"updated_at = excluded.updated_at, completed_at = excluded.completed_at",
+		source,
+		lastID,
+		now,
+		now,
+	)
+}
+
+// BackfillChunkSize - returns number of items to process in the next backfill chunk
+// Each item needs at least `itemPoints` GitHub API points and `minPoints` must remain, returns 0 when there are not enough points
+func BackfillChunkSize(chunk, remaining, minPoints, itemPoints int) int {
+	if itemPoints < 1 {
+		itemPoints = 1
+	}
+	n := (remaining - minPoints) / itemPoints
+	if n > chunk {
+		n = chunk
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
@@ -0,0 +1,32 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestBackfillChunkSize(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		chunk      int
+		remaining  int
+		minPoints  int
+		itemPoints int
+		expected   int
+	}{
+		{chunk: 1000, remaining: 5000, minPoints: 1, itemPoints: 2, expected: 1000},
+		{chunk: 1000, remaining: 1001, minPoints: 1, itemPoints: 2, expected: 500},
+		{chunk: 1000, remaining: 1000, minPoints: 100, itemPoints: 3, expected: 300},
+		{chunk: 1000, remaining: 2, minPoints: 1, itemPoints: 2, expected: 0},
+		{chunk: 1000, remaining: 0, minPoints: 10, itemPoints: 2, expected: 0},
+		{chunk: 10, remaining: 50, minPoints: 0, itemPoints: 0, expected: 10},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.BackfillChunkSize(test.chunk, test.remaining, test.minPoints, test.itemPoints)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %d, got %d", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package devstats
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// MaxQueryParams - Postgres limit on the number of bind parameters in a single query
+const MaxQueryParams int = 0xffff
+
+// BulkInsert - collects rows to insert (grouped by table and columns) and writes them using multi-row inserts
+// It is used by `gha2db` to write all rows from a single GHA hour at once instead of row by row
+// It is safe to use from multiple go routines
+type BulkInsert struct {
+	mtx    sync.Mutex
+	into   []string
+	tables map[string]*bulkTable
+	keys   map[string]struct{}
+	nRows  int
+}
+
+// bulkTable - rows to insert into a single table (with given columns) and keys they belong to
+type bulkTable struct {
+	ignore bool
+	rows   [][]interface{}
+	keys   []string
+}
+
+// NewBulkInsert - creates new empty bulk insert
+func NewBulkInsert() *BulkInsert {
+	return &BulkInsert{
+		tables: make(map[string]*bulkTable),
+		keys:   make(map[string]struct{}),
+	}
+}
+
+// Add - adds a single row to insert, `into` is "into table(col1, ..., colN)"
+// When `ignore` is set, rows are inserted using InsertIgnore semantics
+func (b *BulkInsert) Add(into string, ignore bool, row ...interface{}) {
+	b.AddFor("", into, ignore, row...)
+}
+
+// AddFor - adds a single row belonging to a given key (see AddKey), rows of removed keys are not inserted
+func (b *BulkInsert) AddFor(key, into string, ignore bool, row ...interface{}) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	name := into
+	if ignore {
+		name = "ignore " + into
+	}
+	table, ok := b.tables[name]
+	if !ok {
+		table = &bulkTable{ignore: ignore}
+		b.tables[name] = table
+		b.into = append(b.into, name)
+	}
+	table.rows = append(table.rows, row)
+	table.keys = append(table.keys, key)
+	b.nRows++
+}
+
+// AddKey - marks key as added, returns false if it was already added
+// This is used to skip events already added to this bulk (but not yet written to the DB)
+func (b *BulkInsert) AddKey(key string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if _, ok := b.keys[key]; ok {
+		return false
+	}
+	b.keys[key] = struct{}{}
+	return true
+}
+
+// Remove - removes given keys and all their rows
+func (b *BulkInsert) Remove(keys ...string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.remove(keys)
+}
+
+// remove - removes given keys and their rows, must be called with mutex locked
+func (b *BulkInsert) remove(keys []string) {
+	removed := make(map[string]struct{})
+	for _, key := range keys {
+		if _, ok := b.keys[key]; ok {
+			delete(b.keys, key)
+			removed[key] = struct{}{}
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	into := []string{}
+	for _, name := range b.into {
+		table := b.tables[name]
+		n := 0
+		for i, row := range table.rows {
+			if _, ok := removed[table.keys[i]]; ok {
+				continue
+			}
+			table.rows[n], table.keys[n] = row, table.keys[i]
+			n++
+		}
+		b.nRows -= len(table.rows) - n
+		table.rows, table.keys = table.rows[:n], table.keys[:n]
+		if n == 0 {
+			delete(b.tables, name)
+			continue
+		}
+		into = append(into, name)
+	}
+	b.into = into
+}
+
+// RemoveExisting - removes keys returned by `query` (it gets array of all added keys as $1) and their rows, returns number of removed keys
+// `gha2db` uses it to skip events that are already in the DB using a single query per GHA hour
+func (b *BulkInsert) RemoveExisting(con *sql.DB, ctx *Ctx, query string) int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if len(b.keys) == 0 {
+		return 0
+	}
+	keys := make([]string, 0, len(b.keys))
+	for key := range b.keys {
+		keys = append(keys, key)
+	}
+	rows := QuerySQLWithErr(con, ctx, query, pq.Array(keys))
+	defer func() { FatalOnError(rows.Close()) }()
+	existing := []string{}
+	for rows.Next() {
+		var key string
+		FatalOnError(rows.Scan(&key))
+		existing = append(existing, key)
+	}
+	FatalOnError(rows.Err())
+	b.remove(existing)
+	return len(existing)
+}
+
+// Len - returns number of rows collected
+func (b *BulkInsert) Len() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.nRows
+}
+
+// NMultiValues will return values($1, .., $nCols), ($nCols+1, ..), .. for nRows rows
+func NMultiValues(nRows, nCols int) string {
+	var sb strings.Builder
+	sb.WriteString("values")
+	param := 1
+	for r := 0; r < nRows; r++ {
+		if r > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("(")
+		for c := 0; c < nCols; c++ {
+			if c > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("$" + strconv.Itoa(param))
+			param++
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// Statements - returns multi-row insert statements and their arguments
+// Each statement has no more than maxParams arguments (but at least one row)
+func (b *BulkInsert) Statements(maxParams int) (queries []string, args [][]interface{}) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for _, key := range b.into {
+		table := b.tables[key]
+		into := strings.TrimPrefix(key, "ignore ")
+		nCols := len(table.rows[0])
+		maxRows := 1
+		if nCols > 0 && maxParams/nCols > 1 {
+			maxRows = maxParams / nCols
+		}
+		for from := 0; from < len(table.rows); from += maxRows {
+			to := from + maxRows
+			if to > len(table.rows) {
+				to = len(table.rows)
+			}
+			query := into + " " + NMultiValues(to-from, nCols)
+			if table.ignore {
+				query = InsertIgnore(query)
+			} else {
+				query = "insert " + query
+			}
+			var qArgs []interface{}
+			for _, row := range table.rows[from:to] {
+				qArgs = append(qArgs, row...)
+			}
+			queries = append(queries, query)
+			args = append(args, qArgs)
+		}
+	}
+	return
+}
+
+// Flush - writes all collected rows in a single transaction and clears the bulk
+func (b *BulkInsert) Flush(con *sql.DB, ctx *Ctx) {
+	queries, args := b.Statements(MaxQueryParams)
+	if len(queries) > 0 {
+		tx, err := con.Begin()
+		FatalOnError(err)
+		for i, query := range queries {
+			ExecSQLTxWithErr(tx, ctx, query, args[i]...)
+		}
+		FatalOnError(tx.Commit())
+	}
+	b.mtx.Lock()
+	b.into = []string{}
+	b.tables = make(map[string]*bulkTable)
+	b.keys = make(map[string]struct{})
+	b.nRows = 0
+	b.mtx.Unlock()
+}
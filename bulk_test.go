@@ -0,0 +1,146 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestNMultiValues(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		nRows    int
+		nCols    int
+		expected string
+	}{
+		{nRows: 1, nCols: 1, expected: "values($1)"},
+		{nRows: 1, nCols: 3, expected: "values($1, $2, $3)"},
+		{nRows: 2, nCols: 2, expected: "values($1, $2),($3, $4)"},
+		{nRows: 3, nCols: 1, expected: "values($1),($2),($3)"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.NMultiValues(test.nRows, test.nCols)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestBulkInsertStatements(t *testing.T) {
+	bulk := lib.NewBulkInsert()
+	if bulk.Len() != 0 {
+		t.Errorf("expected empty bulk, got %d rows", bulk.Len())
+	}
+	bulk.Add("into t1(a, b)", false, 1, "a")
+	bulk.Add("into t2(x)", true, 10)
+	bulk.Add("into t1(a, b)", false, 2, "b")
+	bulk.Add("into t1(a, b)", false, 3, "c")
+	bulk.Add("into t2(x)", true, 20)
+	if bulk.Len() != 5 {
+		t.Errorf("expected 5 rows, got %d", bulk.Len())
+	}
+
+	// Test cases
+	var testCases = []struct {
+		maxParams       int
+		expectedQueries []string
+		expectedArgs    [][]interface{}
+	}{
+		{
+			maxParams: lib.MaxQueryParams,
+			expectedQueries: []string{
+				"insert into t1(a, b) values($1, $2),($3, $4),($5, $6)",
+				"insert into t2(x) values($1),($2) on conflict do nothing",
+			},
+			expectedArgs: [][]interface{}{
+				{1, "a", 2, "b", 3, "c"},
+				{10, 20},
+			},
+		},
+		{
+			maxParams: 4,
+			expectedQueries: []string{
+				"insert into t1(a, b) values($1, $2),($3, $4)",
+				"insert into t1(a, b) values($1, $2)",
+				"insert into t2(x) values($1),($2) on conflict do nothing",
+			},
+			expectedArgs: [][]interface{}{
+				{1, "a", 2, "b"},
+				{3, "c"},
+				{10, 20},
+			},
+		},
+		{
+			maxParams: 1,
+			expectedQueries: []string{
+				"insert into t1(a, b) values($1, $2)",
+				"insert into t1(a, b) values($1, $2)",
+				"insert into t1(a, b) values($1, $2)",
+				"insert into t2(x) values($1) on conflict do nothing",
+				"insert into t2(x) values($1) on conflict do nothing",
+			},
+			expectedArgs: [][]interface{}{
+				{1, "a"},
+				{2, "b"},
+				{3, "c"},
+				{10},
+				{20},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		gotQueries, gotArgs := bulk.Statements(test.maxParams)
+		if !reflect.DeepEqual(gotQueries, test.expectedQueries) {
+			t.Errorf("test number %d, expected queries %v, got %v", index+1, test.expectedQueries, gotQueries)
+		}
+		if !reflect.DeepEqual(gotArgs, test.expectedArgs) {
+			t.Errorf("test number %d, expected args %v, got %v", index+1, test.expectedArgs, gotArgs)
+		}
+	}
+}
+
+func TestBulkInsertAddKey(t *testing.T) {
+	bulk := lib.NewBulkInsert()
+	if !bulk.AddKey("1") {
+		t.Errorf("expected first key to be added")
+	}
+	if !bulk.AddKey("2") {
+		t.Errorf("expected second key to be added")
+	}
+	if bulk.AddKey("1") {
+		t.Errorf("expected duplicate key to be rejected")
+	}
+}
+
+func TestBulkInsertRemove(t *testing.T) {
+	bulk := lib.NewBulkInsert()
+	for _, key := range []string{"1", "2", "3"} {
+		bulk.AddKey(key)
+	}
+	bulk.AddFor("1", "into t1(a)", false, 1)
+	bulk.AddFor("2", "into t1(a)", false, 2)
+	bulk.AddFor("2", "into t2(x)", true, 20)
+	bulk.AddFor("3", "into t1(a)", false, 3)
+	bulk.Add("into t2(x)", true, 0)
+	bulk.AddFor("1", "into t3(y)", false, 100)
+
+	// Removing keys removes all their rows and tables left without rows, unknown keys are ignored
+	bulk.Remove("1", "3", "4")
+	if bulk.Len() != 3 {
+		t.Errorf("expected 3 rows, got %d", bulk.Len())
+	}
+	queries, args := bulk.Statements(lib.MaxQueryParams)
+	expectedQueries := []string{"insert into t1(a) values($1)", "insert into t2(x) values($1),($2) on conflict do nothing"}
+	expectedArgs := [][]interface{}{{2}, {20, 0}}
+	if !reflect.DeepEqual(queries, expectedQueries) || !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected %v %v, got %v %v", expectedQueries, expectedArgs, queries, args)
+	}
+
+	// Removed keys can be added again
+	if !bulk.AddKey("1") || bulk.AddKey("2") {
+		t.Errorf("expected removed key to be added again and not removed key to be rejected")
+	}
+}
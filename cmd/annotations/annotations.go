@@ -37,13 +37,34 @@ func makeAnnotations() {
 		lib.Fatalf("project '%s' not found in '%s'", ctx.Project, ctx.ProjectsYaml)
 	}
 
-	// Get annotations using GitHub API and add annotations and quick ranges to InfluxDB
+	// Repositories to get tags (and optionally GitHub releases) from, main repo first
+	repos := []string{}
 	if proj.MainRepo != "" {
-		annotations := lib.GetAnnotations(&ctx, proj.MainRepo, proj.AnnotationRegexp)
-		lib.ProcessAnnotations(&ctx, &annotations, proj.StartDate, proj.JoinDate)
+		repos = append(repos, proj.MainRepo)
+	}
+	repos = append(repos, proj.AnnotationRepos...)
+
+	// Custom project events have the highest priority, then tags, then GitHub releases
+	custom := lib.GetYamlAnnotations(&ctx, dataPrefix+ctx.AnnotationsYaml)
+	var releases lib.Annotations
+	if proj.AnnotationReleases && len(repos) > 0 {
+		con := lib.PgConn(&ctx)
+		releases = lib.GetReleasesAnnotations(&ctx, con, repos, proj.AnnotationRegexp)
+		lib.FatalOnError(con.Close())
+	}
+
+	// Project's quick ranges
+	quickRanges := lib.GetQuickRanges(&ctx, dataPrefix+ctx.QuickRangesYaml, proj.QuickRanges)
+
+	// Get annotations and add annotations and quick ranges to InfluxDB
+	if len(repos) > 0 {
+		annotations := lib.MergeAnnotations(custom, lib.GetRepoAnnotations(&ctx, repos, proj.AnnotationRegexp), releases)
+		lib.ProcessAnnotations(&ctx, &annotations, quickRanges, proj.StartDate, proj.JoinDate)
 	} else if proj.StartDate != nil && proj.JoinDate != nil {
-		annotations := lib.GetFakeAnnotations(*proj.StartDate, *proj.JoinDate)
-		lib.ProcessAnnotations(&ctx, &annotations, nil, nil)
+		annotations := lib.MergeAnnotations(custom, lib.GetFakeAnnotations(*proj.StartDate, *proj.JoinDate))
+		lib.ProcessAnnotations(&ctx, &annotations, quickRanges, nil, nil)
+	} else if len(custom.Annotations) > 0 {
+		lib.ProcessAnnotations(&ctx, &custom, quickRanges, proj.StartDate, proj.JoinDate)
 	}
 }
 
@@ -13,23 +13,6 @@ import (
 	client "github.com/influxdata/influxdb/client/v2"
 )
 
-// valueDescription - return string description for given float value
-// descFunc specifies how to treat value
-// currently supported:
-// `time_diff_as_string`: return string description of value that holds number of hours passed
-// like 30 -> 1 day 6 hours, 100 -> 4 days 4 hours, etc...
-func valueDescription(descFunc string, value float64) (result string) {
-	switch descFunc {
-	case "time_diff_as_string":
-		return lib.DescriblePeriodInHours(value)
-	default:
-		lib.Printf("Error\nUnknown value description function '%v'\n", descFunc)
-		fmt.Fprintf(os.Stdout, "Error\nUnknown value description function '%v'\n", descFunc)
-		os.Exit(1)
-	}
-	return
-}
-
 // Returns multi row and multi column series names array (different for different rows)
 // Each row must be in format: 'prefix;rowName;series1,series2,..,seriesN' serVal1 serVal2 ... serValN
 // if multivalue is true then rowName is not used for generating series name
@@ -119,11 +102,11 @@ func multiRowSingleColumn(col, period string, multivalue, escapeValueName bool)
 // Generate name for given series row and period
 func nameForMetricsRow(metric, name, period string, multivalue, escapeValueName bool) []string {
 	switch metric {
-	case "single_row_multi_column":
+	case lib.SingleRowMultiColumn:
 		return singleRowMultiColumn(name, period)
-	case "multi_row_single_column":
+	case lib.MultiRowSingleColumn:
 		return multiRowSingleColumn(name, period, multivalue, escapeValueName)
-	case "multi_row_multi_column":
+	case lib.MultiRowMultiColumn:
 		return multiRowMultiColumn(name, period, multivalue, escapeValueName)
 	default:
 		lib.Printf("Error\nUnknown metric '%v'\n", metric)
@@ -141,28 +124,20 @@ func roundF2I(val float64) int {
 	return int(val + 0.5)
 }
 
-func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, excludeBots, period, desc string, multivalue, escapeValueName bool, nIntervals int, dt, from, to time.Time) {
+func workerThread(ch chan bool, ctx *lib.Ctx, dataPrefix, seriesNameOrFunc, sqlFile, sqlQuery, period string, descs []lib.ValueDescFunc, multivalue, escapeValueName bool, nIntervals int, dt, from, to time.Time) {
 	// Connect to Postgres DB
 	sqlc := lib.PgConn(ctx)
 	defer func() { lib.FatalOnError(sqlc.Close()) }()
 
-	// Connect to InfluxDB
-	ic := lib.IDBConn(ctx)
-	defer func() { lib.FatalOnError(ic.Close()) }()
-
-	// Get BatchPoints
-	var pts lib.IDBBatchPointsN
-	bp := lib.IDBBatchPoints(ctx, &ic)
-	pts.NPoints = 0
-	pts.Points = &bp
+	// Connect to time series outputs
+	sink := lib.NewSeriesSink(ctx)
+	defer sink.Close()
 
 	// Prepare SQL query
-	sFrom := lib.ToYMDHMSDate(from)
-	sTo := lib.ToYMDHMSDate(to)
-	sqlQuery = strings.Replace(sqlQuery, "{{from}}", sFrom, -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{to}}", sTo, -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(nIntervals)+".0", -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+	sqlQuery, err := lib.RenderSQL(ctx, dataPrefix, sqlQuery, lib.MetricSQLVars(from, to, nIntervals))
+	if err != nil {
+		lib.Fatalf("%s: %v", sqlFile, err)
+	}
 
 	// Execute SQL query
 	rows := lib.QuerySQLWithErr(sqlc, ctx, sqlQuery)
@@ -175,9 +150,6 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 	lib.FatalOnError(err)
 	nColumns := len(columns)
 
-	// Use value descriptions?
-	useDesc := desc != ""
-
 	// Metric Results, assume they're floats
 	var (
 		pValue *float64
@@ -212,11 +184,10 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 		}
 		// Add batch point
 		fields := map[string]interface{}{"value": value}
-		if useDesc {
-			fields["descr"] = valueDescription(desc, value)
+		if descr, ok := lib.DescribeValue(descs, 0, value); ok {
+			fields["descr"] = descr
 		}
-		pt := lib.IDBNewPointWithErr(ctx, name, nil, fields, dt)
-		lib.IDBAddPointN(ctx, &ic, &pts, pt)
+		sink.AddPoint(name, nil, fields, dt)
 	} else if nColumns >= 2 {
 		// Multiple rows, each with (series name, value(s))
 		// Number of columns
@@ -263,25 +234,23 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesNameOrFunc, sqlQuery, exclud
 						}
 						// Add batch point
 						fields := map[string]interface{}{"value": value}
-						if useDesc {
-							fields["descr"] = valueDescription(desc, value)
+						if descr, ok := lib.DescribeValue(descs, idx, value); ok {
+							fields["descr"] = descr
 						}
-						pt := lib.IDBNewPointWithErr(ctx, name, nil, fields, dt)
-						lib.IDBAddPointN(ctx, &ic, &pts, pt)
+						sink.AddPoint(name, nil, fields, dt)
 					}
 				}
 			}
 		}
 		// Multivalue series if any
 		for seriesName, seriesValues := range allFields {
-			pt := lib.IDBNewPointWithErr(ctx, seriesName, nil, seriesValues, dt)
-			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			sink.AddPoint(seriesName, nil, seriesValues, dt)
 		}
 		lib.FatalOnError(rows.Err())
 	}
 	// Write the batch
 	if !ctx.SkipIDB {
-		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+		lib.FatalOnError(sink.Write())
 	} else if ctx.Debug > 0 {
 		lib.Printf("Skipping series write\n")
 	}
@@ -306,26 +275,31 @@ func getPathIndependentKey(key string) string {
 
 // isAlreadyComputed check if given quick range period was already computed
 // It will skip past period marked as compued unless special flags are passed
-func isAlreadyComputed(ic client.Client, ctx *lib.Ctx, key, from string) bool {
+// Period is identified by metric file, quick range suffix and its from/to dates
+func isAlreadyComputed(ic client.Client, ctx *lib.Ctx, key, sfx, from, to string) bool {
 	key = getPathIndependentKey(key)
 	query := fmt.Sprintf(
 		"select count(*) "+
 			"from computed where computed_key = '%s' "+
-			"and computed_from = '%s'",
+			"and computed_sfx = '%s' "+
+			"and computed_from = '%s' "+
+			"and computed_to = '%s'",
 		key,
+		sfx,
 		from,
+		to,
 	)
 	res := lib.QueryIDB(ic, ctx, query)
 	computed := len(res[0].Series) > 0
 	if ctx.Debug > 0 {
-		lib.Printf("Period '%s: %s' compute status: %v\n", key, from, computed)
+		lib.Printf("Period '%s: %s: %s - %s' compute status: %v\n", key, sfx, from, to, computed)
 	}
 	return computed
 }
 
 // setAlreadyComputed marks given quick range period as computed
 // Should be called inside: if !ctx.SkipIDB { ... }
-func setAlreadyComputed(ic client.Client, ctx *lib.Ctx, pts *lib.IDBBatchPointsN, key, from string) {
+func setAlreadyComputed(ctx *lib.Ctx, sink lib.SeriesSink, key, sfx, from, to string) {
 	key = getPathIndependentKey(key)
 	// No fields value needed
 	fields := map[string]interface{}{"value": 0.0}
@@ -333,36 +307,39 @@ func setAlreadyComputed(ic client.Client, ctx *lib.Ctx, pts *lib.IDBBatchPointsN
 	// Tags to insert
 	tags := make(map[string]string)
 	tags["computed_from"] = from
+	tags["computed_to"] = to
+	tags["computed_sfx"] = sfx
 	tags["computed_key"] = key
 	dtFrom := lib.TimeParseAny(from)
 
 	// Add batch point
-	pt := lib.IDBNewPointWithErr(ctx, "computed", tags, fields, dtFrom)
-	lib.IDBAddPointN(ctx, &ic, pts, pt)
+	sink.AddPoint("computed", tags, fields, dtFrom)
 	if ctx.Debug > 0 {
-		lib.Printf("Period '%s: %s' marked as computed\n", key, from)
+		lib.Printf("Period '%s: %s: %s - %s' marked as computed\n", key, sfx, from, to)
 	}
 }
 
-func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, excludeBots, interval, intervalAbbr string, nIntervals int, annotationsRanges, skipPast, multivalue bool) {
+func db2influxHistogram(ctx *lib.Ctx, dataPrefix, seriesNameOrFunc, sqlFile, sqlQuery, interval, intervalAbbr string, nIntervals int, annotationsRanges, skipPast, multivalue bool) {
 	// Connect to Postgres DB
 	sqlc := lib.PgConn(ctx)
 	defer func() { lib.FatalOnError(sqlc.Close()) }()
 
-	// Connect to InfluxDB
+	// Connect to InfluxDB, quick ranges and computed periods are read from it
 	ic := lib.IDBConn(ctx)
 	defer func() { lib.FatalOnError(ic.Close()) }()
 
-	// Get BatchPoints
-	var pts lib.IDBBatchPointsN
-	bp := lib.IDBBatchPoints(ctx, &ic)
-	pts.NPoints = 0
-	pts.Points = &bp
+	// Connect to time series outputs
+	sink := lib.NewSeriesSink(ctx)
+	defer sink.Close()
 
 	lib.Printf("db2influx.go: Histogram running interval '%v,%v' n:%d anno:%v past:%v multi:%v\n", interval, intervalAbbr, nIntervals, annotationsRanges, skipPast, multivalue)
 
 	// If using annotations ranges, then get their values
-	var qrFrom *string
+	var (
+		qrFrom *string
+		qrTo   *string
+		vars   *lib.SQLVars
+	)
 	if annotationsRanges {
 		// Get Quick Ranges from IDB (it is filled by annotations command)
 		quickRanges := lib.GetTagValues(ic, ctx, "quick_ranges_data")
@@ -380,21 +357,22 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 				from := ary[2]
 				to := ary[3]
 				// We can skip past data sometimes
-				if skipPast && period == "" {
+				// In diff mode past ranges are always computed, so they can be compared with existing points
+				if skipPast && period == "" && !ctx.SeriesDiff {
 					dtTo := lib.TimeParseAny(to)
 					prevHour := lib.PrevHourStart(time.Now())
-					if dtTo.Before(prevHour) && isAlreadyComputed(ic, ctx, sqlFile, from) {
+					if dtTo.Before(prevHour) && isAlreadyComputed(ic, ctx, sqlFile, sfx, from, to) {
 						lib.Printf("Skipping past quick range: %v (already computed)\n", from)
 						return
 					}
 				}
-				sqlQuery = lib.PrepareQuickRangeQuery(sqlQuery, period, from, to)
-				sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+				vars = lib.QuickRangeSQLVars(period, from, to)
 				if period == "" {
 					dtTo := lib.TimeParseAny(to)
 					prevHour := lib.PrevHourStart(time.Now())
 					if dtTo.Before(prevHour) {
 						qrFrom = &from
+						qrTo = &to
 					}
 				}
 				break
@@ -404,14 +382,13 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 			lib.Fatalf("quick range not found: '%s' known quick ranges: %+v", intervalAbbr, quickRanges)
 		}
 	} else {
-		// Prepare SQL query
-		dbInterval := fmt.Sprintf("%d %s", nIntervals, interval)
-		if interval == lib.Quarter {
-			dbInterval = fmt.Sprintf("%d month", nIntervals*3)
-		}
-		sqlQuery = strings.Replace(sqlQuery, "{{period}}", dbInterval, -1)
-		sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(nIntervals)+".0", -1)
-		sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+		vars = lib.HistogramSQLVars(interval, nIntervals)
+	}
+
+	// Prepare SQL query
+	sqlQuery, err := lib.RenderSQL(ctx, dataPrefix, sqlQuery, vars)
+	if err != nil {
+		lib.Fatalf("%s: %v", sqlFile, err)
 	}
 
 	// Execute SQL query
@@ -432,7 +409,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		if !ctx.SkipIDB {
 			// Drop existing data
 			if ctx.IDBDrop {
-				lib.QueryIDB(ic, ctx, "delete from \""+seriesNameOrFunc+"\"")
+				sink.DeleteSeries(seriesNameOrFunc, nil)
 			}
 			if ctx.Debug > 0 {
 				lib.Printf("Dropped measurement %s\n", seriesNameOrFunc)
@@ -449,8 +426,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 			}
 			// Add batch point
 			fields := map[string]interface{}{"name": name, "value": value}
-			pt := lib.IDBNewPointWithErr(ctx, seriesNameOrFunc, nil, fields, tm)
-			lib.IDBAddPointN(ctx, &ic, &pts, pt)
+			sink.AddPoint(seriesNameOrFunc, nil, fields, tm)
 			rowCount++
 			tm = tm.Add(-time.Hour)
 		}
@@ -525,8 +501,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 					//lib.Printf("hist %v, %v %v -> %+v\n", name, nIntervals, interval, fields)
 				}
 				// Add batch point
-				pt := lib.IDBNewPointWithErr(ctx, name, nil, fields, tm)
-				lib.IDBAddPointN(ctx, &ic, &pts, pt)
+				sink.AddPoint(name, nil, fields, tm)
 			} else {
 				if nNames > 0 {
 					for i := 0; i < nNames; i++ {
@@ -556,8 +531,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 						}
 						// Add batch point
 						fields := map[string]interface{}{"name": sValue, "value": fValue}
-						pt := lib.IDBNewPointWithErr(ctx, name, nil, fields, tm)
-						lib.IDBAddPointN(ctx, &ic, &pts, pt)
+						sink.AddPoint(name, nil, fields, tm)
 					}
 				}
 			}
@@ -565,7 +539,7 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 		lib.FatalOnError(rows.Err())
 		if len(seriesToClear) > 0 && !ctx.SkipIDB && ctx.IDBDrop {
 			for series := range seriesToClear {
-				lib.QueryIDB(ic, ctx, "delete from \""+series+"\"")
+				sink.DeleteSeries(series, nil)
 				if ctx.Debug > 0 {
 					lib.Printf("Dropped series: %s\n", series)
 				}
@@ -575,10 +549,11 @@ func db2influxHistogram(ctx *lib.Ctx, seriesNameOrFunc, sqlFile, sqlQuery, exclu
 	// Write the batch
 	if !ctx.SkipIDB {
 		// Mark this metric & period as already computed if this is a QR period
-		if qrFrom != nil {
-			setAlreadyComputed(ic, ctx, &pts, sqlFile, *qrFrom)
+		// Diff mode doesn't write anything, so it doesn't mark periods as computed
+		if qrFrom != nil && !ctx.SeriesDiff {
+			setAlreadyComputed(ctx, sink, sqlFile, intervalAbbr, *qrFrom, *qrTo)
 		}
-		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+		lib.FatalOnError(sink.Write())
 	} else if ctx.Debug > 0 {
 		lib.Printf("Skipping series write\n")
 	}
@@ -595,26 +570,27 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 		dataPrefix = "./"
 	}
 
+	// Value descriptions, one function for all value columns or ";" separated functions for consecutive columns
+	descs, err := lib.ValueDescs(desc)
+	if err != nil {
+		lib.Fatalf("%v", err)
+	}
+
 	// Read SQL file.
 	bytes, err := lib.ReadFile(&ctx, sqlFile)
 	lib.FatalOnError(err)
 	sqlQuery := string(bytes)
 
-	// Read bots exclusion partial SQL
-	bytes, err = lib.ReadFile(&ctx, dataPrefix+"util_sql/exclude_bots.sql")
-	lib.FatalOnError(err)
-	excludeBots := string(bytes)
-
 	// Process interval
 	interval, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart := lib.GetIntervalFunctions(intervalAbbr, annotationsRanges)
 
 	if hist {
 		db2influxHistogram(
 			&ctx,
+			dataPrefix,
 			seriesNameOrFunc,
 			sqlFile,
 			sqlQuery,
-			excludeBots,
 			interval,
 			intervalAbbr,
 			nIntervals,
@@ -636,14 +612,19 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
 
+	// Intervals to compute, in incremental mode also past intervals touched by newly imported events
+	dts := lib.IntervalStarts(dFrom, dTo, nextIntervalStart)
+	if !annotationsRanges {
+		dts = lib.AddDirtyIntervals(&ctx, dts, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart, dTo)
+	}
+
 	// Run
-	lib.Printf("db2influx.go: Running (on %d CPUs): %v - %v with interval %s, descriptions '%s', multivalue: %v, escape_value_name: %v\n", thrN, dFrom, dTo, interval, desc, multivalue, escapeValueName)
-	dt := dFrom
+	lib.Printf("db2influx.go: Running (on %d CPUs): %v - %v (%d intervals) with interval %s, descriptions '%s', multivalue: %v, escape_value_name: %v\n", thrN, dFrom, dTo, len(dts), interval, desc, multivalue, escapeValueName)
 	var pDt time.Time
 	if thrN > 1 {
 		ch := make(chan bool)
 		nThreads := 0
-		for dt.Before(dTo) {
+		for _, dt := range dts {
 			nDt := nextIntervalStart(dt)
 			if nIntervals <= 1 {
 				pDt = dt
@@ -653,11 +634,12 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 			go workerThread(
 				ch,
 				&ctx,
+				dataPrefix,
 				seriesNameOrFunc,
+				sqlFile,
 				sqlQuery,
-				excludeBots,
 				intervalAbbr,
-				desc,
+				descs,
 				multivalue,
 				escapeValueName,
 				nIntervals,
@@ -665,7 +647,6 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 				pDt,
 				nDt,
 			)
-			dt = nDt
 			nThreads++
 			if nThreads == thrN {
 				<-ch
@@ -679,7 +660,7 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 		}
 	} else {
 		lib.Printf("Using single threaded version\n")
-		for dt.Before(dTo) {
+		for _, dt := range dts {
 			nDt := nextIntervalStart(dt)
 			if nIntervals <= 1 {
 				pDt = dt
@@ -689,11 +670,12 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 			workerThread(
 				nil,
 				&ctx,
+				dataPrefix,
 				seriesNameOrFunc,
+				sqlFile,
 				sqlQuery,
-				excludeBots,
 				intervalAbbr,
-				desc,
+				descs,
 				multivalue,
 				escapeValueName,
 				nIntervals,
@@ -701,7 +683,6 @@ func db2influx(seriesNameOrFunc, sqlFile, from, to, intervalAbbr string, hist, m
 				pDt,
 				nDt,
 			)
-			dt = nDt
 		}
 	}
 	// Finished
@@ -713,7 +694,7 @@ func main() {
 	if len(os.Args) < 6 {
 		lib.Printf(
 			"Required series name, SQL file name, from, to, period " +
-				"[series_name_or_func some.sql '2015-08-03' '2017-08-21' h|d|w|m|q|y [hist,desc:time_diff_as_string]]\n",
+				"[series_name_or_func some.sql '2015-08-03' '2017-08-21' h|d|w|m|q|y [hist,desc:time_diff_as_string|desc:ordinal;percent]]\n",
 		)
 		lib.Printf(
 			"Series name (series_name_or_func) will become exact series name if " +
@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	yaml "gopkg.in/yaml.v2"
@@ -16,6 +18,8 @@ func syncAllProjects() bool {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
+	lib.PromStart(&ctx, "devstats")
+	defer lib.PromFinish(&ctx)
 
 	// Set non-fatal exec mode, we want to run sync for next project(s) if current fails
 	ctx.ExecFatal = false
@@ -50,6 +54,9 @@ func syncAllProjects() bool {
 	// Schedule remove PID file when finished
 	defer func() { lib.FatalOnError(os.Remove(pidFile)) }()
 
+	// Record this run in sync runs history, `gha2db_sync` runs will be recorded as its children
+	run := lib.NewSyncRun(&ctx, "devstats")
+
 	// Sort projects by "order"
 	orders := []int{}
 	projectsMap := make(map[int]string)
@@ -71,6 +78,7 @@ func syncAllProjects() bool {
 	if !ctx.SkipGetRepos {
 		lib.Printf("Updating git repos for all projects\n")
 		dtStart := time.Now()
+		ph := run.StartPhase("get_repos", "")
 		_, res := lib.ExecCommand(
 			&ctx,
 			[]string{
@@ -81,7 +89,9 @@ func syncAllProjects() bool {
 			},
 		)
 		dtEnd := time.Now()
+		ph.Finish(res)
 		if res != nil {
+			run.Finish(res)
 			lib.Printf("Error updating git repos (took %v): %+v\n", dtEnd.Sub(dtStart), res)
 			fmt.Fprintf(os.Stderr, "%v: Error updating git repos (took %v): %+v\n", dtEnd, dtEnd.Sub(dtStart), res)
 			return false
@@ -89,39 +99,152 @@ func syncAllProjects() bool {
 		lib.Printf("Updated git repos, took: %v\n", dtEnd.Sub(dtStart))
 	}
 
-	// Sync all projects
+	// Sync all projects, up to GHA2DB_PROJECTS_PARALLEL at once
+	// Projects sharing Postgres or Influx database are never synced at the same time
+	pending := []*projectSync{}
 	for _, order := range orders {
 		name := projectsMap[order]
-		proj := projects.Projects[name]
-		projEnv := map[string]string{
-			"GHA2DB_PROJECT": name,
-			"PG_DB":          proj.PDB,
-			"IDB_DB":         proj.IDB,
-		}
-		// Apply eventual per project specific environment
-		for envName, envValue := range proj.Env {
-			projEnv[envName] = envValue
+		pending = append(pending, &projectSync{order: order, name: name, proj: projects.Projects[name]})
+	}
+	all := append([]*projectSync{}, pending...)
+	busy := make(map[string]struct{})
+	ch := make(chan *projectSync)
+	running := 0
+	for len(pending) > 0 || running > 0 {
+		// Start all projects we can (in order), skip projects whose databases are in use
+		for i := 0; i < len(pending) && running < ctx.ProjectsParallel; {
+			ps := pending[i]
+			if dbsBusy(busy, ps.dbs()) {
+				i++
+				continue
+			}
+			for _, db := range ps.dbs() {
+				busy[db] = struct{}{}
+			}
+			pending = append(pending[:i], pending[i+1:]...)
+			running++
+			go syncProject(ch, ctx, run, cmdPrefix, ps)
 		}
-		lib.Printf("Syncing #%d %s\n", order, name)
-		dtStart := time.Now()
-		_, res := lib.ExecCommand(
-			&ctx,
-			[]string{
-				cmdPrefix + "gha2db_sync",
-			},
-			projEnv,
-		)
-		dtEnd := time.Now()
-		if res != nil {
-			lib.Printf("Error result for %s (took %v): %+v\n", name, dtEnd.Sub(dtStart), res)
-			fmt.Fprintf(os.Stderr, "%v: Error result for %s (took %v): %+v\n", dtEnd, name, dtEnd.Sub(dtStart), res)
-			continue
+		// Wait for any project to finish
+		ps := <-ch
+		running--
+		for _, db := range ps.dbs() {
+			delete(busy, db)
 		}
-		lib.Printf("Synced %s, took: %v\n", name, dtEnd.Sub(dtStart))
+	}
+	failed := printSummary(all)
+	if failed > 0 {
+		run.Finish(fmt.Errorf("%d project(s) failed to sync", failed))
+	} else {
+		run.Finish(nil)
 	}
 	return true
 }
 
+// projectSync - single project sync and its result
+type projectSync struct {
+	order   int
+	name    string
+	proj    lib.Project
+	timeout time.Duration
+	took    time.Duration
+	err     error
+}
+
+// dbs - databases used by project
+func (ps *projectSync) dbs() []string {
+	return []string{"pg:" + ps.proj.PDB, "idb:" + ps.proj.IDB}
+}
+
+// timedOut - was project's sync killed because of timeout?
+func (ps *projectSync) timedOut() bool {
+	return ps.err != nil && ps.timeout > 0 && ps.took >= ps.timeout
+}
+
+// dbsBusy - is any of given databases used by projects being synced?
+func dbsBusy(busy map[string]struct{}, dbs []string) bool {
+	for _, db := range dbs {
+		if _, ok := busy[db]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// syncProject - calls `gha2db_sync` for a single project, sends finished project to `ch`
+func syncProject(ch chan *projectSync, ctx lib.Ctx, run *lib.SyncRun, cmdPrefix string, ps *projectSync) {
+	projEnv := map[string]string{
+		"GHA2DB_PROJECT": ps.name,
+		"PG_DB":          ps.proj.PDB,
+		"IDB_DB":         ps.proj.IDB,
+	}
+	if run.ID > 0 {
+		projEnv["GHA2DB_SYNC_RUN_ID"] = strconv.Itoa(run.ID)
+	}
+	// Apply eventual per project specific environment
+	for envName, envValue := range ps.proj.Env {
+		projEnv[envName] = envValue
+	}
+	// Per project timeout from `projects.yaml` has priority over GHA2DB_PROJECT_TIMEOUT
+	ps.timeout = ctx.ProjectTimeout
+	if ps.proj.SyncTimeout > 0 {
+		ps.timeout = ps.proj.SyncTimeout
+	}
+	ctx.ExecTimeout = ps.timeout
+	lib.Printf("Syncing #%d %s\n", ps.order, ps.name)
+	dtStart := time.Now()
+	ph := run.StartPhase("sync", ps.name)
+	_, ps.err = lib.ExecCommand(
+		&ctx,
+		[]string{
+			cmdPrefix + "gha2db_sync",
+		},
+		projEnv,
+	)
+	dtEnd := time.Now()
+	ps.took = dtEnd.Sub(dtStart)
+	ph.Finish(ps.err)
+	status := lib.SyncOK
+	if ps.err != nil {
+		status = lib.SyncFailed
+	}
+	lib.PromSet("devstats_project_sync_duration_seconds", "Last gha2db_sync duration for project.", ps.took.Seconds(), "synced_project", ps.name, "status", status)
+	if ps.err != nil {
+		lib.Printf("Error result for %s (took %v): %+v\n", ps.name, ps.took, ps.err)
+		fmt.Fprintf(os.Stderr, "%v: Error result for %s (took %v): %+v\n", dtEnd, ps.name, ps.took, ps.err)
+	} else {
+		lib.Printf("Synced %s, took: %v\n", ps.name, ps.took)
+	}
+	ch <- ps
+}
+
+// printSummary - outputs all projects sync durations and failures, returns number of failed projects
+func printSummary(all []*projectSync) int {
+	failed := []string{}
+	summary := "Sync summary:\n"
+	for _, ps := range all {
+		status := "ok"
+		if ps.timedOut() {
+			status = "timeout"
+		} else if ps.err != nil {
+			status = "failed"
+		}
+		if ps.err != nil {
+			failed = append(failed, ps.name)
+		}
+		summary += fmt.Sprintf("#%-3d %-20s %-8s %v\n", ps.order, ps.name, status, ps.took)
+	}
+	summary += fmt.Sprintf("%d projects, %d failed", len(all), len(failed))
+	if len(failed) > 0 {
+		summary += ": " + strings.Join(failed, ", ")
+	}
+	lib.Printf("%s\n", summary)
+	if len(failed) > 0 {
+		fmt.Fprintf(os.Stderr, "%v: %d project(s) failed to sync: %s\n", time.Now(), len(failed), strings.Join(failed, ", "))
+	}
+	return len(failed)
+}
+
 func main() {
 	dtStart := time.Now()
 	synced := syncAllProjects()
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +19,9 @@ type dbCommits struct {
 	repos            []string
 	con              *sql.DB
 	filesSkipPattern string
+	identities       bool // `gha_commits_identities` table exists
+	lines            bool // `gha_commits_files_lines` table exists
+	attribution      bool // `gha_commits_companies` table exists
 }
 
 // dirExists checks if given path exist and if is a directory
@@ -115,12 +117,6 @@ func getRepos(ctx *lib.Ctx) (map[string]string, map[string][]string) {
 
 // processRepo - processes single repo (clone or reset+pull) in a separate thread/goroutine
 func processRepo(ch chan string, ctx *lib.Ctx, orgRepo, rwd string) {
-	// Local or cron mode?
-	cmdPrefix := ""
-	if ctx.Local {
-		cmdPrefix = lib.LocalGitScripts
-	}
-
 	// Clone or reset+pull repo
 	exists, err := dirExists(rwd)
 	lib.FatalOnError(err)
@@ -131,13 +127,7 @@ func processRepo(ch chan string, ctx *lib.Ctx, orgRepo, rwd string) {
 		}
 		dtStart := time.Now()
 		// Clone repo into given directory (from command line)
-		// We cannot chdir because this is a multithreaded app
-		// And all threads share CWD (current working directory)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{"git", "clone", "https://github.com/" + orgRepo + ".git", rwd},
-			map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-		)
+		err := lib.GitClone(ctx, "https://github.com/"+orgRepo+".git", rwd)
 		dtEnd := time.Now()
 		if err != nil {
 			if ctx.Debug > 0 {
@@ -156,20 +146,13 @@ func processRepo(ch chan string, ctx *lib.Ctx, orgRepo, rwd string) {
 			lib.Printf("Pulling %s\n", orgRepo)
 		}
 		dtStart := time.Now()
-		// Update repo using shell script that uses 'chdir'
-		// We cannot chdir because this is a multithreaded app
-		// And all threads share CWD (current working directory)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{cmdPrefix + "git_reset_pull.sh", rwd},
-			map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-		)
+		err := lib.GitResetPull(ctx, rwd)
 		dtEnd := time.Now()
 		if err != nil {
 			if ctx.Debug > 0 {
-				lib.Printf("Warning git_reset_pull.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+				lib.Printf("Warning git-reset/pull failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
 			}
-			fmt.Fprintf(os.Stderr, "Warning git_reset_pull.sh failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
+			fmt.Fprintf(os.Stderr, "Warning git-reset/pull failed: %s (took %v): %+v\n", orgRepo, dtEnd.Sub(dtStart), err)
 			ch <- ""
 			return
 		}
@@ -316,6 +299,18 @@ func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, que
 	// Connect to Postgres `db` database.
 	con := lib.PgConnDB(ctx, db)
 
+	// Identities, lines and companies attribution need tables added by util_sql migrations, skip them when not applied
+	dctx := *ctx
+	dctx.PgDB = db
+	commits.identities = lib.TableExists(con, &dctx, "gha_commits_identities", "commits_lines_tables.sql", "commits identities are not saved")
+	commits.lines = lib.TableExists(con, &dctx, "gha_commits_files_lines", "commits_lines_tables.sql", "commits files lines are not saved")
+	commits.attribution = commits.identities &&
+		lib.TableExists(con, &dctx, "gha_commits_companies", "companies_attribution_tables.sql", "commits are not attributed to companies")
+	if !commits.identities {
+		// Without identities commits having files are processed
+		query = strings.Replace(query, "gha_commits_identities", "gha_commits_files", -1)
+	}
+
 	rows, err := con.Query(query)
 	lib.FatalOnError(err)
 	defer func() { lib.FatalOnError(rows.Close()) }()
@@ -337,31 +332,19 @@ func processCommitsDB(ch chan dbCommits, ctx *lib.Ctx, db, filesSkipPattern, que
 }
 
 // getCommitFiles get given commit's list of files and saves it in the database
-func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *regexp.Regexp, repo, sha string) {
-	// Local or cron mode?
-	cmdPrefix := ""
-	if ctx.Local {
-		cmdPrefix = lib.LocalGitScripts
-	}
-
-	// Get files using shell script that does 'chdir'
-	// We cannot chdir because this is a multithreaded app
-	// And all threads share CWD (current working directory)
+func getCommitFiles(ch chan int, ctx *lib.Ctx, commits *dbCommits, filesSkipPattern *regexp.Regexp, repo, sha string) {
+	con := commits.con
 	if ctx.Debug > 1 {
 		lib.Printf("Getting files for commit %s:%s\n", repo, sha)
 	}
 	dtStart := time.Now()
 	rwd := ctx.ReposDir + repo
-	filesStr, err := lib.ExecCommand(
-		ctx,
-		[]string{cmdPrefix + "git_files.sh", rwd, sha},
-		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
-	)
+	commit, err := lib.GitCommitFiles(ctx, rwd, sha)
 	dtEnd := time.Now()
 	if err != nil {
 		if ctx.Debug > 1 {
-			lib.Printf("Warning git_files.sh failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
-			fmt.Fprintf(os.Stderr, "Warning git_files.sh failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
+			lib.Printf("Warning getting commit files failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
+			fmt.Fprintf(os.Stderr, "Warning getting commit files failed: %s:%s (took %v): %+v\n", repo, sha, dtEnd.Sub(dtStart), err)
 		}
 		lib.ExecSQLWithErr(
 			con,
@@ -372,57 +355,67 @@ func getCommitFiles(ch chan int, ctx *lib.Ctx, con *sql.DB, filesSkipPattern *re
 		ch <- -1
 		return
 	}
-	files := strings.Split(filesStr, "\n")
 	nFiles := 0
-	var commitDate time.Time
+	commitDate := commit.CommitterDate
 
 	// Insert files in transaction: all or none
 	tx, err := con.Begin()
 	lib.FatalOnError(err)
-	for i, data := range files {
-		if i == 0 {
-			unixTimeStamp, err := strconv.ParseInt(data, 10, 64)
-			if err != nil {
-				lib.Printf("Invalid time returned for repo: %s, sha: %s: '%s'\n", repo, sha, data)
-			}
-			lib.FatalOnError(err)
-			commitDate = time.Unix(unixTimeStamp, 0)
-			// fmt.Printf("unixTimeStamp: %v, commitDate: %v\n", unixTimeStamp, commitDate)
+	// Commit author and committer identity from the repository
+	if commits.identities {
+		lib.ExecSQLTxWithErr(
+			tx,
+			ctx,
+			lib.InsertIgnore(
+				"into gha_commits_identities(sha, author_name, author_email, author_date, "+
+					"committer_name, committer_email, committer_date) "+lib.NValues(7),
+			),
+			lib.AnyArray{
+				sha,
+				lib.TruncToBytes(commit.AuthorName, 160),
+				lib.TruncToBytes(commit.AuthorEmail, 160),
+				commit.AuthorDate,
+				lib.TruncToBytes(commit.CommitterName, 160),
+				lib.TruncToBytes(commit.CommitterEmail, 160),
+				commit.CommitterDate,
+			}...,
+		)
+	}
+	// Companies the author was affiliated with at the author's date
+	if commits.attribution {
+		lib.AttributeCommitCompaniesTx(tx, ctx, sha)
+	}
+	for _, file := range commit.Files {
+		// If file matches exclude pattern, skip it
+		if file.Path == "" || (filesSkipPattern != nil && filesSkipPattern.MatchString(file.Path)) {
 			continue
 		}
-		fileData := strings.TrimSpace(data)
-		if fileData == "" {
+		lib.ExecSQLTxWithErr(
+			tx,
+			ctx,
+			lib.InsertIgnore("into gha_commits_files(sha, dt, path, size) "+lib.NValues(4)),
+			lib.AnyArray{sha, commitDate, file.Path, file.Size}...,
+		)
+		nFiles++
+		if !commits.lines {
 			continue
 		}
-		// Use '♂♀' separator to avoid any character that can appear inside file name
-		fileDataAry := strings.Split(fileData, "♂♀")
-		if len(fileDataAry) != 2 {
-			lib.Fatalf("invalid fileData returned for repo: %s, sha: %s: '%s'", repo, sha, fileData)
+		// Lines are not counted for binary files
+		var added, removed, oldPath interface{}
+		if !file.Binary {
+			added, removed = file.Added, file.Removed
 		}
-		fileName := fileDataAry[0]
-		// If file matches exclude pattern, skip it
-		if fileName == "" || (filesSkipPattern != nil && filesSkipPattern.MatchString(fileName)) {
-			continue
-		}
-		// fileSize can be:
-		// > 0 - normal file size
-		// 0 - file created - no contenets
-		// -1 - file referenced in the commit SHA but not found in this commit (means deleted)
-		// -2 - file size returned as "-" from git ls-tree - means some special file, directory
-		fileSize, err := strconv.ParseInt(fileDataAry[1], 10, 64)
-		if err != nil {
-			fileSize = -2
+		if file.OldPath != "" {
+			oldPath = file.OldPath
 		}
-		// fmt.Printf("repo: %v, sha: %v, fileData: %v, fileDataAry: %v, fileName: %v, fileSize: %v\n", repo, sha, fileData, fileDataAry, fileName, fileSize)
 		lib.ExecSQLTxWithErr(
 			tx,
 			ctx,
-			lib.InsertIgnore("into gha_commits_files(sha, dt, path, size) "+lib.NValues(4)),
-			lib.AnyArray{sha, commitDate, fileName, fileSize}...,
+			lib.InsertIgnore("into gha_commits_files_lines(sha, dt, path, old_path, status, added, removed, is_binary) "+lib.NValues(8)),
+			lib.AnyArray{sha, commitDate, file.Path, oldPath, file.Status, added, removed, file.Binary}...,
 		)
-		nFiles++
 	}
-	// Some commits have no files (for example only renames)
+	// Some commits have no files (for example merge commits)
 	// Mark them as skipped not to process again
 	if nFiles == 0 {
 		lib.ExecSQLTxWithErr(
@@ -522,8 +515,8 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 	// process all commits
 	ch := make(chan int)
 	nThreads = 0
-	for _, commits := range allCommits {
-		con := commits.con
+	for ci := range allCommits {
+		commits := &allCommits[ci]
 		filesSkipPattern := commits.filesSkipPattern
 		var re *regexp.Regexp
 		if filesSkipPattern != "" {
@@ -531,7 +524,7 @@ func processCommits(ctx *lib.Ctx, dbs map[string]string) {
 		}
 		for i, sha := range commits.shas {
 			repo := commits.repos[i]
-			go getCommitFiles(ch, ctx, con, re, repo, sha)
+			go getCommitFiles(ch, ctx, commits, re, repo, sha)
 			nThreads++
 			if nThreads == thrN {
 				statuses[<-ch]++
@@ -1,80 +1,151 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
+// rowWriter - writes rows directly to the DB (or current transaction) or collects them in bulk insert
+// Bulk mode is used when GHA2DB_BULK is set, then all rows from GHA hour are written at once
+// In bulk mode rows belong to the event ID `key`, so they can be removed when the event already exists
+type rowWriter struct {
+	db   *sql.DB
+	tx   *sql.Tx
+	bulk *lib.BulkInsert
+	key  string
+}
+
+// newRowWriter - returns writer using DB connection, or bulk insert if not nil
+func newRowWriter(db *sql.DB, bulk *lib.BulkInsert, key string) *rowWriter {
+	return &rowWriter{db: db, bulk: bulk, key: key}
+}
+
+// begin - starts transaction for data possibly shared between events, no-op in bulk mode
+func (w *rowWriter) begin() {
+	if w.bulk != nil {
+		return
+	}
+	tx, err := w.db.Begin()
+	lib.FatalOnError(err)
+	w.tx = tx
+}
+
+// commit - commits transaction started by begin
+func (w *rowWriter) commit() {
+	if w.tx == nil {
+		return
+	}
+	lib.FatalOnError(w.tx.Commit())
+	w.tx = nil
+}
+
+// exec - inserts single row, `into` is "into table(col1, ..., colN)"
+func (w *rowWriter) exec(ctx *lib.Ctx, ignore bool, into string, args ...interface{}) {
+	if w.bulk != nil {
+		w.bulk.AddFor(w.key, into, ignore, args...)
+		return
+	}
+	query := into + " " + lib.NValues(len(args))
+	if ignore {
+		query = lib.InsertIgnore(query)
+	} else {
+		query = "insert " + query
+	}
+	if w.tx != nil {
+		lib.ExecSQLTxWithErr(w.tx, ctx, query, args...)
+	} else {
+		lib.ExecSQLWithErr(w.db, ctx, query, args...)
+	}
+}
+
+// insert - inserts single row
+func (w *rowWriter) insert(ctx *lib.Ctx, into string, args ...interface{}) {
+	w.exec(ctx, false, into, args...)
+}
+
+// insertIgnore - inserts single row, skipping it if it already exists (see lib.InsertIgnore)
+func (w *rowWriter) insertIgnore(ctx *lib.Ctx, into string, args ...interface{}) {
+	w.exec(ctx, true, into, args...)
+}
+
+// query - runs query using current transaction or DB connection
+// In bulk mode it only sees rows already written to the DB (not rows waiting in bulk insert)
+func (w *rowWriter) query(ctx *lib.Ctx, query string, args ...interface{}) *sql.Rows {
+	if w.tx != nil {
+		return lib.QuerySQLTxWithErr(w.tx, ctx, query, args...)
+	}
+	return lib.QuerySQLWithErr(w.db, ctx, query, args...)
+}
+
 // Inserts single GHA Actor
-func ghaActor(con *sql.Tx, ctx *lib.Ctx, actor *lib.Actor) {
+func ghaActor(con *rowWriter, ctx *lib.Ctx, actor *lib.Actor) {
 	// gha_actors
 	// {"id:Fixnum"=>48592, "login:String"=>48592, "display_login:String"=>48592,
 	// "gravatar_id:String"=>48592, "url:String"=>48592, "avatar_url:String"=>48592}
 	// {"id"=>8, "login"=>34, "display_login"=>34, "gravatar_id"=>0, "url"=>63, "avatar_url"=>49}
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insertIgnore(
 		ctx,
-		lib.InsertIgnore("into gha_actors(id, login, name) "+lib.NValues(3)),
+		"into gha_actors(id, login, name)",
 		lib.AnyArray{actor.ID, actor.Login, ""}...,
 	)
 }
 
 // Inserts single GHA Repo
-func ghaRepo(db *sql.DB, ctx *lib.Ctx, repo *lib.Repo, orgID, orgLogin interface{}) {
+func ghaRepo(con *rowWriter, ctx *lib.Ctx, repo *lib.Repo, orgID, orgLogin interface{}) {
 	// gha_repos
 	// {"id:Fixnum"=>48592, "name:String"=>48592, "url:String"=>48592}
 	// {"id"=>8, "name"=>111, "url"=>140}
-	lib.ExecSQLWithErr(
-		db,
+	con.insertIgnore(
 		ctx,
-		lib.InsertIgnore("into gha_repos(id, name, org_id, org_login) "+lib.NValues(4)),
+		"into gha_repos(id, name, org_id, org_login)",
 		lib.AnyArray{repo.ID, repo.Name, orgID, orgLogin}...,
 	)
 }
 
 // Inserts single GHA Org
-func ghaOrg(db *sql.DB, ctx *lib.Ctx, org *lib.Org) {
+func ghaOrg(con *rowWriter, ctx *lib.Ctx, org *lib.Org) {
 	// gha_orgs
 	// {"id:Fixnum"=>18494, "login:String"=>18494, "gravatar_id:String"=>18494,
 	// "url:String"=>18494, "avatar_url:String"=>18494}
 	// {"id"=>8, "login"=>38, "gravatar_id"=>0, "url"=>66, "avatar_url"=>49}
 	if org != nil {
-		lib.ExecSQLWithErr(
-			db,
+		con.insertIgnore(
 			ctx,
-			lib.InsertIgnore("into gha_orgs(id, login) "+lib.NValues(2)),
+			"into gha_orgs(id, login)",
 			lib.AnyArray{org.ID, org.Login}...,
 		)
 	}
 }
 
 // Inserts single GHA Milestone
-func ghaMilestone(con *sql.Tx, ctx *lib.Ctx, eid string, milestone *lib.Milestone, ev *lib.Event) {
+func ghaMilestone(con *rowWriter, ctx *lib.Ctx, eid string, milestone *lib.Milestone, ev *lib.Event) {
 	// creator
 	if milestone.Creator != nil {
 		ghaActor(con, ctx, milestone.Creator)
 	}
 
 	// gha_milestones
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_milestones("+
+		"into gha_milestones("+
 			"id, event_id, closed_at, closed_issues, created_at, creator_id, "+
 			"description, due_on, number, open_issues, state, title, updated_at, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dupn_creator_login) "+lib.NValues(20),
+			"dupn_creator_login)",
 		lib.AnyArray{
 			milestone.ID,
 			eid,
@@ -101,7 +172,7 @@ func ghaMilestone(con *sql.Tx, ctx *lib.Ctx, eid string, milestone *lib.Mileston
 }
 
 // Inserts single GHA Forkee (old format < 2015)
-func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld, actor *lib.Actor, repo *lib.Repo, ev *lib.EventOld) {
+func ghaForkeeOld(con *rowWriter, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld, actor *lib.Actor, repo *lib.Repo, ev *lib.EventOld) {
 
 	// Lookup author by GitHub login
 	aid := lookupActorTx(con, ctx, forkee.Owner)
@@ -112,16 +183,15 @@ func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld,
 
 	// gha_forkees
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/forkee_*.json`
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_forkees("+
+		"into gha_forkees("+
 			"id, event_id, name, full_name, owner_id, description, fork, "+
 			"created_at, updated_at, pushed_at, homepage, size, language, organization, "+
 			"stargazers_count, has_issues, has_projects, has_downloads, "+
 			"has_wiki, has_pages, forks, default_branch, open_issues, watchers, public, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_owner_login) "+lib.NValues(32),
+			"dup_owner_login)",
 		lib.AnyArray{
 			forkee.ID,
 			eid,
@@ -160,22 +230,21 @@ func ghaForkeeOld(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.ForkeeOld,
 }
 
 // Inserts single GHA Forkee
-func ghaForkee(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.Forkee, ev *lib.Event) {
+func ghaForkee(con *rowWriter, ctx *lib.Ctx, eid string, forkee *lib.Forkee, ev *lib.Event) {
 	// owner
 	ghaActor(con, ctx, &forkee.Owner)
 
 	// gha_forkees
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/forkee_*.json`
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_forkees("+
+		"into gha_forkees("+
 			"id, event_id, name, full_name, owner_id, description, fork, "+
 			"created_at, updated_at, pushed_at, homepage, size, language, organization, "+
 			"stargazers_count, has_issues, has_projects, has_downloads, "+
 			"has_wiki, has_pages, forks, default_branch, open_issues, watchers, public, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_owner_login) "+lib.NValues(32),
+			"dup_owner_login)",
 		lib.AnyArray{
 			forkee.ID,
 			eid,
@@ -214,7 +283,7 @@ func ghaForkee(con *sql.Tx, ctx *lib.Ctx, eid string, forkee *lib.Forkee, ev *li
 }
 
 // Inserts single GHA Branch
-func ghaBranch(con *sql.Tx, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *lib.Event, skipIDs []int) {
+func ghaBranch(con *rowWriter, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *lib.Event, skipIDs []int) {
 	// user
 	if branch.User != nil {
 		ghaActor(con, ctx, branch.User)
@@ -236,13 +305,12 @@ func ghaBranch(con *sql.Tx, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *li
 	}
 
 	// gha_branches
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_branches("+
+		"into gha_branches("+
 			"sha, event_id, user_id, repo_id, label, ref, "+
 			"dup_type, dup_created_at, dupn_user_login, dupn_forkee_name"+
-			") "+lib.NValues(10),
+			")",
 		lib.AnyArray{
 			branch.SHA,
 			eid,
@@ -260,9 +328,8 @@ func ghaBranch(con *sql.Tx, ctx *lib.Ctx, eid string, branch *lib.Branch, ev *li
 
 // Search for given label using name & color
 // If not found, return hash as its ID
-func lookupLabel(con *sql.Tx, ctx *lib.Ctx, name string, color string) int {
-	rows := lib.QuerySQLTxWithErr(
-		con,
+func lookupLabel(con *rowWriter, ctx *lib.Ctx, name string, color string) int {
+	rows := con.query(
 		ctx,
 		fmt.Sprintf(
 			"select id from gha_labels where name=%s and color=%s",
@@ -307,9 +374,8 @@ func lookupActor(db *sql.DB, ctx *lib.Ctx, login string) int {
 
 // Search for given actor using his/her login
 // If not found, return hash as its ID
-func lookupActorTx(con *sql.Tx, ctx *lib.Ctx, login string) int {
-	rows := lib.QuerySQLTxWithErr(
-		con,
+func lookupActorTx(con *rowWriter, ctx *lib.Ctx, login string) int {
+	rows := con.query(
 		ctx,
 		fmt.Sprintf("select id from gha_actors where login=%s", lib.NValue(1)),
 		login,
@@ -404,20 +470,18 @@ func eventExists(db *sql.DB, ctx *lib.Ctx, eventID string) bool {
 // "action:String"=>370, "sha:String"=>370, "html_url:String"=>370}
 // {"page_name"=>65, "title"=>65, "summary"=>0, "action"=>7, "sha"=>40, "html_url"=>130}
 // 370
-func ghaPages(con *sql.Tx, ctx *lib.Ctx, payloadPages *[]lib.Page, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaPages(con *rowWriter, ctx *lib.Ctx, payloadPages *[]lib.Page, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	pages := []lib.Page{}
 	if payloadPages != nil {
 		pages = *payloadPages
 	}
 	for _, page := range pages {
 		sha := page.SHA
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insertIgnore(
 			ctx,
-			lib.InsertIgnore(
-				"into gha_pages(sha, event_id, action, title, "+
-					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-					") "+lib.NValues(10)),
+			"into gha_pages(sha, event_id, action, title, "+
+				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
+				")",
 			lib.AnyArray{
 				sha,
 				eventID,
@@ -436,7 +500,7 @@ func ghaPages(con *sql.Tx, ctx *lib.Ctx, payloadPages *[]lib.Page, eventID strin
 
 // gha_comments
 // Table details and analysis in `analysis/analysis.txt` and `analysis/comment_*.json`
-func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaComment(con *rowWriter, ctx *lib.Ctx, payloadComment *lib.Comment, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadComment == nil {
 		return
 	}
@@ -447,17 +511,14 @@ func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID
 
 	// comment
 	cid := comment.ID
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insertIgnore(
 		ctx,
-		lib.InsertIgnore(
-			"into gha_comments("+
-				"id, event_id, body, created_at, updated_at, user_id, "+
-				"commit_id, original_commit_id, diff_hunk, position, "+
-				"original_position, path, pull_request_review_id, line, "+
-				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login) "+lib.NValues(21),
-		),
+		"into gha_comments("+
+			"id, event_id, body, created_at, updated_at, user_id, "+
+			"commit_id, original_commit_id, diff_hunk, position, "+
+			"original_position, path, pull_request_review_id, line, "+
+			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+			"dup_user_login)",
 		lib.AnyArray{
 			cid,
 			eventID,
@@ -486,7 +547,7 @@ func ghaComment(con *sql.Tx, ctx *lib.Ctx, payloadComment *lib.Comment, eventID
 
 // gha_releases
 // Table details and analysis in `analysis/analysis.txt` and `analysis/release_*.json`
-func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaRelease(con *rowWriter, ctx *lib.Ctx, payloadRelease *lib.Release, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadRelease == nil {
 		return
 	}
@@ -497,14 +558,13 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 	// release
 	rid := release.ID
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_releases("+
+		"into gha_releases("+
 			"id, event_id, tag_name, target_commitish, name, draft, "+
 			"author_id, prerelease, created_at, published_at, body, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_author_login) "+lib.NValues(18),
+			"dup_author_login)",
 		lib.AnyArray{
 			rid,
 			eventID,
@@ -534,14 +594,13 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 		// asset
 		aid := asset.ID
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_assets("+
+			"into gha_assets("+
 				"id, event_id, name, label, uploader_id, content_type, "+
 				"state, size, download_count, created_at, updated_at, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_uploader_login) "+lib.NValues(18),
+				"dup_uploader_login)",
 			lib.AnyArray{
 				aid,
 				eventID,
@@ -565,10 +624,9 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 		)
 
 		// release-asset connection
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_releases_assets(release_id, event_id, asset_id) "+lib.NValues(3),
+			"into gha_releases_assets(release_id, event_id, asset_id)",
 			lib.AnyArray{rid, eventID, aid}...,
 		)
 	}
@@ -576,7 +634,7 @@ func ghaRelease(con *sql.Tx, ctx *lib.Ctx, payloadRelease *lib.Release, eventID
 
 // gha_pull_requests
 // Table details and analysis in `analysis/analysis.txt` and `analysis/pull_request_*.json`
-func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullRequest, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time, forkeeIDsToSkip []int) {
+func ghaPullRequest(con *rowWriter, ctx *lib.Ctx, payloadPullRequest *lib.PullRequest, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time, forkeeIDsToSkip []int) {
 	if payloadPullRequest == nil {
 		return
 	}
@@ -622,16 +680,15 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 
 	// pull_request
 	prid := pr.ID
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_pull_requests("+
+		"into gha_pull_requests("+
 			"id, event_id, user_id, base_sha, head_sha, merged_by_id, assignee_id, milestone_id, "+
 			"number, state, locked, title, body, created_at, updated_at, closed_at, merged_at, "+
 			"merge_commit_sha, merged, mergeable, rebaseable, mergeable_state, comments, "+
 			"review_comments, maintainer_can_modify, commits, additions, deletions, changed_files, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-			"dup_user_login, dupn_assignee_login, dupn_merged_by_login) "+lib.NValues(38),
+			"dup_user_login, dupn_assignee_login, dupn_merged_by_login)",
 		lib.AnyArray{
 			prid,
 			eventID,
@@ -698,10 +755,9 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 		ghaActor(con, ctx, &assignee)
 
 		// pull_request-assignee connection
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_pull_requests_assignees(pull_request_id, event_id, assignee_id) "+lib.NValues(3),
+			"into gha_pull_requests_assignees(pull_request_id, event_id, assignee_id)",
 			lib.AnyArray{prid, eventID, assignee.ID}...,
 		)
 	}
@@ -713,10 +769,9 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 			ghaActor(con, ctx, &reviewer)
 
 			// pull_request-requested_reviewer connection
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insert(
 				ctx,
-				"insert into gha_pull_requests_requested_reviewers(pull_request_id, event_id, requested_reviewer_id) "+lib.NValues(3),
+				"into gha_pull_requests_requested_reviewers(pull_request_id, event_id, requested_reviewer_id)",
 				lib.AnyArray{prid, eventID, reviewer.ID}...,
 			)
 		}
@@ -724,7 +779,7 @@ func ghaPullRequest(con *sql.Tx, ctx *lib.Ctx, payloadPullRequest *lib.PullReque
 }
 
 // gha_teams
-func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.Forkee, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
+func ghaTeam(con *rowWriter, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.Forkee, eventID string, actor *lib.Actor, repo *lib.Repo, eType string, eCreatedAt time.Time) {
 	if payloadTeam == nil {
 		return
 	}
@@ -732,13 +787,12 @@ func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.
 
 	// team
 	tid := team.ID
-	lib.ExecSQLTxWithErr(
-		con,
+	con.insert(
 		ctx,
-		"insert into gha_teams("+
+		"into gha_teams("+
 			"id, event_id, name, slug, permission, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(11),
+			")",
 		lib.AnyArray{
 			tid,
 			eventID,
@@ -756,10 +810,9 @@ func ghaTeam(con *sql.Tx, ctx *lib.Ctx, payloadTeam *lib.Team, payloadRepo *lib.
 
 	// team-repository connection
 	if payloadRepo != nil {
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_teams_repositories(team_id, event_id, repository_id) "+lib.NValues(3),
+			"into gha_teams_repositories(team_id, event_id, repository_id)",
 			lib.AnyArray{tid, eventID, payloadRepo.ID}...,
 		)
 	}
@@ -771,6 +824,9 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 		return 0
 	}
 
+	// Old format events are always written row by row
+	con := newRowWriter(db, nil, eventID)
+
 	// Lookup author by GitHub login
 	aid := lookupActor(db, ctx, ev.Actor)
 	actor := lib.Actor{ID: aid, Login: ev.Actor}
@@ -788,12 +844,11 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 	}
 
 	// We defer transaction create until we're inserting data that can be shared between different events
-	lib.ExecSQLWithErr(
-		db,
+	con.insert(
 		ctx,
-		"insert into gha_events("+
+		"into gha_events("+
 			"id, type, actor_id, repo_id, public, created_at, "+
-			"dup_actor_login, dup_repo_name, org_id, forkee_id) "+lib.NValues(10),
+			"dup_actor_login, dup_repo_name, org_id, forkee_id)",
 		lib.AnyArray{
 			eventID,
 			ev.Type,
@@ -814,12 +869,12 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 			h := lib.HashStrings([]string{*repository.Organization})
 			oid = &h
 		}
-		ghaOrg(db, ctx, &lib.Org{ID: *oid, Login: *repository.Organization})
+		ghaOrg(con, ctx, &lib.Org{ID: *oid, Login: *repository.Organization})
 	}
 
 	// Add Repository
 	repo := lib.Repo{ID: rid, Name: repository.Name}
-	ghaRepo(db, ctx, &repo, oid, repository.Organization)
+	ghaRepo(con, ctx, &repo, oid, repository.Organization)
 
 	// Pre 2015 Payload
 	pl := ev.Payload
@@ -833,15 +888,14 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 		cid = lib.IntOrNil(pl.CommentID)
 	}
 
-	lib.ExecSQLWithErr(
-		db,
+	con.insert(
 		ctx,
-		"insert into gha_payloads("+
+		"into gha_payloads("+
 			"event_id, push_id, size, ref, head, befor, action, "+
 			"issue_id, pull_request_id, comment_id, ref_type, master_branch, commit, "+
 			"description, number, forkee_id, release_id, member_id, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(24),
+			")",
 		lib.AnyArray{
 			eventID,
 			nil,
@@ -871,8 +925,7 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 	)
 
 	// Start transaction for data possibly shared between events
-	con, err := db.Begin()
-	lib.FatalOnError(err)
+	con.begin()
 
 	// gha_actors
 	ghaActor(con, ctx, &actor)
@@ -904,13 +957,12 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 			if !ok {
 				lib.Fatalf("commit[0] is not string: %+v", commit[0])
 			}
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insert(
 				ctx,
-				"insert into gha_commits("+
+				"into gha_commits("+
 					"sha, event_id, author_name, message, is_distinct, "+
 					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-					") "+lib.NValues(11),
+					")",
 				lib.AnyArray{
 					sha,
 					eventID,
@@ -969,14 +1021,13 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 		if pr.Locked != nil {
 			locked = *pr.Locked
 		}
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_issues("+
+			"into gha_issues("+
 				"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
 				"locked, milestone_id, number, state, title, updated_at, user_id, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login, dupn_assignee_login, is_pull_request) "+lib.NValues(23),
+				"dup_user_login, dupn_assignee_login, is_pull_request)",
 			lib.AnyArray{
 				iid,
 				eventID,
@@ -1023,26 +1074,28 @@ func writeToDBOldFmt(db *sql.DB, ctx *lib.Ctx, eventID string, ev *lib.EventOld)
 
 		for _, assignee := range assignees {
 			// pull_request-assignee connection
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insert(
 				ctx,
-				"insert into gha_issues_assignees(issue_id, event_id, assignee_id) "+lib.NValues(3),
+				"into gha_issues_assignees(issue_id, event_id, assignee_id)",
 				lib.AnyArray{iid, eventID, assignee.ID}...,
 			)
 		}
 	}
 
 	// Final commit
-	lib.FatalOnError(con.Commit())
+	con.commit()
 	return 1
 }
 
 // Write entire GHA event (in a new 2015+ format) into Postgres DB
-func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
+// When bulk is not nil, rows are only collected in bulk and written when it is flushed
+// Events already in the DB are then removed from bulk before it is flushed, using a single query
+func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event, bulk *lib.BulkInsert) int {
 	eventID := ev.ID
-	if eventExists(db, ctx, eventID) {
+	if (bulk != nil && !bulk.AddKey(eventID)) || (bulk == nil && eventExists(db, ctx, eventID)) {
 		return 0
 	}
+	con := newRowWriter(db, bulk, eventID)
 
 	// We defer transaction create until we're inserting data that can be shared between different events
 	// gha_events
@@ -1053,12 +1106,11 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	// "created_at"=>20, "org"=>230}
 	// Fields dup_actor_login, dup_repo_name are copied from (gha_actors and gha_repos) to save
 	// joins on complex queries (MySQL has no hash joins and is very slow on big tables joins)
-	lib.ExecSQLWithErr(
-		db,
+	con.insert(
 		ctx,
-		"insert into gha_events("+
+		"into gha_events("+
 			"id, type, actor_id, repo_id, public, created_at, "+
-			"dup_actor_login, dup_repo_name, org_id, forkee_id) "+lib.NValues(10),
+			"dup_actor_login, dup_repo_name, org_id, forkee_id)",
 		lib.AnyArray{
 			eventID,
 			ev.Type,
@@ -1076,11 +1128,11 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	// Repository
 	repo := ev.Repo
 	org := ev.Org
-	ghaRepo(db, ctx, &repo, lib.OrgIDOrNil(org), lib.OrgLoginOrNil(org))
+	ghaRepo(con, ctx, &repo, lib.OrgIDOrNil(org), lib.OrgLoginOrNil(org))
 
 	// Organization
 	if org != nil {
-		ghaOrg(db, ctx, org)
+		ghaOrg(con, ctx, org)
 	}
 
 	// gha_payloads
@@ -1099,15 +1151,14 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	// using exec_stmt (without select), because payload are per event_id.
 	// Columns duplicated from gha_events starts with "dup_"
 	pl := ev.Payload
-	lib.ExecSQLWithErr(
-		db,
+	con.insert(
 		ctx,
-		"insert into gha_payloads("+
+		"into gha_payloads("+
 			"event_id, push_id, size, ref, head, befor, action, "+
 			"issue_id, pull_request_id, comment_id, ref_type, master_branch, commit, "+
 			"description, number, forkee_id, release_id, member_id, "+
 			"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-			") "+lib.NValues(24),
+			")",
 		lib.AnyArray{
 			eventID,
 			lib.IntOrNil(pl.PushID),
@@ -1137,8 +1188,7 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	)
 
 	// Start transaction for data possibly shared between events
-	con, err := db.Begin()
-	lib.FatalOnError(err)
+	con.begin()
 
 	// gha_actors
 	ghaActor(con, ctx, &ev.Actor)
@@ -1156,13 +1206,12 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	}
 	for _, commit := range commits {
 		sha := commit.SHA
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_commits("+
+			"into gha_commits("+
 				"sha, event_id, author_name, message, is_distinct, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at"+
-				") "+lib.NValues(11),
+				")",
 			lib.AnyArray{
 				sha,
 				eventID,
@@ -1207,14 +1256,13 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 		if issue.PullRequest != nil {
 			isPR = true
 		}
-		lib.ExecSQLTxWithErr(
-			con,
+		con.insert(
 			ctx,
-			"insert into gha_issues("+
+			"into gha_issues("+
 				"id, event_id, assignee_id, body, closed_at, comments, created_at, "+
 				"locked, milestone_id, number, state, title, updated_at, user_id, "+
 				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-				"dup_user_login, dupn_assignee_login, is_pull_request) "+lib.NValues(23),
+				"dup_user_login, dupn_assignee_login, is_pull_request)",
 			lib.AnyArray{
 				iid,
 				eventID,
@@ -1258,10 +1306,9 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			ghaActor(con, ctx, &assignee)
 
 			// issue-assignee connection
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insert(
 				ctx,
-				"insert into gha_issues_assignees(issue_id, event_id, assignee_id) "+lib.NValues(3),
+				"into gha_issues_assignees(issue_id, event_id, assignee_id)",
 				lib.AnyArray{iid, eventID, aid}...,
 			)
 		}
@@ -1274,22 +1321,19 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 			}
 
 			// label
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insertIgnore(
 				ctx,
-				lib.InsertIgnore("into gha_labels(id, name, color, is_default) "+lib.NValues(4)),
+				"into gha_labels(id, name, color, is_default)",
 				lib.AnyArray{lid, lib.TruncToBytes(label.Name, 160), label.Color, lib.BoolOrNil(label.Default)}...,
 			)
 
 			// issue-label connection
-			lib.ExecSQLTxWithErr(
-				con,
+			con.insertIgnore(
 				ctx,
-				lib.InsertIgnore(
-					"into gha_issues_labels(issue_id, event_id, label_id, "+
-						"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
-						"dup_issue_number, dup_label_name"+
-						") "+lib.NValues(11)),
+				"into gha_issues_labels(issue_id, event_id, label_id, "+
+					"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+					"dup_issue_number, dup_label_name"+
+					")",
 				lib.AnyArray{
 					iid,
 					eventID,
@@ -1319,12 +1363,13 @@ func writeToDB(db *sql.DB, ctx *lib.Ctx, ev *lib.Event) int {
 	ghaPullRequest(con, ctx, pl.PullRequest, eventID, &ev.Actor, &ev.Repo, ev.Type, ev.CreatedAt, []int{})
 
 	// Final commit
-	lib.FatalOnError(con.Commit())
+	con.commit()
 	return 1
 }
 
 // parseJSON - parse signle GHA JSON event
-func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, frepo map[string]struct{}) (f int, e int) {
+// Dates of written events are added to `dirty`
+func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, frepo map[string]struct{}, filter *lib.EventFilter, bulk *lib.BulkInsert, dirty *lib.DirtyRange) (f int, e int) {
 	var (
 		h         lib.Event
 		hOld      lib.EventOld
@@ -1346,14 +1391,18 @@ func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, fr
 		fmt.Fprintf(os.Stderr, "%v: JSON Unmarshal failed for:\n'%v'\n", dt, string(pretty))
 	}
 	lib.FatalOnError(err)
+	fev := lib.FilterEvent{JSON: jsonStr}
 	if ctx.OldFormat {
 		fullName = lib.MakeOldRepoName(&hOld.Repository)
 		actorName = hOld.Actor
+		fev.Type, fev.CreatedAt = hOld.Type, hOld.CreatedAt
 	} else {
 		fullName = h.Repo.Name
 		actorName = h.Actor.Login
+		fev.Type, fev.CreatedAt = h.Type, h.CreatedAt
 	}
-	if lib.RepoHit(ctx, fullName, forg, frepo) && lib.ActorHit(ctx, actorName) {
+	fev.Repo, fev.Actor = fullName, actorName
+	if filter.Hit(&fev, lib.RepoHit(ctx, fullName, forg, frepo) && lib.ActorHit(ctx, actorName)) {
 		if ctx.OldFormat {
 			eid = fmt.Sprintf("%v", lib.HashStrings([]string{hOld.Type, hOld.Actor, hOld.Repository.Name, lib.ToYMDHMSDate(hOld.CreatedAt)}))
 		} else {
@@ -1369,7 +1418,10 @@ func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, fr
 			if ctx.OldFormat {
 				e = writeToDBOldFmt(con, ctx, eid, &hOld)
 			} else {
-				e = writeToDB(con, ctx, &h)
+				e = writeToDB(con, ctx, &h, bulk)
+			}
+			if e > 0 {
+				dirty.Add(fev.CreatedAt)
 			}
 		}
 		if ctx.Debug >= 1 {
@@ -1380,33 +1432,78 @@ func parseJSON(con *sql.DB, ctx *lib.Ctx, jsonStr []byte, dt time.Time, forg, fr
 	return
 }
 
+// parseJSONs - worker that parses JSONs received from `jsons` channel until it is closed
+// Sends number of matching JSONs and events processed to `ch` when finished
+func parseJSONs(ch chan [2]int, jsons chan []byte, con *sql.DB, ctx *lib.Ctx, dt time.Time, forg, frepo map[string]struct{}, filter *lib.EventFilter, bulk *lib.BulkInsert, dirty *lib.DirtyRange) {
+	f, e := 0, 0
+	for json := range jsons {
+		fi, ei := parseJSON(con, ctx, json, dt, forg, frepo, filter, bulk, dirty)
+		f += fi
+		e += ei
+	}
+	ch <- [2]int{f, e}
+}
+
+// GHA hours processed counter name and description
+const (
+	promHours     = "devstats_gha_hours_total"
+	promHoursHelp = "GHA hours processed by status."
+)
+
 // getGHAJSON - This is a work for single go routine - 1 hour of GHA data
 // Usually such JSON conatin about 15000 - 60000 singe GHA events
+// JSONs are read from gzip stream one by one and parsed by GHA2DB_PARSE_WORKERS workers
+// So we never hold the entire decompressed hour in memory
 // Boolean channel `ch` is used to synchronize go routines
-func getGHAJSON(ch chan bool, ctx *lib.Ctx, dt time.Time, forg map[string]struct{}, frepo map[string]struct{}) {
+func getGHAJSON(ch chan bool, ctx *lib.Ctx, dt time.Time, forg map[string]struct{}, frepo map[string]struct{}, filter *lib.EventFilter) {
 	lib.Printf("Working on %v\n", dt)
+	dtStart := time.Now()
 
 	// Connect to Postgres DB
 	con := lib.PgConn(ctx)
 	defer func() { lib.FatalOnError(con.Close()) }()
 
-	fn := fmt.Sprintf("http://data.gharchive.org/%s.json.gz", lib.ToGHADate(dt))
+	// Mark hour as started, it will stay in this state if we crash
+	if ctx.DBOut {
+		lib.SetImportedHour(con, ctx, dt, lib.ImportStarted, 0, 0, 0)
+	}
+
+	fn := lib.GHAArchivePath(ctx, dt)
 
-	// Get gzipped JSON array via HTTP
-	response, err := http.Get(fn)
+	// Get gzipped JSON array from cache, local mirror or via HTTP
+	response, err := lib.GetGHAArchive(ctx, dt)
 	if err != nil {
-		lib.Printf("%v: Error http.Get:\n%v\n", dt, err)
-		fmt.Fprintf(os.Stderr, "%v: Error http.Get:\n%v\n", dt, err)
+		status := lib.ImportFailed
+		if os.IsNotExist(err) {
+			status = lib.ImportNoData
+			lib.Printf("%v: No data, missing GHA archive:\n%v\n", dt, err)
+			fmt.Fprintf(os.Stderr, "%v: No data, missing GHA archive:\n%v\n", dt, err)
+		} else {
+			// For example 5xx HTTP status, hour is marked as failed, so it will be retried
+			lib.Printf("%v: Error getting GHA archive:\n%v\n", dt, err)
+			fmt.Fprintf(os.Stderr, "%v: Error getting GHA archive:\n%v\n", dt, err)
+		}
+		if ctx.DBOut {
+			lib.SetImportedHour(con, ctx, dt, status, 0, 0, 0)
+		}
+		lib.PromAdd(promHours, promHoursHelp, 1, "status", status)
+		if ch != nil {
+			ch <- true
+		}
+		return
 	}
-	lib.FatalOnError(err)
-	defer func() { _ = response.Body.Close() }()
+	defer func() { _ = response.Close() }()
 
 	// Decompress Gzipped response
-	reader, err := gzip.NewReader(response.Body)
+	reader, err := gzip.NewReader(response)
 	//lib.FatalOnError(err)
 	if err != nil {
 		lib.Printf("%v: No data yet, gzip reader:\n%v\n", dt, err)
 		fmt.Fprintf(os.Stderr, "%v: No data yet, gzip reader:\n%v\n", dt, err)
+		if ctx.DBOut {
+			lib.SetImportedHour(con, ctx, dt, lib.ImportNoData, 0, 0, 0)
+		}
+		lib.PromAdd(promHours, promHoursHelp, 1, "status", lib.ImportNoData)
 		if ch != nil {
 			ch <- true
 		}
@@ -1415,42 +1512,127 @@ func getGHAJSON(ch chan bool, ctx *lib.Ctx, dt time.Time, forg map[string]struct
 	lib.Printf("Opened %s\n", fn)
 	defer func() { _ = reader.Close() }()
 
-	jsonsBytes, err := ioutil.ReadAll(reader)
-	//lib.FatalOnError(err)
-	if err != nil {
-		lib.Printf("%v: Error (no data yet, ioutil readall):\n%v\n", dt, err)
-		fmt.Fprintf(os.Stderr, "%v: Error (no data yet, ioutil readall):\n%v\n", dt, err)
-		if ch != nil {
-			ch <- true
-		}
-		return
+	// In bulk mode all rows of this hour are collected and written at once
+	var bulk *lib.BulkInsert
+	if ctx.Bulk && ctx.DBOut && !ctx.OldFormat {
+		bulk = lib.NewBulkInsert()
 	}
-	lib.Printf("Decompressed %s\n", fn)
 
-	// Split JSON array into separate JSONs
-	jsonsArray := bytes.Split(jsonsBytes, []byte("\n"))
-	lib.Printf("Splitted %s, %d JSONs\n", fn, len(jsonsArray))
+	// Collect dates of written events, `gha2db_sync` in incremental mode recomputes only periods touched by them
+	var dirty lib.DirtyRange
 
-	// Process JSONs one by one
+	// Start parse workers, JSONs channel is buffered so reader can stay a bit ahead of workers
+	nWorkers := ctx.ParseWorkers
+	var (
+		jsons   chan []byte
+		results chan [2]int
+	)
+	if nWorkers > 1 {
+		jsons = make(chan []byte, 2*nWorkers)
+		results = make(chan [2]int)
+		for i := 0; i < nWorkers; i++ {
+			go parseJSONs(results, jsons, con, ctx, dt, forg, frepo, filter, bulk, &dirty)
+		}
+	}
+
+	// Read newline separated JSONs one by one
 	n, f, e := 0, 0, 0
-	for _, json := range jsonsArray {
-		if len(json) < 1 {
-			continue
+	status := lib.ImportOK
+	bufReader := bufio.NewReaderSize(reader, 0x100000)
+	for {
+		line, err := bufReader.ReadBytes('\n')
+		json := bytes.TrimSpace(line)
+		if len(json) > 0 {
+			n++
+			if jsons != nil {
+				jsons <- json
+			} else {
+				fi, ei := parseJSON(con, ctx, json, dt, forg, frepo, filter, bulk, &dirty)
+				f += fi
+				e += ei
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			lib.Printf("%v: Error (no more data, reading after %d JSONs):\n%v\n", dt, n, err)
+			fmt.Fprintf(os.Stderr, "%v: Error (no more data, reading after %d JSONs):\n%v\n", dt, n, err)
+			status = lib.ImportFailed
+			break
 		}
-		fi, ei := parseJSON(con, ctx, json, dt, forg, frepo)
-		n++
-		f += fi
-		e += ei
+	}
+
+	// Wait for workers
+	if jsons != nil {
+		close(jsons)
+		for i := 0; i < nWorkers; i++ {
+			res := <-results
+			f += res[0]
+			e += res[1]
+		}
+	}
+	if bulk != nil {
+		e -= bulk.RemoveExisting(con, ctx, "select id from gha_events where id = any($1::bigint[])")
 	}
 	lib.Printf(
 		"Parsed: %s: %d JSONs, found %d matching, events %d\n",
 		fn, n, f, e,
 	)
+	if bulk != nil {
+		rows := bulk.Len()
+		bulk.Flush(con, ctx)
+		lib.Printf("%v: bulk inserted %d rows\n", dt, rows)
+	}
+	if ctx.DBOut {
+		// When healing an hour, some of its events could have been written before the crash, so mark the whole hour
+		if ctx.MissingHours {
+			dirty.Add(dt)
+			dirty.Add(lib.NextHourStart(dt).Add(-time.Second))
+		}
+		dirty.Save(con, ctx, "gha2db")
+		lib.SetImportedHour(con, ctx, dt, status, n, f, e)
+	}
+	lib.PromAdd(promHours, promHoursHelp, 1, "status", status)
+	lib.PromAdd("devstats_gha_jsons_total", "JSONs (events) read from GHA archives.", float64(n))
+	lib.PromAdd("devstats_gha_jsons_matched_total", "JSONs matching orgs, repos, actors and event filter.", float64(f))
+	lib.PromAdd("devstats_gha_events_written_total", "GHA events written to the database.", float64(e))
+	lib.PromObserveSince("devstats_gha_hour_duration_seconds", "GHA hour import duration.", lib.PromPhaseBuckets, dtStart, "status", status)
 	if ch != nil {
 		ch <- true
 	}
 }
 
+// getEventFilter - returns event filter defined for current project (GHA2DB_PROJECT) in `projects.yaml`
+// Returns nil when no project is set or project has no `event_filter` rules
+func getEventFilter(ctx *lib.Ctx) *lib.EventFilter {
+	if ctx.Project == "" {
+		return nil
+	}
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := lib.ReadFile(ctx, dataPrefix+ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	proj, ok := projects.Projects[ctx.Project]
+	if !ok {
+		return nil
+	}
+	filter, err := lib.NewEventFilter(proj.EventFilter)
+	lib.FatalOnError(err)
+	if filter != nil {
+		lib.Printf("Using %d event filter rule(s) for project '%s'\n", len(proj.EventFilter), ctx.Project)
+	}
+	return filter
+}
+
 // gha2db - main work horse
 func gha2db(args []string) {
 	// Environment context parse
@@ -1463,6 +1645,8 @@ func gha2db(args []string) {
 		dTo      time.Time
 	)
 	ctx.Init()
+	lib.PromStart(&ctx, "gha2db")
+	defer lib.PromFinish(&ctx)
 
 	// Current date
 	now := time.Now()
@@ -1524,6 +1708,9 @@ func gha2db(args []string) {
 		)
 	}
 
+	// Project's event filter from `projects.yaml` (if any)
+	filter := getEventFilter(&ctx)
+
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
 	lib.Printf(
@@ -1533,13 +1720,20 @@ func gha2db(args []string) {
 		strings.Join(lib.StringsSetKeys(repo), "+"),
 	)
 
-	dt := dFrom
+	// All hours in range or only those not imported yet
+	hours := lib.MissingHours(dFrom, dTo, map[time.Time]struct{}{})
+	if ctx.MissingHours {
+		con := lib.PgConn(&ctx)
+		hours = lib.MissingHours(dFrom, dTo, lib.GetImportedHours(con, &ctx, dFrom, dTo))
+		lib.FatalOnError(con.Close())
+		lib.Printf("Missing hours mode: %d hours to import\n", len(hours))
+	}
+
 	if thrN > 1 {
 		ch := make(chan bool)
 		nThreads := 0
-		for dt.Before(dTo) || dt.Equal(dTo) {
-			go getGHAJSON(ch, &ctx, dt, org, repo)
-			dt = dt.Add(time.Hour)
+		for _, dt := range hours {
+			go getGHAJSON(ch, &ctx, dt, org, repo, filter)
 			nThreads++
 			if nThreads == thrN {
 				<-ch
@@ -1553,9 +1747,8 @@ func gha2db(args []string) {
 		}
 	} else {
 		lib.Printf("Using single threaded version\n")
-		for dt.Before(dTo) || dt.Equal(dTo) {
-			getGHAJSON(nil, &ctx, dt, org, repo)
-			dt = dt.Add(time.Hour)
+		for _, dt := range hours {
+			getGHAJSON(nil, &ctx, dt, org, repo, filter)
 		}
 	}
 	// Finished
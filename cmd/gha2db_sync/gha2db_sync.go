@@ -1,64 +1,21 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"math/rand"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	lib "devstats"
 
+	client "github.com/influxdata/influxdb/client/v2"
 	yaml "gopkg.in/yaml.v2"
 )
 
-// gaps contain list of metrics to fill gaps
-type gaps struct {
-	Metrics []metricGap `yaml:"metrics"`
-}
-
-// metricGap conain list of series names and periods to fill gaps
-// Series formula allows writing a lot of series name in a shorter way
-// Say we have series in this form prefix_{x}_{y}_{z}_suffix
-// and {x} can be a,b,c,d, {y} can be 1,2,3, z can be yes,no
-// Instead of listing all combinations prefix_a_1_yes_suffix, ..., prefix_d_3_no_suffix
-// Which is 4 * 3 * 2 = 24 items, You can write series formula:
-// "=prefix;suffix;_;a,b,c,d;1,2,3;yes,no"
-// format is "=prefix;suffix;join;list1item1,list1item2,...;list2item1,list2item2,...;..."
-// Values can be set the same way as Series, it is the array of series properties to clear
-// If not specified, ["value"] is assumed - it is used for multi-value series
-type metricGap struct {
-	Name      string   `yaml:"name"`
-	Series    []string `yaml:"series"`
-	Periods   string   `yaml:"periods"`
-	Aggregate string   `yaml:"aggregate"`
-	Skip      string   `yaml:"skip"`
-	Desc      bool     `yaml:"desc"`
-	Values    []string `yaml:"values"`
-}
-
-// metrics contain list of metrics to evaluate
-type metrics struct {
-	Metrics []metric `yaml:"metrics"`
-}
-
-// metric contain each metric data
-type metric struct {
-	Name              string `yaml:"name"`
-	Periods           string `yaml:"periods"`
-	SeriesNameOrFunc  string `yaml:"series_name_or_func"`
-	MetricSQL         string `yaml:"sql"`
-	AddPeriodToName   bool   `yaml:"add_period_to_name"`
-	Histogram         bool   `yaml:"histogram"`
-	Aggregate         string `yaml:"aggregate"`
-	Skip              string `yaml:"skip"`
-	Desc              string `yaml:"desc"`
-	MultiValue        bool   `yaml:"multi_value"`
-	EscapeValueName   bool   `yaml:"escape_value_name"`
-	AnnotationsRanges bool   `yaml:"annotations_ranges"`
-}
-
 // Add _period to all array items
 func addPeriodSuffix(seriesArr []string, period string) (result []string) {
 	for _, series := range seriesArr {
@@ -156,21 +113,75 @@ func createSeriesFromFormula(def string) (result []string) {
 	return
 }
 
+// syncState - data shared by all sync phases
+type syncState struct {
+	ctx        *lib.Ctx
+	run        *lib.SyncRun
+	con        *sql.DB
+	ic         client.Client
+	org        []string
+	repo       []string
+	cmdPrefix  string
+	dataPrefix string
+	maxDtPg    time.Time
+	maxDtIDB   time.Time
+	from       time.Time
+	to         time.Time
+	phaseEnv   map[string]map[string]string
+	dirtyMaxID *int
+}
+
+// getDirtyMaxID - returns the most recent `gha_dirty_ranges` ID, it is read once, so `gaps` and `db2influx` phases use the same ranges
+func (st *syncState) getDirtyMaxID() int {
+	if st.dirtyMaxID == nil {
+		maxID := lib.MaxDirtyRangeID(st.con, st.ctx)
+		st.dirtyMaxID = &maxID
+	}
+	return *st.dirtyMaxID
+}
+
+// dirtyEnv - in incremental mode tells `db2influx` and `z2influx` to also compute intervals touched by dirty ranges
+func (st *syncState) dirtyEnv() map[string]string {
+	if !st.ctx.Incremental {
+		return nil
+	}
+	maxID := st.getDirtyMaxID()
+	if maxID == 0 {
+		return nil
+	}
+	return map[string]string{"GHA2DB_DIRTY_MAX_ID": strconv.Itoa(maxID)}
+}
+
+// builtinPhases - phases implemented by gha2db_sync, custom phases (with command or sql) can be added in `sync.yaml` or `projects.yaml`
+var builtinPhases = map[string]func(st *syncState){
+	"heal":        healPhase,
+	"gha2db":      gha2dbPhase,
+	"get_repos":   getReposPhase,
+	"ghapi2db":    ghapi2dbPhase,
+	"structure":   structurePhase,
+	"idb_tags":    idbTagsPhase,
+	"annotations": annotationsPhase,
+	"gaps":        gapsPhase,
+	"db2influx":   db2influxPhase,
+}
+
+// pdbPhases - built-in phases skipped by GHA2DB_SKIPPDB, all other built-in phases are skipped by GHA2DB_SKIPIDB
+var pdbPhases = map[string]struct{}{
+	"heal":      {},
+	"gha2db":    {},
+	"get_repos": {},
+	"ghapi2db":  {},
+	"structure": {},
+}
+
 // fills series gaps
 // Reads config from YAML (which series, for which periods)
-func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
+func fillGapsInSeries(st *syncState, from, to time.Time) {
 	lib.Printf("Fill gaps in series\n")
-	var gaps gaps
-
-	// Local or cron mode?
-	cmdPrefix := ""
-	dataPrefix := lib.DataDir
-	if ctx.Local {
-		cmdPrefix = "./"
-		dataPrefix = "./"
-	}
+	var gaps lib.AllGaps
+	ctx := st.ctx
 
-	data, err := lib.ReadFile(ctx, dataPrefix+ctx.GapsYaml)
+	data, err := lib.ReadFile(ctx, st.dataPrefix+ctx.GapsYaml)
 	if err != nil {
 		lib.FatalOnError(err)
 		return
@@ -238,7 +249,7 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 					lib.Printf("Skipped filling gaps on period %s\n", periodAggr)
 					continue
 				}
-				if !ctx.ResetIDB && !lib.ComputePeriodAtThisDate(ctx, period, to) {
+				if !ctx.ResetIDB && !ctx.Incremental && !lib.ComputePeriodAtThisDate(ctx, period, to) {
 					lib.Printf("Skipping filling gaps for period \"%s\" for date %v\n", periodAggr, to)
 					continue
 				}
@@ -249,26 +260,470 @@ func fillGapsInSeries(ctx *lib.Ctx, from, to time.Time) {
 						bTo = nSeries
 					}
 					lib.Printf("Filling metric gaps %v, descriptions %v, period: %s, %d series (%d - %d)...\n", metric.Name, metric.Desc, periodAggr, nSeries, bFrom, bTo)
-					_, err := lib.ExecCommand(
-						ctx,
+					st.execPhase(
+						"gaps",
+						fmt.Sprintf("%s %s %d-%d", metric.Name, periodAggr, bFrom, bTo),
 						[]string{
-							cmdPrefix + "z2influx",
+							st.cmdPrefix + "z2influx",
 							strings.Join(addPeriodSuffix(series[bFrom:bTo], periodAggr), ","),
 							lib.ToYMDHDate(from),
 							lib.ToYMDHDate(to),
 							periodAggr,
 							strings.Join(extraParams, ","),
 						},
-						nil,
+						st.dirtyEnv(),
 					)
-					lib.FatalOnError(err)
 				}
 			}
 		}
 	}
 }
 
-func sync(ctx *lib.Ctx, args []string) {
+// healHours - re-imports hours that are not marked as imported in `gha_imported_hours`
+// Only hours after the first recorded hour and before `to` are checked
+func healHours(st *syncState, to time.Time) {
+	ctx := st.ctx
+	firstDt := lib.FirstImportedHour(st.con, ctx)
+	if firstDt == nil {
+		lib.Printf("No imported hours recorded yet, skipping holes detection\n")
+		return
+	}
+	to = lib.PrevHourStart(to)
+	missing := lib.MissingHours(*firstDt, to, lib.GetImportedHours(st.con, ctx, *firstDt, to))
+	if len(missing) == 0 {
+		return
+	}
+	from, to := missing[0], missing[len(missing)-1]
+
+	lib.Printf("Found %d missing or failed GHA hours in %s - %s, re-importing\n", len(missing), lib.ToYMDHDate(from), lib.ToYMDHDate(to))
+	st.execPhase(
+		"heal",
+		fmt.Sprintf("%d hours", len(missing)),
+		[]string{
+			st.cmdPrefix + "gha2db",
+			lib.ToYMDDate(from),
+			strconv.Itoa(from.Hour()),
+			lib.ToYMDDate(to),
+			strconv.Itoa(to.Hour()),
+			strings.Join(st.org, ","),
+			strings.Join(st.repo, ","),
+		},
+		map[string]string{"GHA2DB_MISSING_HOURS": "1"},
+	)
+}
+
+// execPhase - executes sync phase's command, recording it in the sync run history
+// Phase's `env:` from `sync.yaml`/`projects.yaml` is added to the command's environment
+// Exits on error, like lib.ExecCommand in fatal mode, but marks the phase and the run as failed first
+func (st *syncState) execPhase(phase, detail string, cmdAndArgs []string, env map[string]string) {
+	if len(st.phaseEnv[phase]) > 0 {
+		phaseEnv := make(map[string]string)
+		for k, v := range env {
+			phaseEnv[k] = v
+		}
+		for k, v := range st.phaseEnv[phase] {
+			phaseEnv[k] = v
+		}
+		env = phaseEnv
+	}
+	execCtx := *st.ctx
+	execCtx.ExecFatal = false
+	ph := st.run.StartPhase(phase, detail)
+	dtStart := time.Now()
+	_, err := lib.ExecCommand(&execCtx, cmdAndArgs, env)
+	ph.Finish(err)
+	promPhase(phase, detail, dtStart, err)
+	if err != nil {
+		st.run.Finish(err)
+		lib.FatalOnError(err)
+	}
+}
+
+// promPhase - records phase's command duration in operational metrics
+// For "db2influx" phase (detail is "metric period") it also records duration of each metric and period
+func promPhase(phase, detail string, dtStart time.Time, err error) {
+	status := lib.SyncOK
+	if err != nil {
+		status = lib.SyncFailed
+	}
+	lib.PromObserveSince("devstats_sync_phase_duration_seconds", "gha2db_sync phases commands duration.", lib.PromPhaseBuckets, dtStart, "phase", phase, "status", status)
+	if phase != "db2influx" {
+		return
+	}
+	ary := strings.Split(detail, " ")
+	if len(ary) != 2 {
+		return
+	}
+	lib.PromSet("devstats_db2influx_duration_seconds", "Last db2influx duration for metric and period.", time.Now().Sub(dtStart).Seconds(), "metric", ary[0], "period", ary[1], "status", status)
+}
+
+// Detect and heal holes: hours not imported (or failed) between first recorded hour and max event date
+func healPhase(st *syncState) {
+	if st.ctx.SkipHeal {
+		lib.Printf("Skipping detecting missing GHA hours\n")
+		return
+	}
+	healHours(st, st.maxDtPg)
+}
+
+// Get new GHAs
+func gha2dbPhase(st *syncState) {
+	// Just to get into next GHA hour
+	from := st.maxDtPg.Add(5 * time.Minute)
+	fromDate := lib.ToYMDDate(from)
+	fromHour := strconv.Itoa(from.Hour())
+	toDate := lib.ToYMDDate(st.to)
+	toHour := strconv.Itoa(st.to.Hour())
+	lib.Printf("GHA range: %s %s - %s %s\n", fromDate, fromHour, toDate, toHour)
+	st.execPhase(
+		"gha2db",
+		"",
+		[]string{
+			st.cmdPrefix + "gha2db",
+			fromDate,
+			fromHour,
+			toDate,
+			toHour,
+			strings.Join(st.org, ","),
+			strings.Join(st.repo, ","),
+		},
+		nil,
+	)
+}
+
+// Only run commits analysis for current DB here
+// We have updated repos to the newest state as 1st step in "devstats" call
+// We have also fetched all data from current GHA hour using "gha2db"
+// Now let's update new commits files (from newest hour)
+func getReposPhase(st *syncState) {
+	if st.ctx.SkipGetRepos {
+		return
+	}
+	lib.Printf("Update git commits\n")
+	st.execPhase(
+		"get_repos",
+		"",
+		[]string{
+			st.cmdPrefix + "get_repos",
+		},
+		map[string]string{
+			"GHA2DB_PROCESS_COMMITS":  "1",
+			"GHA2DB_PROJECTS_COMMITS": st.ctx.Project,
+		},
+	)
+}
+
+// GitHub API calls to get open issues state
+// It updates milestone and/or label(s) when different sice last comment state
+func ghapi2dbPhase(st *syncState) {
+	if st.ctx.SkipGHAPI && st.ctx.SkipArtificailClean {
+		return
+	}
+	lib.Printf("Update data from GitHub API\n")
+	st.execPhase(
+		"ghapi2db",
+		"",
+		[]string{
+			st.cmdPrefix + "ghapi2db",
+		},
+		nil,
+	)
+}
+
+// Eventual postprocess SQL's from 'structure' call
+func structurePhase(st *syncState) {
+	lib.Printf("Update structure\n")
+	// Recompute views and DB summaries
+	st.execPhase(
+		"structure",
+		"",
+		[]string{
+			st.cmdPrefix + "structure",
+		},
+		map[string]string{
+			"GHA2DB_SKIPTABLE": "1",
+			"GHA2DB_MGETC":     "y",
+		},
+	)
+}
+
+// InfluxDB tags (repo groups template variable currently)
+func idbTagsPhase(st *syncState) {
+	if st.ctx.ResetIDB || time.Now().Hour() == 0 {
+		st.execPhase("idb_tags", "", []string{st.cmdPrefix + "idb_tags"}, nil)
+	} else {
+		lib.Printf("Skipping `idb_tags` recalculation, it is only computed once per day\n")
+	}
+}
+
+// Annotations
+func annotationsPhase(st *syncState) {
+	if st.ctx.Project != "" && (st.ctx.ResetIDB || time.Now().Hour() == 0) {
+		st.execPhase(
+			"annotations",
+			"",
+			[]string{
+				st.cmdPrefix + "annotations",
+			},
+			nil,
+		)
+	} else {
+		lib.Printf("Skipping `annotations` recalculation, it is only computed once per day\n")
+	}
+}
+
+// Fill gaps in series
+func gapsPhase(st *syncState) {
+	fillGapsInSeries(st, st.from, st.to)
+}
+
+// DB2Influx
+func db2influxPhase(st *syncState) {
+	ctx := st.ctx
+	from, to := st.from, st.to
+	metricsDir := st.dataPrefix + "metrics"
+	if ctx.Project != "" {
+		metricsDir += "/" + ctx.Project
+	}
+
+	// All dirty ranges up to this ID are recomputed by this phase
+	dirtyMaxID := st.getDirtyMaxID()
+
+	// Get Quick Ranges from IDB (it is filled by annotations command), data is "suffix;period;from;to"
+	quickRangesData := make(map[string]string)
+	quickRanges := []string{}
+	for _, data := range lib.GetTagValues(st.ic, ctx, "quick_ranges_data") {
+		sfx := strings.Split(data, ";")[0]
+		quickRangesData[sfx] = data
+		quickRanges = append(quickRanges, sfx)
+	}
+	lib.Printf("Quick ranges: %+v\n", quickRanges)
+
+	// Read metrics configuration
+	data, err := lib.ReadFile(ctx, st.dataPrefix+ctx.MetricsYaml)
+	if err != nil {
+		lib.FatalOnError(err)
+		return
+	}
+	var allMetrics lib.AllMetrics
+	lib.FatalOnError(yaml.Unmarshal(data, &allMetrics))
+
+	// Keep all histograms here
+	var hists [][]string
+	onlyMetrics := false
+	if len(ctx.OnlyMetrics) > 0 {
+		onlyMetrics = true
+	}
+
+	// Iterate all metrics
+	for _, metric := range allMetrics.Metrics {
+		if onlyMetrics {
+			_, ok := ctx.OnlyMetrics[metric.MetricSQL]
+			if !ok {
+				continue
+			}
+		}
+		extraParams := []string{}
+		if metric.Histogram {
+			extraParams = append(extraParams, "hist")
+		}
+		if metric.MultiValue {
+			extraParams = append(extraParams, "multivalue")
+		}
+		if metric.EscapeValueName {
+			extraParams = append(extraParams, "escape_value_name")
+		}
+		if metric.Desc != "" {
+			extraParams = append(extraParams, "desc:"+metric.Desc)
+		}
+		periods := strings.Split(metric.Periods, ",")
+		aggregate := metric.Aggregate
+		if aggregate == "" {
+			aggregate = "1"
+		}
+		if metric.AnnotationsRanges {
+			extraParams = append(extraParams, "annotations_ranges")
+			periods = quickRanges
+			aggregate = "1"
+		}
+		aggregateArr := strings.Split(aggregate, ",")
+		skips := strings.Split(metric.Skip, ",")
+		skipMap := make(map[string]struct{})
+		for _, skip := range skips {
+			skipMap[skip] = struct{}{}
+		}
+		if !ctx.ResetIDB && !ctx.ResetRanges {
+			extraParams = append(extraParams, "skip_past")
+		}
+		for _, aggrStr := range aggregateArr {
+			_, err := strconv.Atoi(aggrStr)
+			lib.FatalOnError(err)
+			aggrSuffix := aggrStr
+			if aggrSuffix == "1" {
+				aggrSuffix = ""
+			}
+			for _, period := range periods {
+				periodAggr := period + aggrSuffix
+				_, found := skipMap[periodAggr]
+				if found {
+					lib.Printf("Skipped period %s\n", periodAggr)
+					continue
+				}
+				// In incremental mode only intervals touched by new events are computed, so all periods can be computed every run
+				// Histograms are always computed for the whole period, so they still use the time of day schedule
+				computeAtThisDate := lib.ComputePeriodAtThisDate
+				if metric.AnnotationsRanges {
+					computeAtThisDate = func(ctx *lib.Ctx, period string, dt time.Time) bool {
+						return lib.ComputeQuickRangeAtThisDate(ctx, quickRangesData[period], dt)
+					}
+				}
+				if !ctx.ResetIDB && !(ctx.Incremental && !metric.Histogram) && !computeAtThisDate(ctx, period, to) {
+					lib.Printf("Skipping recalculating period \"%s%s\" for date to %v\n", period, aggrSuffix, to)
+					continue
+				}
+				seriesNameOrFunc := metric.SeriesNameOrFunc
+				if metric.AddPeriodToName {
+					seriesNameOrFunc += "_" + periodAggr
+				}
+				// Histogram metrics usualy take long time, but executes single query, so there is no way to
+				// Implement multi threading inside "db2influx" call fro them
+				// So we're creating array of such metrics to be executed at the end - each in a separate go routine
+				if metric.Histogram {
+					lib.Printf("Scheduled histogram metric %v, period %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Desc, aggrSuffix)
+					hists = append(
+						hists,
+						[]string{
+							st.cmdPrefix + "db2influx",
+							seriesNameOrFunc,
+							fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL),
+							lib.ToYMDHDate(from),
+							lib.ToYMDHDate(to),
+							periodAggr,
+							strings.Join(extraParams, ","),
+						},
+					)
+				} else {
+					lib.Printf("Calculate metric %v, period %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Desc, aggrSuffix)
+					st.execPhase(
+						"db2influx",
+						metric.MetricSQL+" "+periodAggr,
+						[]string{
+							st.cmdPrefix + "db2influx",
+							seriesNameOrFunc,
+							fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL),
+							lib.ToYMDHDate(from),
+							lib.ToYMDHDate(to),
+							periodAggr,
+							strings.Join(extraParams, ","),
+						},
+						st.dirtyEnv(),
+					)
+				}
+			}
+		}
+	}
+	// Process histograms (possibly MT)
+	// Get number of CPUs available
+	thrN := lib.GetThreadsNum(ctx)
+	if thrN > 1 {
+		lib.Printf("Now processing %d histograms using MT%d version\n", len(hists), thrN)
+		ch := make(chan bool)
+		nThreads := 0
+		for _, hist := range hists {
+			go calcHistogram(ch, st, hist)
+			nThreads++
+			if nThreads == thrN {
+				<-ch
+				nThreads--
+			}
+		}
+		lib.Printf("Final threads join\n")
+		for nThreads > 0 {
+			<-ch
+			nThreads--
+		}
+	} else {
+		lib.Printf("Now processing %d histograms using ST version\n", len(hists))
+		for _, hist := range hists {
+			calcHistogram(nil, st, hist)
+		}
+	}
+
+	// All periods touched by dirty ranges are computed now, unless only selected metrics were computed
+	if dirtyMaxID > 0 && !ctx.SkipIDB && !ctx.SeriesDiff && !onlyMetrics {
+		lib.ClearDirtyRanges(st.con, ctx, dirtyMaxID)
+		lib.Printf("Cleared dirty ranges up to id %d\n", dirtyMaxID)
+	}
+}
+
+// customPhase - executes phase's command or SQL file (using `runq` on project's database)
+func customPhase(st *syncState, phase *lib.SyncPhaseDef) {
+	cmdAndArgs := phase.Command
+	if phase.SQL != "" {
+		cmdAndArgs = []string{st.cmdPrefix + "runq", st.dataPrefix + phase.SQL}
+	}
+	lib.Printf("Execute custom phase %s: %v\n", phase.Name, cmdAndArgs)
+	st.execPhase(phase.Name, strings.Join(cmdAndArgs, " "), cmdAndArgs, nil)
+}
+
+// getSyncPhases - reads sync phases from `sync.yaml`, applies project's changes from `projects.yaml` (`sync_phases:`)
+// Returns phases in execution order
+func getSyncPhases(ctx *lib.Ctx, projPhases []lib.SyncPhaseDef) []lib.SyncPhaseDef {
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	data, err := lib.ReadFile(ctx, dataPrefix+ctx.SyncYaml)
+	if err != nil {
+		lib.FatalOnError(err)
+		return nil
+	}
+	var pipeline lib.SyncPipeline
+	lib.FatalOnError(yaml.Unmarshal(data, &pipeline))
+	pipeline.Merge(projPhases)
+	phases, err := pipeline.Order()
+	lib.FatalOnError(err)
+	for _, phase := range phases {
+		if _, ok := builtinPhases[phase.Name]; phase.Builtin() && !ok {
+			lib.Fatalf("sync phase '%s' is not a built-in phase and has no command or sql", phase.Name)
+		}
+	}
+	return phases
+}
+
+func sync(ctx *lib.Ctx, args []string, phases []lib.SyncPhaseDef) {
+	// Restart from a given phase or from the phase that failed in the last run
+	startFrom := ctx.SyncFrom
+	if startFrom == "" && ctx.SyncResume {
+		startFrom = lib.LastFailedSyncPhase(ctx, "gha2db_sync")
+		if startFrom != "" {
+			lib.Printf("Last sync failed in phase %s, resuming from it\n", startFrom)
+		}
+	}
+	fromPhases, err := lib.SyncPhasesFrom(phases, startFrom)
+	if err != nil && ctx.SyncFrom == "" {
+		// Failed phase can be removed or renamed since the last run, only explicitly given phase must exist
+		lib.Printf("Last failed sync phase %s is not defined anymore, starting from the first phase\n", startFrom)
+		fromPhases, err = phases, nil
+	}
+	lib.FatalOnError(err)
+	phases = fromPhases
+
+	// db2influx output is not visible when called by gha2db_sync, so differences must go to a file
+	if ctx.SeriesDiff && ctx.DiffFile == "" {
+		lib.Fatalf("diff mode (GHA2DB_DIFF) requires GHA2DB_DIFF_FILE")
+	}
+
+	// Record this run in sync runs history, mark it as failed if we exit on error
+	run := lib.NewSyncRun(ctx, "gha2db_sync")
+	defer func() {
+		if r := recover(); r != nil {
+			run.Finish(fmt.Errorf("%v", r))
+			panic(r)
+		}
+	}()
+
 	// Strip function to be used by MapString
 	stripFunc := func(x string) string { return strings.TrimSpace(x) }
 
@@ -321,284 +776,80 @@ func sync(ctx *lib.Ctx, args []string) {
 		}
 	}
 
-	// Create date range
-	// Just to get into next GHA hour
-	from := maxDtPg.Add(5 * time.Minute)
-	to := time.Now()
-	fromDate := lib.ToYMDDate(from)
-	fromHour := strconv.Itoa(from.Hour())
-	toDate := lib.ToYMDDate(to)
-	toHour := strconv.Itoa(to.Hour())
+	// Incremental mode needs `gha_dirty_ranges` table, without it periods are computed using time of day schedule
+	if ctx.Incremental && !lib.DirtyRangesEnabled(con, ctx) {
+		ctx.Incremental = false
+	}
+
+	// Regenerate Influx points from this date
+	from := maxDtIDB
+	if ctx.ResetIDB {
+		from = ctx.DefaultStartDate
+	}
+	st := &syncState{
+		ctx:        ctx,
+		run:        run,
+		con:        con,
+		ic:         ic,
+		org:        org,
+		repo:       repo,
+		cmdPrefix:  cmdPrefix,
+		dataPrefix: dataPrefix,
+		maxDtPg:    maxDtPg,
+		maxDtIDB:   maxDtIDB,
+		from:       from,
+		to:         time.Now(),
+		phaseEnv:   make(map[string]map[string]string),
+	}
+	for _, phase := range phases {
+		st.phaseEnv[phase.Name] = phase.Env
+	}
 
-	// Get new GHAs
+	// Clear old DB logs
 	if !ctx.SkipPDB {
-		// Clear old DB logs
 		lib.ClearDBLogs()
-
-		// gha2db
-		lib.Printf("GHA range: %s %s - %s %s\n", fromDate, fromHour, toDate, toHour)
-		_, err := lib.ExecCommand(
-			ctx,
-			[]string{
-				cmdPrefix + "gha2db",
-				fromDate,
-				fromHour,
-				toDate,
-				toHour,
-				strings.Join(org, ","),
-				strings.Join(repo, ","),
-			},
-			nil,
-		)
-		lib.FatalOnError(err)
-
-		// Only run commits analysis for current DB here
-		// We have updated repos to the newest state as 1st step in "devstats" call
-		// We have also fetched all data from current GHA hour using "gha2db"
-		// Now let's update new commits files (from newest hour)
-		if !ctx.SkipGetRepos {
-			lib.Printf("Update git commits\n")
-			_, err = lib.ExecCommand(
-				ctx,
-				[]string{
-					cmdPrefix + "get_repos",
-				},
-				map[string]string{
-					"GHA2DB_PROCESS_COMMITS":  "1",
-					"GHA2DB_PROJECTS_COMMITS": ctx.Project,
-				},
-			)
-			lib.FatalOnError(err)
-		}
-
-		// GitHub API calls to get open issues state
-		// It updates milestone and/or label(s) when different sice last comment state
-		if !ctx.SkipGHAPI || !ctx.SkipArtificailClean {
-			lib.Printf("Update data from GitHub API\n")
-			// Recompute views and DB summaries
-			_, err = lib.ExecCommand(
-				ctx,
-				[]string{
-					cmdPrefix + "ghapi2db",
-				},
-				nil,
-			)
-			lib.FatalOnError(err)
-		}
-
-		// Eventual postprocess SQL's from 'structure' call
-		lib.Printf("Update structure\n")
-		// Recompute views and DB summaries
-		_, err = lib.ExecCommand(
-			ctx,
-			[]string{
-				cmdPrefix + "structure",
-			},
-			map[string]string{
-				"GHA2DB_SKIPTABLE": "1",
-				"GHA2DB_MGETC":     "y",
-			},
-		)
-		lib.FatalOnError(err)
 	}
-
-	// DB2Influx
 	if !ctx.SkipIDB {
-		metricsDir := dataPrefix + "metrics"
-		if ctx.Project != "" {
-			metricsDir += "/" + ctx.Project
-		}
-		// Regenerate points from this date
-		if ctx.ResetIDB {
-			from = ctx.DefaultStartDate
-		} else {
-			from = maxDtIDB
-		}
-		lib.Printf("Influx range: %s - %s\n", lib.ToYMDHDate(from), lib.ToYMDHDate(to))
-
-		// InfluxDB tags (repo groups template variable currently)
-		if ctx.ResetIDB || time.Now().Hour() == 0 {
-			_, err := lib.ExecCommand(ctx, []string{cmdPrefix + "idb_tags"}, nil)
-			lib.FatalOnError(err)
-		} else {
-			lib.Printf("Skipping `idb_tags` recalculation, it is only computed once per day\n")
-		}
-
-		// Annotations
-		if ctx.Project != "" && (ctx.ResetIDB || time.Now().Hour() == 0) {
-			_, err := lib.ExecCommand(
-				ctx,
-				[]string{
-					cmdPrefix + "annotations",
-				},
-				nil,
-			)
-			lib.FatalOnError(err)
-		} else {
-			lib.Printf("Skipping `annotations` recalculation, it is only computed once per day\n")
-		}
-
-		// Get Quick Ranges from IDB (it is filled by annotations command)
-		quickRanges := lib.GetTagValues(ic, ctx, "quick_ranges_suffix")
-		lib.Printf("Quick ranges: %+v\n", quickRanges)
-
-		// Fill gaps in series
-		fillGapsInSeries(ctx, from, to)
-
-		// Read metrics configuration
-		data, err := lib.ReadFile(ctx, dataPrefix+ctx.MetricsYaml)
-		if err != nil {
-			lib.FatalOnError(err)
-			return
-		}
-		var allMetrics metrics
-		lib.FatalOnError(yaml.Unmarshal(data, &allMetrics))
+		lib.Printf("Influx range: %s - %s\n", lib.ToYMDHDate(st.from), lib.ToYMDHDate(st.to))
+	}
 
-		// Keep all histograms here
-		var hists [][]string
-		onlyMetrics := false
-		if len(ctx.OnlyMetrics) > 0 {
-			onlyMetrics = true
+	// Execute phases in order
+	for i := range phases {
+		phase := &phases[i]
+		if phase.Skip {
+			lib.Printf("Skipping phase %s\n", phase.Name)
+			continue
 		}
-
-		// Iterate all metrics
-		for _, metric := range allMetrics.Metrics {
-			if onlyMetrics {
-				_, ok := ctx.OnlyMetrics[metric.MetricSQL]
-				if !ok {
-					continue
-				}
-			}
-			extraParams := []string{}
-			if metric.Histogram {
-				extraParams = append(extraParams, "hist")
-			}
-			if metric.MultiValue {
-				extraParams = append(extraParams, "multivalue")
-			}
-			if metric.EscapeValueName {
-				extraParams = append(extraParams, "escape_value_name")
-			}
-			if metric.Desc != "" {
-				extraParams = append(extraParams, "desc:"+metric.Desc)
-			}
-			periods := strings.Split(metric.Periods, ",")
-			aggregate := metric.Aggregate
-			if aggregate == "" {
-				aggregate = "1"
-			}
-			if metric.AnnotationsRanges {
-				extraParams = append(extraParams, "annotations_ranges")
-				periods = quickRanges
-				aggregate = "1"
-			}
-			aggregateArr := strings.Split(aggregate, ",")
-			skips := strings.Split(metric.Skip, ",")
-			skipMap := make(map[string]struct{})
-			for _, skip := range skips {
-				skipMap[skip] = struct{}{}
-			}
-			if !ctx.ResetIDB && !ctx.ResetRanges {
-				extraParams = append(extraParams, "skip_past")
-			}
-			for _, aggrStr := range aggregateArr {
-				_, err := strconv.Atoi(aggrStr)
-				lib.FatalOnError(err)
-				aggrSuffix := aggrStr
-				if aggrSuffix == "1" {
-					aggrSuffix = ""
-				}
-				for _, period := range periods {
-					periodAggr := period + aggrSuffix
-					_, found := skipMap[periodAggr]
-					if found {
-						lib.Printf("Skipped period %s\n", periodAggr)
-						continue
-					}
-					if !ctx.ResetIDB && !lib.ComputePeriodAtThisDate(ctx, period, to) {
-						lib.Printf("Skipping recalculating period \"%s%s\" for date to %v\n", period, aggrSuffix, to)
-						continue
-					}
-					seriesNameOrFunc := metric.SeriesNameOrFunc
-					if metric.AddPeriodToName {
-						seriesNameOrFunc += "_" + periodAggr
-					}
-					// Histogram metrics usualy take long time, but executes single query, so there is no way to
-					// Implement multi threading inside "db2influx" call fro them
-					// So we're creating array of such metrics to be executed at the end - each in a separate go routine
-					if metric.Histogram {
-						lib.Printf("Scheduled histogram metric %v, period %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Desc, aggrSuffix)
-						hists = append(
-							hists,
-							[]string{
-								cmdPrefix + "db2influx",
-								seriesNameOrFunc,
-								fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL),
-								lib.ToYMDHDate(from),
-								lib.ToYMDHDate(to),
-								periodAggr,
-								strings.Join(extraParams, ","),
-							},
-						)
-					} else {
-						lib.Printf("Calculate metric %v, period %v, desc: '%v', aggregate: '%v' ...\n", metric.Name, period, metric.Desc, aggrSuffix)
-						_, err = lib.ExecCommand(
-							ctx,
-							[]string{
-								cmdPrefix + "db2influx",
-								seriesNameOrFunc,
-								fmt.Sprintf("%s/%s.sql", metricsDir, metric.MetricSQL),
-								lib.ToYMDHDate(from),
-								lib.ToYMDHDate(to),
-								periodAggr,
-								strings.Join(extraParams, ","),
-							},
-							nil,
-						)
-						lib.FatalOnError(err)
-					}
-				}
+		// In diff mode only metrics are computed and compared with existing series
+		if ctx.SeriesDiff && phase.Name != "db2influx" {
+			if ctx.Debug > 0 {
+				lib.Printf("Diff mode: skipping phase %s\n", phase.Name)
 			}
+			continue
 		}
-		// Process histograms (possibly MT)
-		// Get number of CPUs available
-		thrN := lib.GetThreadsNum(ctx)
-		if thrN > 1 {
-			lib.Printf("Now processing %d histograms using MT%d version\n", len(hists), thrN)
-			ch := make(chan bool)
-			nThreads := 0
-			for _, hist := range hists {
-				go calcHistogram(ch, ctx, hist)
-				nThreads++
-				if nThreads == thrN {
-					<-ch
-					nThreads--
-				}
-			}
-			lib.Printf("Final threads join\n")
-			for nThreads > 0 {
-				<-ch
-				nThreads--
-			}
-		} else {
-			lib.Printf("Now processing %d histograms using ST version\n", len(hists))
-			for _, hist := range hists {
-				calcHistogram(nil, ctx, hist)
-			}
+		if !phase.Builtin() {
+			customPhase(st, phase)
+			continue
 		}
+		_, pdb := pdbPhases[phase.Name]
+		if (pdb && ctx.SkipPDB) || (!pdb && ctx.SkipIDB) {
+			continue
+		}
+		builtinPhases[phase.Name](st)
 	}
+	run.Finish(nil)
 	lib.Printf("Sync success\n")
 }
 
 // calcHistogram - calculate single histogram by calling "db2influx" program with parameters from "hist"
-func calcHistogram(ch chan bool, ctx *lib.Ctx, hist []string) {
+func calcHistogram(ch chan bool, st *syncState, hist []string) {
 	if len(hist) != 7 {
 		lib.Fatalf("calcHistogram, expected 7 strings, got: %d: %v", len(hist), hist)
 	}
 	envMap := make(map[string]string)
 	rSrc := rand.NewSource(time.Now().UnixNano())
 	rnd := rand.New(rSrc)
-	if ctx.IDBDropProbN > 0 && rnd.Intn(ctx.IDBDropProbN) == 1 {
+	if st.ctx.IDBDropProbN > 0 && rnd.Intn(st.ctx.IDBDropProbN) == 1 {
 		envMap["GHA2DB_IDB_DROP_SERIES"] = "1"
 	}
 	lib.Printf(
@@ -611,8 +862,9 @@ func calcHistogram(ch chan bool, ctx *lib.Ctx, hist []string) {
 		hist[6],
 	)
 	// Execute "db2influx"
-	_, err := lib.ExecCommand(
-		ctx,
+	st.execPhase(
+		"db2influx",
+		strings.TrimSuffix(path.Base(hist[2]), ".sql")+" "+hist[5],
 		[]string{
 			hist[0],
 			hist[1],
@@ -624,7 +876,6 @@ func calcHistogram(ch chan bool, ctx *lib.Ctx, hist []string) {
 		},
 		envMap,
 	)
-	lib.FatalOnError(err)
 	// Synchronize go routine
 	if ch != nil {
 		ch <- true
@@ -675,12 +926,34 @@ func getSyncArgs(ctx *lib.Ctx, osArgs []string) []string {
 	return []string{}
 }
 
+// getProjectSyncPhases - returns project's changes to sync phases (`sync_phases:` in `projects.yaml`)
+func getProjectSyncPhases(ctx *lib.Ctx) []lib.SyncPhaseDef {
+	if ctx.Project == "" {
+		return nil
+	}
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	data, err := lib.ReadFile(ctx, dataPrefix+ctx.ProjectsYaml)
+	if err != nil {
+		lib.FatalOnError(err)
+		return nil
+	}
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+	return projects.Projects[ctx.Project].SyncPhases
+}
+
 func main() {
 	dtStart := time.Now()
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
-	sync(&ctx, getSyncArgs(&ctx, os.Args))
+	lib.PromStart(&ctx, "gha2db_sync")
+	defer lib.PromFinish(&ctx)
+	sync(&ctx, getSyncArgs(&ctx, os.Args), getSyncPhases(&ctx, getProjectSyncPhases(&ctx)))
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -26,8 +27,18 @@ type issueConfig struct {
 	ghIssue     *github.Issue
 }
 
+type prConfig struct {
+	repo      string
+	number    int
+	prID      int64
+	ghPR      *github.PullRequest
+	reviewers *github.Reviewers
+	reviews   []*github.PullRequestReview
+	comments  []*github.PullRequestComment
+}
+
 // handlePossibleError - display error specific message, detect rate limit and abuse
-func handlePossibleError(err error, cfg *issueConfig, info string) {
+func handlePossibleError(err error, cfg interface{}, info string) {
 	if err != nil {
 		_, rate := err.(*github.RateLimitError)
 		_, abuse := err.(*github.AbuseRateLimitError)
@@ -75,6 +86,7 @@ func artificialEvent(
 	labels map[int64]string,
 	labelsChanged bool,
 	ghIssue *github.Issue,
+	dirty *lib.DirtyRange,
 ) (err error) {
 	if ctx.SkipPDB {
 		if ctx.Debug > 0 {
@@ -223,6 +235,7 @@ func artificialEvent(
 	// Final commit
 	lib.FatalOnError(tc.Commit())
 	//lib.FatalOnError(tc.Rollback())
+	dirty.Add(now)
 	return
 }
 
@@ -285,6 +298,7 @@ func cleanArtificialEvents(ctx *lib.Ctx) {
 	nRows := 0
 	var counterMutex = &sync.Mutex{}
 	deleted := 0
+	var dirty lib.DirtyRange
 	for rows.Next() {
 		lib.FatalOnError(rows.Scan(&issueID, &eventID, &milestoneID, &updatedAt))
 		go func(ch chan bool, iid int64, eid int64, mid *int64, updated time.Time) {
@@ -483,6 +497,7 @@ func cleanArtificialEvents(ctx *lib.Ctx) {
 			}
 			// Delete artificial event
 			lib.FatalOnError(deleteArtificialEvent(c, ctx, eid))
+			dirty.Add(updated)
 
 			// Safe increase counter
 			counterMutex.Lock()
@@ -506,55 +521,27 @@ func cleanArtificialEvents(ctx *lib.Ctx) {
 	}
 	lib.FatalOnError(rows.Err())
 	lib.Printf("Processed %d artificial events, deleted %d\n", nRows, deleted)
-}
-
-// Insert Postgres vars
-func ghapi2db(ctx *lib.Ctx) {
-	// Connect to Postgres DB
-	c := lib.PgConn(ctx)
-	defer func() { lib.FatalOnError(c.Close()) }()
-
-	// Connect to GitHub API
-	gctx, gc := lib.GHClient(ctx)
-
-	// Get RateLimits info
-	_, rem, wait := lib.GetRateLimits(gctx, gc, true)
-
-	// Get number of CPUs available
-	thrN := lib.GetThreadsNum(ctx)
-	lib.Printf("ghapi2db.go: Running (on %d CPUs): %d API points available, resets in %v\n", thrN, rem, wait)
-
-	// Local or cron mode?
-	dataPrefix := lib.DataDir
-	if ctx.Local {
-		dataPrefix = "./"
+	if !ctx.SkipPDB {
+		dirty.Save(c, ctx, "ghapi2db")
 	}
-	// Get recently modified opened issues/PRs
-	bytes, err := lib.ReadFile(
-		ctx,
-		dataPrefix+"util_sql/open_issues_and_prs.sql",
-	)
-	lib.FatalOnError(err)
-	sqlQuery := string(bytes)
-
-	// Set range from a context
-	sqlQuery = strings.Replace(sqlQuery, "{{period}}", ctx.RecentRange, -1)
-	rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
-	defer func() { lib.FatalOnError(rows.Close()) }()
+}
 
-	// Get issues/PRs to check
-	// repo, number, issueID, is_pr
-	var issuesMutex = &sync.Mutex{}
+// readIssues - reads issues/PRs to check from query returning: repo, number, issue ID, is PR
+// Returns issues configs and maximum issue ID
+func readIssues(ctx *lib.Ctx, rows *sql.Rows) (map[int64]issueConfig, int64) {
 	issues := make(map[int64]issueConfig)
 	var (
 		repo    string
 		number  int
 		issueID int64
 		pr      bool
+		maxID   int64
 	)
-	nIssues := 0
 	for rows.Next() {
 		lib.FatalOnError(rows.Scan(&repo, &number, &issueID, &pr))
+		if issueID > maxID {
+			maxID = issueID
+		}
 		cfg := issueConfig{
 			repo:    repo,
 			number:  number,
@@ -569,63 +556,36 @@ func ghapi2db(ctx *lib.Ctx) {
 			continue
 		}
 		issues[issueID] = cfg
-		nIssues++
 		if ctx.Debug > 0 {
-			lib.Printf("Open Issue ID '%d' --> '%v'\n", issueID, cfg)
+			lib.Printf("Issue ID '%d' --> '%v'\n", issueID, cfg)
 		}
 	}
 	lib.FatalOnError(rows.Err())
-	if ctx.Debug > 0 {
-		lib.Printf("Got %d open issues for period %s\n", nIssues, ctx.RecentRange)
-	}
+	return issues, maxID
+}
 
-	if len(ctx.OnlyIssues) > 0 {
-		ary := []string{}
-		for _, issue := range ctx.OnlyIssues {
-			ary = append(ary, strconv.FormatInt(issue, 10))
-		}
-		onlyIssues := make(map[int64]issueConfig)
-		nOnlyIssues := 0
-		lib.Printf("Processing only selected %d %v issues for debugging\n", len(ctx.OnlyIssues), ctx.OnlyIssues)
-		irows := lib.QuerySQLWithErr(
-			c,
-			ctx,
-			fmt.Sprintf(
-				"select distinct dup_repo_name, number, id, is_pull_request from gha_issues where id in (%s)",
-				strings.Join(ary, ","),
-			),
-		)
-		defer func() { lib.FatalOnError(irows.Close()) }()
-		for irows.Next() {
-			lib.FatalOnError(irows.Scan(&repo, &number, &issueID, &pr))
-			cfg := issueConfig{
-				repo:    repo,
-				number:  number,
-				issueID: issueID,
-				pr:      pr,
-			}
-			v, ok := onlyIssues[issueID]
-			if ok {
-				if ctx.Debug > 0 {
-					lib.Printf("Warning: we already have issue config for id=%d: %v, skipped new config: %v\n", issueID, v, cfg)
-				}
-				continue
-			}
-			onlyIssues[issueID] = cfg
-			nOnlyIssues++
-			_, ok = issues[issueID]
-			if ok {
-				lib.Printf("Issue %d(%v) would also be processed by the default workflow\n", issueID, cfg)
-			} else {
-				lib.Printf("Issue %d(%v) would not be processed by the default workflow\n", issueID, cfg)
-			}
+// labelsString - returns sorted, comma separated label IDs
+func labelsString(labelsMap map[int64]string) (labels string) {
+	labelsAry := lib.Int64Ary{}
+	for label := range labelsMap {
+		labelsAry = append(labelsAry, label)
+	}
+	sort.Sort(labelsAry)
+	l := len(labelsAry)
+	for i, label := range labelsAry {
+		if i == l-1 {
+			labels += fmt.Sprintf("%d", label)
+		} else {
+			labels += fmt.Sprintf("%d,", label)
 		}
-		lib.FatalOnError(irows.Err())
-		lib.Printf("Processing %d/%d user provided issues\n", nOnlyIssues, len(ctx.OnlyIssues))
-		issues = onlyIssues
-		nIssues = nOnlyIssues
 	}
+	return
+}
 
+// getIssuesREST - gets issues/PRs milestones and labels using GitHub REST API (v3), at least 2 API calls per issue
+func getIssuesREST(ctx *lib.Ctx, gctx context.Context, gc *github.Client, issues map[int64]issueConfig, thrN int) {
+	var issuesMutex = &sync.Mutex{}
+	nIssues := len(issues)
 	// GitHub paging config
 	opt := &github.ListOptions{PerPage: 1000}
 	// GitHub don't like MT quering - they say that:
@@ -663,6 +623,9 @@ func ghapi2db(ctx *lib.Ctx) {
 				if ctx.Debug > 0 {
 					lib.Printf("Warning: wrong repository name: %s\n", cfg.repo)
 				}
+				if ch != nil {
+					ch <- true
+				}
 				return
 			}
 			// Use Github API to get issue info
@@ -680,6 +643,15 @@ func ghapi2db(ctx *lib.Ctx) {
 					}
 				}
 				issue, _, err := gc.Issues.Get(gctx, ary[0], ary[1], cfg.number)
+				// Backfill checks all issues, some are in deleted, renamed or transferred repositories
+				// Skip them (like issues not found by GraphQL API), so backfill cursor can move on
+				if ctx.GHAPIBackfill && lib.GHNotFound(err) {
+					lib.Printf("Issue %s#%d not found, skipping: %v\n", cfg.repo, cfg.number, err)
+					if ch != nil {
+						ch <- true
+					}
+					return
+				}
 				handlePossibleError(err, &cfg, "Issues.Get")
 				if issue.Milestone != nil {
 					cfg.milestoneID = issue.Milestone.ID
@@ -723,19 +695,7 @@ func ghapi2db(ctx *lib.Ctx) {
 				}
 				opt.Page = resp.NextPage
 			}
-			labelsAry := lib.Int64Ary{}
-			for label := range cfg.labelsMap {
-				labelsAry = append(labelsAry, label)
-			}
-			sort.Sort(labelsAry)
-			l := len(labelsAry)
-			for i, label := range labelsAry {
-				if i == l-1 {
-					cfg.labels += fmt.Sprintf("%d", label)
-				} else {
-					cfg.labels += fmt.Sprintf("%d,", label)
-				}
-			}
+			cfg.labels = labelsString(cfg.labelsMap)
 			if ctx.Debug > 0 {
 				lib.Printf("GitHub Issue ID (after) '%d' --> '%v'\n", iid, cfg)
 			}
@@ -772,15 +732,105 @@ func ghapi2db(ctx *lib.Ctx) {
 		_, rem, wait := lib.GetRateLimits(gctx, gc, true)
 		lib.ProgressInfo(checked, nIssues, dtStart, &lastTime, time.Duration(10)*time.Second, fmt.Sprintf("API points: %d, resets in: %v", rem, wait))
 	}
+}
+
+// getIssuesGraphQL - gets issues/PRs milestones and labels using GitHub GraphQL API (v4), up to 100 issues per query
+// Issues that are not found (deleted or transferred) are left without GitHub data and are skipped
+func getIssuesGraphQL(ctx *lib.Ctx, gctx context.Context, gql *lib.GHGraphQL, issues map[int64]issueConfig) {
+	nIssues := len(issues)
+	keys := lib.Int64Ary{}
+	for key := range issues {
+		keys = append(keys, key)
+	}
+	sort.Sort(keys)
+	dtStart := time.Now()
+	lastTime := dtStart
+	checked := 0
+	lib.Printf("ghapi2db.go: Processing %d issues - GraphQL part\n", nIssues)
+	for from := 0; from < nIssues; from += lib.GHGraphQLMaxIssues {
+		to := from + lib.GHGraphQLMaxIssues
+		if to > nIssues {
+			to = nIssues
+		}
+		ghKeys := []lib.GHIssueKey{}
+		for _, iid := range keys[from:to] {
+			cfg := issues[iid]
+			ghKeys = append(ghKeys, lib.GHIssueKey{Repo: cfg.repo, Number: cfg.number})
+		}
+		for {
+			_, rem, waitPeriod, err := gql.RateLimits(gctx)
+			handlePossibleError(err, ghKeys, "GraphQL rateLimit")
+			if rem > ctx.MinGHAPIPoints {
+				break
+			}
+			if waitPeriod.Seconds() > float64(ctx.MaxGHAPIWaitSeconds) {
+				lib.Fatalf("GraphQL API limit reached while getting issues data, aborting, don't want to wait %v", waitPeriod)
+			}
+			lib.Printf("GraphQL API limit reached while getting issues data, waiting %v\n", waitPeriod)
+			time.Sleep(time.Duration(1) * time.Second)
+			time.Sleep(waitPeriod)
+		}
+		ghIssues, err := gql.Issues(gctx, ghKeys)
+		handlePossibleError(err, ghKeys, "GraphQL issues")
+		for _, iid := range keys[from:to] {
+			cfg := issues[iid]
+			issue, ok := ghIssues[lib.GHIssueKey{Repo: cfg.repo, Number: cfg.number}]
+			if !ok {
+				if ctx.Debug > 0 {
+					lib.Printf("Warning: issue not found using GraphQL API: %v\n", cfg)
+				}
+				continue
+			}
+			if issue.Milestone != nil {
+				cfg.milestoneID = issue.Milestone.ID
+			}
+			cfg.ghIssue = issue
+			cfg.labelsMap = make(map[int64]string)
+			for _, label := range issue.Labels {
+				cfg.labelsMap[*label.ID] = *label.Name
+			}
+			cfg.labels = labelsString(cfg.labelsMap)
+			if ctx.Debug > 0 {
+				lib.Printf("GitHub Issue ID (after) '%d' --> '%v'\n", iid, cfg)
+			}
+			issues[iid] = cfg
+		}
+		checked += len(ghKeys)
+		_, rem, wait, _ := gql.RateLimits(gctx)
+		lib.ProgressInfo(checked, nIssues, dtStart, &lastTime, time.Duration(10)*time.Second, fmt.Sprintf("GraphQL API points: %d, resets in: %v", rem, wait))
+	}
+}
+
+// ghGraphQL - returns GitHub GraphQL API client when GHA2DB_GHAPI_GRAPHQL is set, nil otherwise
+func ghGraphQL(ctx *lib.Ctx) *lib.GHGraphQL {
+	if !ctx.GHAPIGraphQL {
+		return nil
+	}
+	return lib.NewGHGraphQL(ctx, lib.GHGraphQLURL)
+}
+
+// syncIssues - gets issues/PRs milestones and labels from GitHub API and creates artificial events
+// for issues whose current milestone or labels set in the database differ from GitHub
+// Uses GraphQL API when gql is set, REST API otherwise
+// Returns number of checked and updated issues
+func syncIssues(c *sql.DB, ctx *lib.Ctx, gctx context.Context, gc *github.Client, gql *lib.GHGraphQL, issues map[int64]issueConfig, thrN int) (int, int) {
+	if gql != nil {
+		getIssuesGraphQL(ctx, gctx, gql, issues)
+	} else {
+		getIssuesREST(ctx, gctx, gc, issues, thrN)
+	}
+	var issuesMutex = &sync.Mutex{}
+	nIssues := len(issues)
 
 	// Now iterate all issues/PR in MT mode
-	ch = make(chan bool)
-	nThreads = 0
-	dtStart = time.Now()
-	lastTime = dtStart
-	checked = 0
+	ch := make(chan bool)
+	nThreads := 0
+	dtStart := time.Now()
+	lastTime := dtStart
+	checked := 0
 	var updatesMutex = &sync.Mutex{}
 	updates := 0
+	var dirty lib.DirtyRange
 	lib.Printf("ghapi2db.go: Processing %d issues - GHA part\n", nIssues)
 	// Use map key to pass to the closure
 	for key := range issues {
@@ -792,6 +842,13 @@ func ghapi2db(ctx *lib.Ctx) {
 			if ctx.Debug > 0 {
 				lib.Printf("GHA Issue ID '%d' --> '%v'\n", iid, cfg)
 			}
+			// Issue not found using GraphQL API
+			if cfg.ghIssue == nil {
+				if ch != nil {
+					ch <- true
+				}
+				return
+			}
 			var (
 				ghaMilestoneID *int64
 				ghaEventID     int64
@@ -863,6 +920,7 @@ func ghapi2db(ctx *lib.Ctx) {
 						cfg.labelsMap,
 						ghaLabels != cfg.labels,
 						cfg.ghIssue,
+						&dirty,
 					),
 				)
 				updatesMutex.Lock()
@@ -893,6 +951,110 @@ func ghapi2db(ctx *lib.Ctx) {
 		checked++
 		lib.ProgressInfo(checked, nIssues, dtStart, &lastTime, time.Duration(10)*time.Second, "")
 	}
+	// Record dates of created artificial events
+	if !ctx.SkipPDB {
+		dirty.Save(c, ctx, "ghapi2db")
+	}
+
+	return checked, updates
+}
+
+// Insert Postgres vars
+func ghapi2db(ctx *lib.Ctx) {
+	// Connect to Postgres DB
+	c := lib.PgConn(ctx)
+	defer func() { lib.FatalOnError(c.Close()) }()
+
+	// Connect to GitHub API
+	gctx, gc := lib.GHClient(ctx)
+	gql := ghGraphQL(ctx)
+
+	// Get RateLimits info
+	_, rem, wait := lib.GetRateLimits(gctx, gc, true)
+
+	// Get number of CPUs available
+	thrN := lib.GetThreadsNum(ctx)
+	lib.Printf("ghapi2db.go: Running (on %d CPUs): %d API points available, resets in %v\n", thrN, rem, wait)
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	// Get recently modified opened issues/PRs
+	bytes, err := lib.ReadFile(
+		ctx,
+		dataPrefix+"util_sql/open_issues_and_prs.sql",
+	)
+	lib.FatalOnError(err)
+
+	// Set range from a context
+	sqlQuery, err := lib.RenderSQL(ctx, dataPrefix, string(bytes), lib.NewSQLVars().SetString(lib.SQLVarPeriod, ctx.RecentRange))
+	lib.FatalOnError(err)
+	rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+
+	// Get issues/PRs to check
+	issues, _ := readIssues(ctx, rows)
+	if ctx.Debug > 0 {
+		lib.Printf("Got %d open issues for period %s\n", len(issues), ctx.RecentRange)
+	}
+
+	if len(ctx.OnlyIssues) > 0 {
+		ary := []string{}
+		for _, issue := range ctx.OnlyIssues {
+			ary = append(ary, strconv.FormatInt(issue, 10))
+		}
+		onlyIssues := make(map[int64]issueConfig)
+		nOnlyIssues := 0
+		var (
+			repo    string
+			number  int
+			issueID int64
+			pr      bool
+		)
+		lib.Printf("Processing only selected %d %v issues for debugging\n", len(ctx.OnlyIssues), ctx.OnlyIssues)
+		irows := lib.QuerySQLWithErr(
+			c,
+			ctx,
+			fmt.Sprintf(
+				"select distinct dup_repo_name, number, id, is_pull_request from gha_issues where id in (%s)",
+				strings.Join(ary, ","),
+			),
+		)
+		defer func() { lib.FatalOnError(irows.Close()) }()
+		for irows.Next() {
+			lib.FatalOnError(irows.Scan(&repo, &number, &issueID, &pr))
+			cfg := issueConfig{
+				repo:    repo,
+				number:  number,
+				issueID: issueID,
+				pr:      pr,
+			}
+			v, ok := onlyIssues[issueID]
+			if ok {
+				if ctx.Debug > 0 {
+					lib.Printf("Warning: we already have issue config for id=%d: %v, skipped new config: %v\n", issueID, v, cfg)
+				}
+				continue
+			}
+			onlyIssues[issueID] = cfg
+			nOnlyIssues++
+			_, ok = issues[issueID]
+			if ok {
+				lib.Printf("Issue %d(%v) would also be processed by the default workflow\n", issueID, cfg)
+			} else {
+				lib.Printf("Issue %d(%v) would not be processed by the default workflow\n", issueID, cfg)
+			}
+		}
+		lib.FatalOnError(irows.Err())
+		lib.Printf("Processing %d/%d user provided issues\n", nOnlyIssues, len(ctx.OnlyIssues))
+		issues = onlyIssues
+	}
+
+	// Check issues/PRs using GitHub API and create artificial events when needed
+	checked, updates := syncIssues(c, ctx, gctx, gc, gql, issues, thrN)
+
 	// Get RateLimits info
 	_, rem, wait = lib.GetRateLimits(gctx, gc, true)
 	lib.Printf(
@@ -901,10 +1063,565 @@ func ghapi2db(ctx *lib.Ctx) {
 	)
 }
 
+// ghapi2dbBackfill - checks all issues/PRs (not only recently updated open ones) in chunks of GHA2DB_GHAPI_BACKFILL_CHUNK
+// Last checked issue ID is saved in `gha_backfill_cursors` after each chunk, so the next run continues from there
+// Stops (keeping the cursor) when there are not enough GitHub API points and their reset is later than GHA2DB_MAX_GHAPI_WAIT
+func ghapi2dbBackfill(ctx *lib.Ctx) {
+	// Connect to Postgres DB
+	c := lib.PgConn(ctx)
+	defer func() { lib.FatalOnError(c.Close()) }()
+
+	// Connect to GitHub API
+	gctx, gc := lib.GHClient(ctx)
+	gql := ghGraphQL(ctx)
+
+	// Get number of CPUs available
+	thrN := lib.GetThreadsNum(ctx)
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	// Get all issues/PRs after the cursor
+	bytes, err := lib.ReadFile(
+		ctx,
+		dataPrefix+"util_sql/all_issues_and_prs.sql",
+	)
+	lib.FatalOnError(err)
+
+	after := lib.GetBackfillCursor(c, ctx, "ghapi2db")
+	lib.Printf(
+		"ghapi2db.go: Backfill (on %d CPUs) starting after issue ID %d, chunk size %d\n",
+		thrN, after, ctx.GHAPIBackfillChunk,
+	)
+	checked, updates, chunks := 0, 0, 0
+	for {
+		// Each issue needs at least 2 REST API calls: issue and its labels
+		// GraphQL API needs a few points per 100 issues, 1 point per issue is a safe upper bound
+		var (
+			rem        int
+			waitPeriod time.Duration
+		)
+		itemPoints := 2
+		if gql != nil {
+			_, rem, waitPeriod, err = gql.RateLimits(gctx)
+			handlePossibleError(err, after, "GraphQL rateLimit")
+			itemPoints = 1
+		} else {
+			_, rem, waitPeriod = lib.GetRateLimits(gctx, gc, true)
+		}
+		lim := lib.BackfillChunkSize(ctx.GHAPIBackfillChunk, rem, ctx.MinGHAPIPoints, itemPoints)
+		if lim == 0 {
+			if waitPeriod.Seconds() <= float64(ctx.MaxGHAPIWaitSeconds) {
+				lib.Printf("API limit reached while backfilling, waiting %v\n", waitPeriod)
+				time.Sleep(waitPeriod)
+				continue
+			}
+			lib.Printf(
+				"API limit reached while backfilling, stopping, don't want to wait %v, next run will continue after issue ID %d\n",
+				waitPeriod, after,
+			)
+			break
+		}
+		sqlQuery, err := lib.RenderSQL(
+			ctx,
+			dataPrefix,
+			string(bytes),
+			lib.NewSQLVars().
+				Set(lib.SQLVarAfter, strconv.FormatInt(after, 10)).
+				Set(lib.SQLVarLim, strconv.Itoa(lim)),
+		)
+		lib.FatalOnError(err)
+		rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
+		issues, maxID := readIssues(ctx, rows)
+		lib.FatalOnError(rows.Close())
+		if len(issues) == 0 {
+			if !ctx.SkipPDB {
+				lib.CompleteBackfill(c, ctx, "ghapi2db", after)
+			}
+			lib.Printf("ghapi2db.go: Backfill completed, next run will start from the beginning\n")
+			break
+		}
+		nChecked, nUpdates := syncIssues(c, ctx, gctx, gc, gql, issues, thrN)
+		checked += nChecked
+		updates += nUpdates
+		chunks++
+		after = maxID
+		if !ctx.SkipPDB {
+			lib.SaveBackfillCursor(c, ctx, "ghapi2db", after)
+		}
+		lib.Printf("ghapi2db.go: Backfill chunk %d: checked %d issues/PRs (%d updated), cursor at issue ID %d\n", chunks, nChecked, nUpdates, after)
+	}
+
+	// Get RateLimits info
+	_, rem, wait := lib.GetRateLimits(gctx, gc, true)
+	lib.Printf(
+		"ghapi2db.go: Backfill processed %d issues/PRs in %d chunks (%d updated): %d API points remain, resets in %v\n",
+		checked, chunks, updates, rem, wait,
+	)
+}
+
+// waitForGHAPIPoints - waits for GitHub API points reset when there are no more than GHA2DB_MIN_GHAPI_POINTS left
+// Aborts when reset is later than GHA2DB_MAX_GHAPI_WAIT
+func waitForGHAPIPoints(ctx *lib.Ctx, gctx context.Context, gc *github.Client, what string) {
+	for {
+		_, rem, waitPeriod := lib.GetRateLimits(gctx, gc, true)
+		if rem > ctx.MinGHAPIPoints {
+			return
+		}
+		if waitPeriod.Seconds() > float64(ctx.MaxGHAPIWaitSeconds) {
+			lib.Fatalf("API limit reached while getting %s, aborting, don't want to wait %v", what, waitPeriod)
+		}
+		lib.Printf("API limit reached while getting %s, waiting %v\n", what, waitPeriod)
+		time.Sleep(time.Duration(1) * time.Second)
+		time.Sleep(waitPeriod)
+	}
+}
+
+// getPRData - gets pull request, its requested reviewers, reviews and review comments using GitHub API
+func getPRData(ctx *lib.Ctx, gctx context.Context, gc *github.Client, owner, repo string, cfg *prConfig) {
+	waitForGHAPIPoints(ctx, gctx, gc, "pull request")
+	pr, _, err := gc.PullRequests.Get(gctx, owner, repo, cfg.number)
+	handlePossibleError(err, cfg, "PullRequests.Get")
+	cfg.ghPR = pr
+
+	waitForGHAPIPoints(ctx, gctx, gc, "pull request requested reviewers")
+	reviewers, _, err := gc.PullRequests.ListReviewers(gctx, owner, repo, cfg.number, &github.ListOptions{PerPage: 100})
+	handlePossibleError(err, cfg, "PullRequests.ListReviewers")
+	cfg.reviewers = reviewers
+
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		waitForGHAPIPoints(ctx, gctx, gc, "pull request reviews")
+		reviews, resp, err := gc.PullRequests.ListReviews(gctx, owner, repo, cfg.number, opt)
+		handlePossibleError(err, cfg, "PullRequests.ListReviews")
+		cfg.reviews = append(cfg.reviews, reviews...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	copt := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		waitForGHAPIPoints(ctx, gctx, gc, "pull request review comments")
+		comments, resp, err := gc.PullRequests.ListComments(gctx, owner, repo, cfg.number, copt)
+		handlePossibleError(err, cfg, "PullRequests.ListComments")
+		cfg.comments = append(cfg.comments, comments...)
+		if resp.NextPage == 0 {
+			break
+		}
+		copt.Page = resp.NextPage
+	}
+}
+
+// artificialPREvent - create artificial 'ArtificialEvent' for a pull request
+// creates new pull request state with merge state and requested reviewers from GitHub API, artificial event and its payload
+func artificialPREvent(c *sql.DB, ctx *lib.Ctx, prID, eid int64, pr *github.PullRequest, reviewers *github.Reviewers, dirty *lib.DirtyRange) {
+	if ctx.SkipPDB {
+		if ctx.Debug > 0 {
+			lib.Printf("Skipping write for pull_request_id: %d, event_id: %d\n", prID, eid)
+		}
+		return
+	}
+	// Create artificial event
+	eventID := lib.PRArtificialEventID(eid)
+	now := time.Now()
+	var mergedByID, mergedByLogin interface{}
+	if pr.MergedBy != nil {
+		mergedByID, mergedByLogin = pr.MergedBy.GetID(), pr.MergedBy.GetLogin()
+	}
+
+	// Start transaction
+	tc, err := c.Begin()
+	lib.FatalOnError(err)
+
+	// Actors: merged by and requested reviewers
+	actors := []*github.User{pr.MergedBy}
+	if reviewers != nil {
+		actors = append(actors, reviewers.Users...)
+	}
+	for _, actor := range actors {
+		if actor == nil {
+			continue
+		}
+		lib.ExecSQLTxWithErr(
+			tc,
+			ctx,
+			lib.InsertIgnore("into gha_actors(id, login, name) "+lib.NValues(3)),
+			lib.AnyArray{actor.GetID(), actor.GetLogin(), ""}...,
+		)
+	}
+
+	// Create new pull request state
+	lib.ExecSQLTxWithErr(
+		tc,
+		ctx,
+		fmt.Sprintf(
+			"insert into gha_pull_requests("+
+				"id, event_id, user_id, base_sha, head_sha, merged_by_id, assignee_id, milestone_id, "+
+				"number, state, locked, title, body, created_at, updated_at, closed_at, merged_at, "+
+				"merge_commit_sha, merged, mergeable, rebaseable, mergeable_state, comments, review_comments, "+
+				"maintainer_can_modify, commits, additions, deletions, changed_files, "+
+				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at, "+
+				"dup_user_login, dupn_assignee_login, dupn_merged_by_login) "+
+				"select id, %s, user_id, base_sha, head_sha, %s, assignee_id, milestone_id, "+
+				"number, %s, locked, title, body, created_at, %s, %s, %s, "+
+				"merge_commit_sha, %s, mergeable, rebaseable, mergeable_state, comments, review_comments, "+
+				"maintainer_can_modify, commits, additions, deletions, changed_files, "+
+				"0, 'devstats-bot', dup_repo_id, dup_repo_name, 'ArtificialEvent', %s, "+
+				"dup_user_login, dupn_assignee_login, %s "+
+				"from gha_pull_requests where id = %s and event_id = %s",
+			lib.NValue(1),
+			lib.NValue(2),
+			lib.NValue(3),
+			lib.NValue(4),
+			lib.NValue(5),
+			lib.NValue(6),
+			lib.NValue(7),
+			lib.NValue(8),
+			lib.NValue(9),
+			lib.NValue(10),
+			lib.NValue(11),
+		),
+		lib.AnyArray{
+			eventID,
+			mergedByID,
+			pr.GetState(),
+			now,
+			lib.TimeOrNil(pr.ClosedAt),
+			lib.TimeOrNil(pr.MergedAt),
+			pr.GetMerged(),
+			now,
+			mergedByLogin,
+			prID,
+			eid,
+		}...,
+	)
+
+	// Copy assignees, set requested reviewers
+	lib.ExecSQLTxWithErr(
+		tc,
+		ctx,
+		fmt.Sprintf(
+			"insert into gha_pull_requests_assignees(pull_request_id, event_id, assignee_id) "+
+				"select pull_request_id, %s, assignee_id "+
+				"from gha_pull_requests_assignees where pull_request_id = %s and event_id = %s",
+			lib.NValue(1),
+			lib.NValue(2),
+			lib.NValue(3),
+		),
+		lib.AnyArray{eventID, prID, eid}...,
+	)
+	if reviewers != nil {
+		for _, reviewer := range reviewers.Users {
+			lib.ExecSQLTxWithErr(
+				tc,
+				ctx,
+				lib.InsertIgnore("into gha_pull_requests_requested_reviewers(pull_request_id, event_id, requested_reviewer_id) "+lib.NValues(3)),
+				lib.AnyArray{prID, eventID, reviewer.GetID()}...,
+			)
+		}
+	}
+
+	// Create artificial 'ArtificialEvent' event
+	lib.ExecSQLTxWithErr(
+		tc,
+		ctx,
+		fmt.Sprintf(
+			"insert into gha_events("+
+				"id, type, actor_id, repo_id, public, created_at, "+
+				"dup_actor_login, dup_repo_name, org_id, forkee_id) "+
+				"select %s, 'ArtificialEvent', 0, repo_id, public, %s, "+
+				"'devstats-bot', dup_repo_name, org_id, forkee_id "+
+				"from gha_events where id = %s",
+			lib.NValue(1),
+			lib.NValue(2),
+			lib.NValue(3),
+		),
+		lib.AnyArray{eventID, now, eid}...,
+	)
+
+	// Create artificial event's payload
+	lib.ExecSQLTxWithErr(
+		tc,
+		ctx,
+		fmt.Sprintf(
+			"insert into gha_payloads("+
+				"event_id, push_id, size, ref, head, befor, action, "+
+				"issue_id, pull_request_id, comment_id, ref_type, master_branch, commit, "+
+				"description, number, forkee_id, release_id, member_id, "+
+				"dup_actor_id, dup_actor_login, dup_repo_id, dup_repo_name, dup_type, dup_created_at) "+
+				"select %s, null, null, null, null, null, 'artificial', "+
+				"issue_id, %s, null, null, null, null, "+
+				"null, number, null, null, null, "+
+				"0, 'devstats-bot', dup_repo_id, dup_repo_name, 'ArtificialEvent', %s "+
+				"from gha_payloads where event_id = %s",
+			lib.NValue(1),
+			lib.NValue(2),
+			lib.NValue(3),
+			lib.NValue(4),
+		),
+		lib.AnyArray{eventID, prID, now, eid}...,
+	)
+
+	// Final commit
+	lib.FatalOnError(tc.Commit())
+	dirty.Add(now)
+}
+
+// savePRData - creates artificial event when pull request merge state or requested reviewers differ from GitHub API
+// and saves its reviews and review comments, returns if artificial event was created and number of saved reviews and comments
+func savePRData(c *sql.DB, ctx *lib.Ctx, cfg *prConfig, dirty *lib.DirtyRange) (bool, int, int) {
+	// Last pull request state
+	var (
+		eid       int64
+		state     lib.PRState
+		merged    *bool
+		repoID    int64
+		reviewers string
+	)
+	rows := lib.QuerySQLWithErr(
+		c,
+		ctx,
+		fmt.Sprintf(
+			"select event_id, state, merged, merged_at, merged_by_id, closed_at, dup_repo_id "+
+				"from gha_pull_requests where id = %s order by updated_at desc, event_id desc limit 1",
+			lib.NValue(1),
+		),
+		cfg.prID,
+	)
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	got := false
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&eid, &state.State, &merged, &state.MergedAt, &state.MergedByID, &state.ClosedAt, &repoID))
+		got = true
+	}
+	lib.FatalOnError(rows.Err())
+	if !got {
+		return false, 0, 0
+	}
+	state.Merged = merged != nil && *merged
+	lib.FatalOnError(
+		lib.QueryRowSQL(
+			c,
+			ctx,
+			fmt.Sprintf(
+				"select coalesce(string_agg(sub.requested_reviewer_id::text, ','), '') from "+
+					"(select requested_reviewer_id from gha_pull_requests_requested_reviewers "+
+					"where pull_request_id = %s and event_id = %s order by requested_reviewer_id) sub",
+				lib.NValue(1),
+				lib.NValue(2),
+			),
+			cfg.prID,
+			eid,
+		).Scan(&reviewers),
+	)
+	state.Reviewers = reviewers
+
+	// Create artificial event if needed
+	diffs := state.Diff(lib.PRStateFromGitHub(cfg.ghPR, cfg.reviewers))
+	if len(diffs) > 0 {
+		if ctx.Debug > 0 {
+			lib.Printf("Updating pull request '%s#%d' (event_id %d): %s\n", cfg.repo, cfg.number, eid, strings.Join(diffs, ", "))
+		}
+		artificialPREvent(c, ctx, cfg.prID, eid, cfg.ghPR, cfg.reviewers, dirty)
+	}
+	if ctx.SkipPDB {
+		return len(diffs) > 0, 0, 0
+	}
+
+	// Reviews, pending reviews are only visible to their authors and are skipped
+	now := time.Now()
+	nReviews := 0
+	for _, review := range cfg.reviews {
+		if review.ID == nil || review.GetState() == "PENDING" {
+			continue
+		}
+		var userID interface{}
+		if review.User != nil {
+			userID = review.User.GetID()
+			lib.ExecSQLWithErr(c, ctx, lib.InsertIgnore("into gha_actors(id, login, name) "+lib.NValues(3)), review.User.GetID(), review.User.GetLogin(), "")
+		}
+		lib.ExecSQLWithErr(
+			c,
+			ctx,
+			"insert into gha_reviews(id, pull_request_id, number, user_id, state, body, commit_id, submitted_at, synced_at, "+
+				"dup_repo_id, dup_repo_name, dup_user_login) "+lib.NValues(12)+
+				" on conflict(id) do update set state = excluded.state, body = excluded.body, commit_id = excluded.commit_id, "+
+				"submitted_at = excluded.submitted_at, synced_at = excluded.synced_at",
+			*review.ID,
+			cfg.prID,
+			cfg.number,
+			userID,
+			review.GetState(),
+			lib.TruncStringOrNil(review.Body, 0xffff),
+			lib.StringOrNil(review.CommitID),
+			lib.TimeOrNil(review.SubmittedAt),
+			now,
+			repoID,
+			cfg.repo,
+			review.User.GetLogin(),
+		)
+		nReviews++
+	}
+
+	// Review comments
+	nComments := 0
+	for _, comment := range cfg.comments {
+		if comment.ID == nil || comment.CreatedAt == nil || comment.UpdatedAt == nil {
+			continue
+		}
+		var userID interface{}
+		if comment.User != nil {
+			userID = comment.User.GetID()
+			lib.ExecSQLWithErr(c, ctx, lib.InsertIgnore("into gha_actors(id, login, name) "+lib.NValues(3)), comment.User.GetID(), comment.User.GetLogin(), "")
+		}
+		lib.ExecSQLWithErr(
+			c,
+			ctx,
+			"insert into gha_reviews_comments(id, pull_request_id, number, user_id, body, path, position, original_position, "+
+				"commit_id, original_commit_id, in_reply_to, created_at, updated_at, synced_at, "+
+				"dup_repo_id, dup_repo_name, dup_user_login) "+lib.NValues(17)+
+				" on conflict(id) do update set body = excluded.body, position = excluded.position, "+
+				"commit_id = excluded.commit_id, updated_at = excluded.updated_at, synced_at = excluded.synced_at",
+			*comment.ID,
+			cfg.prID,
+			cfg.number,
+			userID,
+			lib.TruncStringOrNil(comment.Body, 0xffff),
+			lib.StringOrNil(comment.Path),
+			lib.IntOrNil(comment.Position),
+			lib.IntOrNil(comment.OriginalPosition),
+			lib.StringOrNil(comment.CommitID),
+			lib.StringOrNil(comment.OriginalCommitID),
+			comment.InReplyTo,
+			*comment.CreatedAt,
+			*comment.UpdatedAt,
+			now,
+			repoID,
+			cfg.repo,
+			comment.User.GetLogin(),
+		)
+		nComments++
+	}
+	return len(diffs) > 0, nReviews, nComments
+}
+
+// syncPRs - checks recently updated pull requests (GHA2DB_RECENT_RANGE) using GitHub API
+// Creates artificial events when merge state or requested reviewers differ and saves reviews and review comments
+func syncPRs(ctx *lib.Ctx) {
+	// Connect to Postgres DB
+	c := lib.PgConn(ctx)
+	defer func() { lib.FatalOnError(c.Close()) }()
+
+	// Connect to GitHub API
+	gctx, gc := lib.GHClient(ctx)
+
+	// Get number of CPUs available
+	thrN := lib.GetThreadsNum(ctx)
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	// Get recently updated pull requests
+	bytes, err := lib.ReadFile(
+		ctx,
+		dataPrefix+"util_sql/recent_prs.sql",
+	)
+	lib.FatalOnError(err)
+	sqlQuery, err := lib.RenderSQL(ctx, dataPrefix, string(bytes), lib.NewSQLVars().SetString(lib.SQLVarPeriod, ctx.RecentRange))
+	lib.FatalOnError(err)
+	rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
+	prs := []prConfig{}
+	for rows.Next() {
+		var cfg prConfig
+		lib.FatalOnError(rows.Scan(&cfg.repo, &cfg.number, &cfg.prID))
+		prs = append(prs, cfg)
+	}
+	lib.FatalOnError(rows.Err())
+	lib.FatalOnError(rows.Close())
+	nPRs := len(prs)
+	_, rem, wait := lib.GetRateLimits(gctx, gc, true)
+	lib.Printf("ghapi2db.go: Processing %d pull requests (on %d CPUs): %d API points available, resets in %v\n", nPRs, thrN, rem, wait)
+
+	// The same GitHub abuse detection limits as for issues
+	allowedThrN := 16
+	if allowedThrN > thrN {
+		allowedThrN = thrN
+	}
+	ch := make(chan bool)
+	nThreads := 0
+	dtStart := time.Now()
+	lastTime := dtStart
+	checked := 0
+	var (
+		countersMutex = &sync.Mutex{}
+		updates       int
+		reviews       int
+		comments      int
+		dirty         lib.DirtyRange
+	)
+	for _, pr := range prs {
+		go func(ch chan bool, cfg prConfig) {
+			// Synchronize go routine
+			defer func() { ch <- true }()
+			ary := strings.Split(cfg.repo, "/")
+			if len(ary) != 2 {
+				if ctx.Debug > 0 {
+					lib.Printf("Warning: wrong repository name: %s\n", cfg.repo)
+				}
+				return
+			}
+			getPRData(ctx, gctx, gc, ary[0], ary[1], &cfg)
+			updated, nReviews, nComments := savePRData(c, ctx, &cfg, &dirty)
+			countersMutex.Lock()
+			if updated {
+				updates++
+			}
+			reviews += nReviews
+			comments += nComments
+			countersMutex.Unlock()
+		}(ch, pr)
+
+		nThreads++
+		if nThreads == allowedThrN {
+			<-ch
+			nThreads--
+			checked++
+			lib.ProgressInfo(checked, nPRs, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+		}
+	}
+	// Usually all work happens on '<-ch'
+	lib.Printf("Final pull requests threads join\n")
+	for nThreads > 0 {
+		<-ch
+		nThreads--
+		checked++
+		lib.ProgressInfo(checked, nPRs, dtStart, &lastTime, time.Duration(10)*time.Second, "")
+	}
+	// Record dates of created artificial events
+	if !ctx.SkipPDB {
+		dirty.Save(c, ctx, "ghapi2db")
+	}
+
+	// Get RateLimits info
+	_, rem, wait = lib.GetRateLimits(gctx, gc, true)
+	lib.Printf(
+		"ghapi2db.go: Processed %d pull requests (%d updated, %d reviews, %d review comments): %d API points remain, resets in %v\n",
+		checked, updates, reviews, comments, rem, wait,
+	)
+}
+
 func main() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
+	lib.PromStart(&ctx, "ghapi2db")
+	defer lib.PromFinish(&ctx)
 
 	dtStart := time.Now()
 	// Clean unneeded events
@@ -912,9 +1629,16 @@ func main() {
 		cleanArtificialEvents(&ctx)
 	}
 
-	// Create artificial events
+	// Create artificial events, for recently updated open issues/PRs or for all of them in backfill mode
 	if !ctx.SkipGHAPI {
-		ghapi2db(&ctx)
+		if ctx.GHAPIBackfill {
+			ghapi2dbBackfill(&ctx)
+		} else {
+			ghapi2db(&ctx)
+		}
+		if ctx.GHAPIReviews {
+			syncPRs(&ctx)
+		}
 	}
 	dtEnd := time.Now()
 	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
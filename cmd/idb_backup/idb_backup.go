@@ -9,19 +9,11 @@ import (
 )
 
 func copySeries(ch chan bool, ctxI, ctxO *lib.Ctx, seriesName string) {
-	// Connect to InfluxDB databases
+	// Connect to input InfluxDB database and output time series sinks
 	icI := lib.IDBConn(ctxI)
-	icO := lib.IDBConn(ctxO)
-	defer func() {
-		lib.FatalOnError(icI.Close())
-		lib.FatalOnError(icO.Close())
-	}()
-
-	// Get BatchPoints
-	var pts lib.IDBBatchPointsN
-	bp := lib.IDBBatchPoints(ctxO, &icO)
-	pts.NPoints = 0
-	pts.Points = &bp
+	defer func() { lib.FatalOnError(icI.Close()) }()
+	sink := lib.NewSeriesSink(ctxO)
+	defer sink.Close()
 
 	// Get values from series
 	//lib.Printf("seriesName: '%s'\n", seriesName)
@@ -54,13 +46,12 @@ func copySeries(ch chan bool, ctxI, ctxO *lib.Ctx, seriesName string) {
 			if ctxI.Debug > 0 || ctxO.Debug > 0 {
 				fmt.Printf("%s: tags=%+v, fields=%+v, dt=%v\n", series.Name, tags, fields, dt)
 			}
-			pt := lib.IDBNewPointWithErr(ctxO, series.Name, tags, fields, dt)
-			lib.IDBAddPointN(ctxO, &icO, &pts, pt)
+			sink.AddPoint(series.Name, tags, fields, dt)
 		}
 	}
 	// Write the batch
 	if !ctxO.SkipIDB {
-		lib.FatalOnError(lib.IDBWritePointsN(ctxO, &icO, &pts))
+		lib.FatalOnError(sink.Write())
 	} else if ctxI.Debug > 0 || ctxO.Debug > 0 {
 		lib.Printf("Skipping tags series write\n")
 	}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"strings"
 	"time"
 
 	lib "devstats"
@@ -9,20 +8,6 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// tags contain list of InfluxDB tags
-type tags struct {
-	Tags []tag `yaml:"tags"`
-}
-
-// tag contain each InfluxDB tag data
-type tag struct {
-	Name       string `yaml:"name"`
-	SQLFile    string `yaml:"sql"`
-	SeriesName string `yaml:"series_name"`
-	NameTag    string `yaml:"name_tag"`
-	ValueTag   string `yaml:"value_tag"`
-}
-
 // Insert InfluxDB tags
 func idbTags() {
 	// Environment context parse
@@ -33,10 +18,6 @@ func idbTags() {
 	con := lib.PgConn(&ctx)
 	defer func() { lib.FatalOnError(con.Close()) }()
 
-	// Connect to InfluxDB
-	ic := lib.IDBConn(&ctx)
-	defer func() { lib.FatalOnError(ic.Close()) }()
-
 	// Local or cron mode?
 	dataPrefix := lib.DataDir
 	if ctx.Local {
@@ -49,7 +30,7 @@ func idbTags() {
 		lib.FatalOnError(err)
 		return
 	}
-	var allTags tags
+	var allTags lib.AllTags
 	lib.FatalOnError(yaml.Unmarshal(data, &allTags))
 
 	// No fields value needed
@@ -75,25 +56,19 @@ func idbTags() {
 				lib.Printf("Tag '%s' --> '%s'\n", tg.Name, tg.SeriesName)
 			}
 
-			// Get BatchPoints
-			var pts lib.IDBBatchPointsN
-			bp := lib.IDBBatchPoints(&ctx, &ic)
-			pts.NPoints = 0
-			pts.Points = &bp
+			// Connect to time series outputs, closed before signaling that this tag is done
+			sink := lib.NewSeriesSink(&ctx)
 
 			// Read SQL file
-			bytes, err := lib.ReadFile(&ctx, dataPrefix+dir+tg.SQLFile+".sql")
-			lib.FatalOnError(err)
-			sqlQuery := string(bytes)
-
-			// Handle excluding bots
-			bytes, err = lib.ReadFile(&ctx, dataPrefix+"util_sql/exclude_bots.sql")
+			sqlFile := dataPrefix + dir + tg.SQLFile + ".sql"
+			bytes, err := lib.ReadFile(&ctx, sqlFile)
 			lib.FatalOnError(err)
-			excludeBots := string(bytes)
 
 			// Transform SQL
-			sqlQuery = strings.Replace(sqlQuery, "{{lim}}", "69", -1)
-			sqlQuery = strings.Replace(sqlQuery, "{{exclude_bots}}", excludeBots, -1)
+			sqlQuery, err := lib.RenderSQL(&ctx, dataPrefix, string(bytes), lib.TagsSQLVars(69))
+			if err != nil {
+				lib.Fatalf("%s: %v", sqlFile, err)
+			}
 
 			// Execute SQL
 			rows := lib.QuerySQLWithErr(con, &ctx, sqlQuery)
@@ -101,7 +76,7 @@ func idbTags() {
 
 			// Drop current tags
 			if ctx.IDBDrop {
-				lib.QueryIDB(ic, &ctx, "delete from \""+tg.SeriesName+"\"")
+				sink.DeleteSeries(tg.SeriesName, nil)
 			}
 			tm := lib.TimeParseAny("2014-01-01")
 
@@ -120,17 +95,17 @@ func idbTags() {
 					tags[tg.ValueTag] = lib.NormalizeName(strVal)
 				}
 				// Add batch point
-				pt := lib.IDBNewPointWithErr(&ctx, tg.SeriesName, tags, fields, tm)
-				lib.IDBAddPointN(&ctx, &ic, &pts, pt)
+				sink.AddPoint(tg.SeriesName, tags, fields, tm)
 			}
 			lib.FatalOnError(rows.Err())
 
 			// Write the batch
 			if !ctx.SkipIDB {
-				lib.FatalOnError(lib.IDBWritePointsN(&ctx, &ic, &pts))
+				lib.FatalOnError(sink.Write())
 			} else if ctx.Debug > 0 {
 				lib.Printf("Skipping tags series write\n")
 			}
+			sink.Close()
 
 			// Synchronize go routine
 			if ch != nil {
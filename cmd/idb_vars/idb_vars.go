@@ -10,34 +10,15 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// vars contain list of InfluxDB tag/value pairs
-type vars struct {
-	Vars []tag `yaml:"vars"`
-}
-
-// tag contain each InfluxDB tag data
-type tag struct {
-	Tag     string   `yaml:"tag"`
-	Name    string   `yaml:"name"`
-	Value   string   `yaml:"value"`
-	Command []string `yaml:"command"`
-}
-
 // Insert InfluxDB vars
 func idbVars() {
 	// Environment context parse
 	var ctx lib.Ctx
 	ctx.Init()
 
-	// Connect to InfluxDB
-	ic := lib.IDBConn(&ctx)
-	defer func() { lib.FatalOnError(ic.Close()) }()
-
-	// Get BatchPoints
-	var pts lib.IDBBatchPointsN
-	bp := lib.IDBBatchPoints(&ctx, &ic)
-	pts.NPoints = 0
-	pts.Points = &bp
+	// Connect to time series outputs
+	sink := lib.NewSeriesSink(&ctx)
+	defer sink.Close()
 
 	// Local or cron mode?
 	dataPrefix := lib.DataDir
@@ -51,7 +32,7 @@ func idbVars() {
 		lib.FatalOnError(err)
 		return
 	}
-	var allVars vars
+	var allVars lib.AllIVars
 	lib.FatalOnError(yaml.Unmarshal(data, &allVars))
 
 	// No fields value needed
@@ -68,7 +49,7 @@ func idbVars() {
 		}
 		// Drop current vars
 		if ctx.IDBDrop {
-			lib.QueryIDB(ic, &ctx, "drop series from \""+tag.Tag+"\"")
+			sink.DeleteSeries(tag.Tag, nil)
 		}
 
 		if len(tag.Command) > 0 {
@@ -91,23 +72,17 @@ func idbVars() {
 		}
 
 		// Insert tag name/value
-		lib.IDBAddPointN(
-			&ctx,
-			&ic,
-			&pts,
-			lib.IDBNewPointWithErr(
-				&ctx,
-				tag.Tag,
-				map[string]string{tag.Name: tag.Value},
-				fields,
-				lib.TimeParseAny("2014"),
-			),
+		sink.AddPoint(
+			tag.Tag,
+			map[string]string{tag.Name: tag.Value},
+			fields,
+			lib.TimeParseAny("2014"),
 		)
 	}
 
 	// Write the batch
 	if !ctx.SkipIDB {
-		lib.FatalOnError(lib.IDBWritePointsN(&ctx, &ic, &pts))
+		lib.FatalOnError(sink.Write())
 	} else if ctx.Debug > 0 {
 		lib.Printf("Skipping vars series write\n")
 	}
@@ -32,12 +32,34 @@ type mapStringSet map[string]stringSet
 // mapIntArray - this is a map form string to array of ints
 type mapIntArray map[string][]int
 
+// actorSet - set of actor IDs
+type actorSet map[int64]struct{}
+
+// add - adds actor ID to the set if given insert actually added a row
+func (s actorSet) add(res sql.Result, aid int) {
+	n, err := res.RowsAffected()
+	lib.FatalOnError(err)
+	if n > 0 {
+		s[int64(aid)] = struct{}{}
+	}
+}
+
+// ids - returns actor IDs from the set, it is never nil
+func (s actorSet) ids() []int64 {
+	ids := []int64{}
+	for aid := range s {
+		ids = append(ids, aid)
+	}
+	return ids
+}
+
 // affData - holds single affiliation data
 type affData struct {
-	Login   string
-	Company string
-	From    time.Time
-	To      time.Time
+	Login    string
+	Company  string
+	Original string
+	From     time.Time
+	To       time.Time
 }
 
 // decode emails with ! instead of @
@@ -125,6 +147,13 @@ func importAffs(jsonFN string) {
 	}
 	lib.FatalOnError(json.Unmarshal(data, &users))
 
+	// Company names aliases
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+	companyNames := lib.GetCompanyNames(&ctx, dataPrefix+ctx.CompaniesYaml)
+
 	// Process users affiliations
 	emptyVal := struct{}{}
 	loginEmails := make(mapStringSet)
@@ -205,6 +234,7 @@ func importAffs(jsonFN string) {
 	lib.Printf("%d non-empty names, added actors: %d, updated actors: %d\n", len(loginNames), added, updated)
 
 	// Login - Email(s) 1:N
+	changedActors := make(actorSet)
 	cacheActIDs := make(mapIntArray)
 	added, allEmails := 0, 0
 	for login, emails := range loginEmails {
@@ -224,10 +254,11 @@ func importAffs(jsonFN string) {
 			// And then in new API era 2015+ that actor was active too (so He/Sha will
 			// have entry with valid GitHub actor_id > 0)
 			for _, aid := range actIDs {
-				lib.ExecSQLWithErr(con, &ctx,
+				res := lib.ExecSQLWithErr(con, &ctx,
 					lib.InsertIgnore("into gha_actors_emails(actor_id, email) "+lib.NValues(2)),
 					lib.AnyArray{aid, email}...,
 				)
+				changedActors.add(res, aid)
 				allEmails++
 			}
 		}
@@ -278,7 +309,7 @@ func importAffs(jsonFN string) {
 		for _, aff := range affsAry {
 			var dtFrom, dtTo time.Time
 			ary := strings.Split(aff, " < ")
-			company := strings.TrimSpace(ary[0])
+			company := companyNames.Normalize(ary[0])
 			if len(ary) > 1 {
 				// "company < date" form
 				dtFrom = prevDate
@@ -289,7 +320,7 @@ func importAffs(jsonFN string) {
 				dtTo = defaultEndDate
 			}
 			companies[company] = emptyVal
-			affList = append(affList, affData{Login: login, Company: company, From: dtFrom, To: dtTo})
+			affList = append(affList, affData{Login: login, Company: company, Original: ary[0], From: dtFrom, To: dtTo})
 			prevDate = dtTo
 			allAffs++
 		}
@@ -331,17 +362,60 @@ func importAffs(jsonFN string) {
 		dtFrom := aff.From
 		dtTo := aff.To
 		for _, aid := range actIDs {
-			lib.ExecSQLWithErr(con, &ctx,
+			res := lib.ExecSQLWithErr(con, &ctx,
 				lib.InsertIgnore(
-					"into gha_actors_affiliations(actor_id, company_name, dt_from, dt_to) "+lib.NValues(4)),
-				lib.AnyArray{aid, company, dtFrom, dtTo}...,
+					"into gha_actors_affiliations(actor_id, company_name, original_company_name, dt_from, dt_to) "+lib.NValues(5)),
+				lib.AnyArray{aid, company, lib.TruncToBytes(aff.Original, 160), dtFrom, dtTo}...,
 			)
+			changedActors.add(res, aid)
 		}
 	}
 	lib.Printf(
 		"Processed %d affiliations, added %d actors, cache hit: %d, miss: %d\n",
 		len(affList), added, cached, nonCached,
 	)
+
+	// Rename already imported companies using aliases (when companies aliases were updated)
+	for _, aid := range lib.ApplyCompanyNames(con, &ctx, companyNames) {
+		changedActors[aid] = struct{}{}
+	}
+
+	// Recompute events and commits companies attribution for actors with new emails or changed affiliations
+	lib.UpdateCompaniesAttribution(con, &ctx, changedActors.ids())
+
+	// Report companies that are probably the same but have no aliases
+	reportSimilarCompanies(con, &ctx, companyNames)
+}
+
+// reportSimilarCompanies - lists company names that are probably spellings of the same company
+// Pairs of canonical names from companies aliases file are skipped, they are known to be different companies
+func reportSimilarCompanies(con *sql.DB, ctx *lib.Ctx, companyNames lib.CompanyNames) {
+	rows := lib.QuerySQLWithErr(con, ctx, "select name from gha_companies")
+	defer func() { lib.FatalOnError(rows.Close()) }()
+	var (
+		name  string
+		names []string
+	)
+	for rows.Next() {
+		lib.FatalOnError(rows.Scan(&name))
+		names = append(names, name)
+	}
+	lib.FatalOnError(rows.Err())
+	isCanonical := func(name string) bool {
+		return companyNames[strings.ToLower(name)] == name
+	}
+	n := 0
+	for _, similar := range lib.FindSimilarCompanies(names, lib.CompanySimilarityThreshold) {
+		if isCanonical(similar.Name1) && isCanonical(similar.Name2) {
+			continue
+		}
+		if n == 0 {
+			lib.Printf("Similar company names without aliases, consider adding them to %s:\n", ctx.CompaniesYaml)
+		}
+		lib.Printf("'%s' ~ '%s' (%.2f)\n", similar.Name1, similar.Name2, similar.Similarity)
+		n++
+	}
+	lib.Printf("Found %d similar company names without aliases\n", n)
 }
 
 func main() {
@@ -59,8 +59,14 @@ func mergePDBs() {
 		{"gha_comments", "", "-"},
 		{"gha_commits", "", "-"},
 		{"gha_commits_files", "", "-"},
+		{"gha_commits_files_lines", "", "-"},
+		{"gha_commits_identities", "", "-"},
+		//{"gha_commits_companies", "", "-"},
 		//{"gha_companies", "", "-"},
+		//{"gha_companies_aliases", "", "-"},
 		{"gha_events", "id > 0", "id <= 0"},
+		//{"gha_events_companies", "", "-"},
+		//{"gha_events_companies_hours", "", "-"},
 		//{"gha_events_commits_files", "", "-"},
 		{"gha_forkees", "", "-"},
 		{"gha_issues", "id > 0", "id <= 0"},
@@ -81,6 +87,8 @@ func mergePDBs() {
 		{"gha_releases", "", "-"},
 		{"gha_releases_assets", "", "-"},
 		{"gha_repos", "", "-"},
+		{"gha_reviews", "", "-"},
+		{"gha_reviews_comments", "", "-"},
 		{"gha_skip_commits", "", "-"},
 		{"gha_teams", "", "-"},
 		{"gha_teams_repositories", "", "-"},
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// projectCtx - returns context for linting given project, YAML paths set via env are only used for GHA2DB_PROJECT
+func projectCtx(ctx *lib.Ctx, name string, proj *lib.Project) *lib.Ctx {
+	pctx := *ctx
+	pctx.PgDB = proj.PDB
+	if name == ctx.Project {
+		return &pctx
+	}
+	pctx.Project = name
+	dir := lib.Metrics + name + "/"
+	pctx.MetricsYaml = dir + "metrics.yaml"
+	pctx.GapsYaml = dir + "gaps.yaml"
+	pctx.TagsYaml = dir + "idb_tags.yaml"
+	pctx.IVarsYaml = dir + "idb_vars.yaml"
+	pctx.PVarsYaml = dir + "pdb_vars.yaml"
+	return &pctx
+}
+
+// Lint metrics definitions of all projects from "projects.yaml" (or only GHA2DB_PROJECT)
+func metricsLint() bool {
+	// Environment context parse
+	var ctx lib.Ctx
+	ctx.Init()
+
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
+	// Read defined projects
+	data, err := ioutil.ReadFile(dataPrefix + ctx.ProjectsYaml)
+	lib.FatalOnError(err)
+	var projects lib.AllProjects
+	lib.FatalOnError(yaml.Unmarshal(data, &projects))
+
+	// Sort projects by "order"
+	names := []string{}
+	for name, proj := range projects.Projects {
+		if (ctx.Project != "" && name != ctx.Project) || lib.IsProjectDisabled(&ctx, name, proj.Disabled) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		lib.Fatalf("no enabled projects to check in %s (project: '%s')", ctx.ProjectsYaml, ctx.Project)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return projects.Projects[names[i]].Order < projects.Projects[names[j]].Order
+	})
+
+	// Files shared between projects are reported once
+	reported := make(map[string]struct{})
+	nErrors, nWarnings := 0, 0
+	for _, name := range names {
+		proj := projects.Projects[name]
+		linter := lib.MetricsLinter{Ctx: projectCtx(&ctx, name, &proj), DataPrefix: dataPrefix}
+		if ctx.LintExplain {
+			linter.Con = lib.PgConn(linter.Ctx)
+		}
+		issues := linter.LintProject()
+		if linter.Con != nil {
+			lib.FatalOnError(linter.Con.Close())
+		}
+		for _, issue := range issues {
+			line := issue.String()
+			if _, ok := reported[line]; ok {
+				continue
+			}
+			reported[line] = struct{}{}
+			fmt.Printf("%s: %s\n", name, line)
+			if issue.Warning {
+				nWarnings++
+			} else {
+				nErrors++
+			}
+		}
+	}
+	lib.Printf("Checked %d projects: %d errors, %d warnings\n", len(names), nErrors, nWarnings)
+	return nErrors == 0
+}
+
+func main() {
+	dtStart := time.Now()
+	ok := metricsLint()
+	dtEnd := time.Now()
+	lib.Printf("Time: %v\n", dtEnd.Sub(dtStart))
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -12,20 +12,6 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// vars contain list of Postgres variables to set
-type pvars struct {
-	Vars []pvar `yaml:"vars"`
-}
-
-// pvar contain each Postgres data
-type pvar struct {
-	Name     string     `yaml:"name"`
-	Type     string     `yaml:"type"`
-	Value    string     `yaml:"value"`
-	Command  []string   `yaml:"command"`
-	Replaces [][]string `yaml:"replaces"`
-}
-
 // Insert Postgres vars
 func pdbVars() {
 	// Environment context parse
@@ -48,7 +34,7 @@ func pdbVars() {
 		lib.FatalOnError(err)
 		return
 	}
-	var allVars pvars
+	var allVars lib.AllPVars
 	lib.FatalOnError(yaml.Unmarshal(data, &allVars))
 
 	// All key name - values are stored in map
@@ -45,26 +45,37 @@ func runq(sqlFile string, params []string) {
 		}
 	}
 
+	// Local or cron mode?
+	dataPrefix := lib.DataDir
+	if ctx.Local {
+		dataPrefix = "./"
+	}
+
 	// Read and eventually transform SQL file.
 	bytes, err := lib.ReadFile(&ctx, sqlFile)
 	lib.FatalOnError(err)
 	sqlQuery := string(bytes)
-	qrPeriod := ""
-	qrFrom := ""
-	qrTo := ""
-	qr := false
+	vars := lib.NewSQLVars()
 	for from, to := range replaces {
-		// Special replace 'qr' 'period,from,to' is used for {{period.alias.name}} replacements
+		// Special replace 'qr' 'period,from,to' is used for {{period:alias.name}} replacements
 		if from == "qr" {
 			qrAry := strings.Split(to, ",")
-			qr = true
-			qrPeriod, qrFrom, qrTo = qrAry[0], qrAry[1], qrAry[2]
+			if len(qrAry) != 3 {
+				lib.Fatalf("qr parameter must be 'period,from,to', got: '%s'", to)
+			}
+			vars.SetQuickRange(qrAry[0], qrAry[1], qrAry[2])
+			continue
+		}
+		// "{{name}}" parameters are SQL template variables, values are used as given
+		if len(from) > 4 && strings.HasPrefix(from, "{{") && strings.HasSuffix(from, "}}") {
+			vars.Set(from[2:len(from)-2], to)
 			continue
 		}
 		sqlQuery = strings.Replace(sqlQuery, from, to, -1)
 	}
-	if qr {
-		sqlQuery = lib.PrepareQuickRangeQuery(sqlQuery, qrPeriod, qrFrom, qrTo)
+	sqlQuery, err = lib.RenderSQL(&ctx, dataPrefix, sqlQuery, vars)
+	if err != nil {
+		lib.Fatalf("%s: %v", sqlFile, err)
 	}
 	if ctx.Explain {
 		sqlQuery = strings.Replace(sqlQuery, "select\n", "explain select\n", -1)
@@ -218,7 +229,8 @@ func main() {
 	dtStart := time.Now()
 	if len(os.Args) < 2 {
 		lib.Printf("Required SQL file name [param1 value1 [param2 value2 ...]]\n")
-		lib.Printf("Special replace 'qr' 'period,from,to' is used for {{period.alias.name}} replacements\n")
+		lib.Printf("Parameters '{{name}}' set SQL template variables, other parameters are replaced as given\n")
+		lib.Printf("Special replace 'qr' 'period,from,to' is used for {{period:alias.name}} replacements\n")
 		os.Exit(1)
 	}
 	runq(os.Args[1], os.Args[2:])
@@ -29,15 +29,13 @@ func getMatchingSeries(ctx *lib.Ctx, seriesRegExp string) (seriesSet map[string]
 }
 
 func workerThread(ch chan bool, ctx *lib.Ctx, seriesSet map[string]struct{}, period string, desc bool, values []string, from, to time.Time) {
-	// Connect to InfluxDB
+	// Connect to InfluxDB, current columns are read from it when overwriting all values
 	ic := lib.IDBConn(ctx)
 	defer func() { lib.FatalOnError(ic.Close()) }()
 
-	// Get BatchPoints
-	var pts lib.IDBBatchPointsN
-	bp := lib.IDBBatchPoints(ctx, &ic)
-	pts.NPoints = 0
-	pts.Points = &bp
+	// Connect to time series outputs
+	sink := lib.NewSeriesSink(ctx)
+	defer sink.Close()
 
 	// Zero
 	fields := make(map[string]interface{})
@@ -85,13 +83,12 @@ func workerThread(ch chan bool, ctx *lib.Ctx, seriesSet map[string]struct{}, per
 		}
 
 		// Add batch point
-		pt := lib.IDBNewPointWithErr(ctx, series, nil, fields, from)
-		lib.IDBAddPointN(ctx, &ic, &pts, pt)
+		sink.AddPoint(series, nil, fields, from)
 	}
 
 	// Write the batch
 	if !ctx.SkipIDB {
-		lib.FatalOnError(lib.IDBWritePointsN(ctx, &ic, &pts))
+		lib.FatalOnError(sink.Write())
 	} else if ctx.Debug > 0 {
 		lib.Printf("Skipping series write\n")
 	}
@@ -126,12 +123,16 @@ func z2influx(series, from, to, intervalAbbr string, desc bool, values []string)
 	dTo := lib.TimeParseAny(to)
 
 	// Process interval
-	interval, _, intervalStart, nextIntervalStart, _ := lib.GetIntervalFunctions(intervalAbbr, false)
+	interval, nIntervals, intervalStart, nextIntervalStart, prevIntervalStart := lib.GetIntervalFunctions(intervalAbbr, false)
 
 	// Round dates to the given interval
 	dFrom = intervalStart(dFrom)
 	dTo = nextIntervalStart(dTo)
 
+	// Intervals to fill, in incremental mode also past intervals touched by newly imported events
+	// Series are filled with zeros here so gaps are filled in the same intervals that `db2influx` computes
+	dts := lib.AddDirtyIntervals(&ctx, lib.IntervalStarts(dFrom, dTo, nextIntervalStart), nIntervals, intervalStart, nextIntervalStart, prevIntervalStart, dTo)
+
 	// Get number of CPUs available
 	thrN := lib.GetThreadsNum(&ctx)
 
@@ -142,14 +143,12 @@ func z2influx(series, from, to, intervalAbbr string, desc bool, values []string)
 	} else {
 		lib.Printf("z2influx.go: Running (on %d CPUs): %v - %v with interval %s, descriptions %v, nValues: %d\n", thrN, dFrom, dTo, interval, desc, nValues)
 	}
-	dt := dFrom
 	if thrN > 1 {
 		ch := make(chan bool)
 		nThreads := 0
-		for dt.Before(dTo) {
+		for _, dt := range dts {
 			nDt := nextIntervalStart(dt)
 			go workerThread(ch, &ctx, seriesSet, intervalAbbr, desc, values, dt, nDt)
-			dt = nDt
 			nThreads++
 			if nThreads == thrN {
 				<-ch
@@ -163,10 +162,9 @@ func z2influx(series, from, to, intervalAbbr string, desc bool, values []string)
 		}
 	} else {
 		lib.Printf("Using single threaded version\n")
-		for dt.Before(dTo) {
+		for _, dt := range dts {
 			nDt := nextIntervalStart(dt)
 			workerThread(nil, &ctx, seriesSet, intervalAbbr, desc, values, dt, nDt)
-			dt = nDt
 		}
 	}
 	// Finished
@@ -0,0 +1,386 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Resolved company per event (`gha_events_companies`) and per commit (`gha_commits_companies`)
+// Events of GHA hours imported (or re-imported) since the last run are attributed hourly by `util_sql/postprocess_companies.sql`
+// postprocess script, `gha_events_companies_hours` holds `gha_imported_hours` update dates already attributed
+// Commits are attributed by `get_repos` when their identity is saved
+// Both are recomputed by `import_affs` for actors whose emails or affiliations were changed
+
+// eventsCompaniesQuery - attributes events to companies that actors were affiliated with at event's date
+const eventsCompaniesQuery = "insert into gha_events_companies(event_id, actor_id, company_name, created_at) " +
+	"select ev.id, ev.actor_id, aa.company_name, ev.created_at " +
+	"from gha_events ev, gha_actors_affiliations aa " +
+	"where ev.actor_id = aa.actor_id " +
+	"and aa.dt_from <= ev.created_at " +
+	"and aa.dt_to > ev.created_at " +
+	"and ev.type != 'ArtificialEvent'"
+
+// commitsCompaniesQuery - attributes commits to companies that their author (found by email) was affiliated with at author's date
+const commitsCompaniesQuery = "insert into gha_commits_companies(sha, actor_id, author_email, company_name, dt) " +
+	"select ci.sha, ae.actor_id, ci.author_email, aa.company_name, ci.author_date " +
+	"from gha_commits_identities ci, gha_actors_emails ae, gha_actors_affiliations aa " +
+	"where ci.author_email = ae.email " +
+	"and ae.actor_id = aa.actor_id " +
+	"and aa.dt_from <= ci.author_date " +
+	"and aa.dt_to > ci.author_date"
+
+// AttributeCommitCompaniesTx - attributes a single commit (its identity must be already saved) to companies
+// Used by `get_repos` in the same transaction that saves commit's identity
+func AttributeCommitCompaniesTx(tx *sql.Tx, ctx *Ctx, sha string) {
+	ExecSQLTxWithErr(tx, ctx, commitsCompaniesQuery+" and ci.sha = $1 on conflict do nothing", sha)
+}
+
+// UpdateCompaniesAttribution - recomputes events and commits companies attribution for given actor IDs, nil means all actors
+// Attribution rows no longer matching any affiliation are removed for all actors, because affiliations
+// can also be removed outside of `import_affs` (for example by `scripts/clean_affiliations.sql`)
+func UpdateCompaniesAttribution(con *sql.DB, ctx *Ctx, actorIDs []int64) {
+	if actorIDs != nil && len(actorIDs) == 0 {
+		return
+	}
+	dtStart := time.Now()
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"delete from gha_events_companies ec where not exists(select 1 from gha_actors_affiliations aa "+
+			"where aa.actor_id = ec.actor_id and aa.company_name = ec.company_name "+
+			"and aa.dt_from <= ec.created_at and aa.dt_to > ec.created_at)",
+	)
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"delete from gha_commits_companies cc where not exists(select 1 from gha_actors_emails ae, gha_actors_affiliations aa "+
+			"where ae.actor_id = cc.actor_id and ae.email = cc.author_email "+
+			"and aa.actor_id = cc.actor_id and aa.company_name = cc.company_name "+
+			"and aa.dt_from <= cc.dt and aa.dt_to > cc.dt)",
+	)
+	var (
+		nEvents  int64
+		nCommits int64
+		err      error
+	)
+	if actorIDs == nil {
+		nEvents, err = ExecSQLWithErr(con, ctx, eventsCompaniesQuery+" on conflict do nothing").RowsAffected()
+		FatalOnError(err)
+		nCommits, err = ExecSQLWithErr(con, ctx, commitsCompaniesQuery+" on conflict do nothing").RowsAffected()
+		FatalOnError(err)
+	} else {
+		nEvents, err = ExecSQLWithErr(
+			con,
+			ctx,
+			eventsCompaniesQuery+" and ev.actor_id = any($1) on conflict do nothing",
+			pq.Array(actorIDs),
+		).RowsAffected()
+		FatalOnError(err)
+		nCommits, err = ExecSQLWithErr(
+			con,
+			ctx,
+			commitsCompaniesQuery+" and ae.actor_id = any($1) on conflict do nothing",
+			pq.Array(actorIDs),
+		).RowsAffected()
+		FatalOnError(err)
+	}
+	actors := "all actors"
+	if actorIDs != nil {
+		actors = fmt.Sprintf("%d actors", len(actorIDs))
+	}
+	Printf(
+		"Companies attribution updated for %s: %d events, %d commits added, took %v\n",
+		actors, nEvents, nCommits, time.Now().Sub(dtStart),
+	)
+}
+
+// CompanyAlias - canonical company name and its other spellings
+type CompanyAlias struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// CompanyAliases - holds all companies aliases (`companies.yaml` file)
+type CompanyAliases struct {
+	Companies []CompanyAlias `yaml:"companies"`
+}
+
+// CompanyNames - maps lower case company name spellings (aliases and canonical names) to canonical names
+type CompanyNames map[string]string
+
+// CleanCompanyName - trims company name and replaces any whitespace sequence with a single space
+func CleanCompanyName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Names - returns company names map, validates aliases
+// Canonical names must be unique and cannot be aliases of other companies, one alias can only belong to one company
+func (c *CompanyAliases) Names() (CompanyNames, error) {
+	names := make(CompanyNames)
+	canonical := make(map[string]struct{})
+	for _, company := range c.Companies {
+		name := CleanCompanyName(company.Name)
+		if name == "" {
+			return nil, fmt.Errorf("company name cannot be empty")
+		}
+		key := strings.ToLower(name)
+		if _, ok := canonical[key]; ok {
+			return nil, fmt.Errorf("company '%s' defined more than once", name)
+		}
+		canonical[key] = struct{}{}
+		names[key] = name
+	}
+	for _, company := range c.Companies {
+		name := CleanCompanyName(company.Name)
+		for _, alias := range company.Aliases {
+			alias = CleanCompanyName(alias)
+			if alias == "" {
+				return nil, fmt.Errorf("company '%s': alias cannot be empty", name)
+			}
+			key := strings.ToLower(alias)
+			if _, ok := canonical[key]; ok && key != strings.ToLower(name) {
+				return nil, fmt.Errorf("company '%s': alias '%s' is a name of another company", name, alias)
+			}
+			if other, ok := names[key]; ok && other != name {
+				return nil, fmt.Errorf("company '%s': alias '%s' already belongs to '%s'", name, alias, other)
+			}
+			names[key] = name
+		}
+	}
+	return names, nil
+}
+
+// Normalize - returns canonical company name for a given spelling, case insensitive
+// Names without alias are only cleaned (whitespace)
+func (n CompanyNames) Normalize(name string) string {
+	name = CleanCompanyName(name)
+	if canonical, ok := n[strings.ToLower(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// GetCompanyNames - reads companies aliases YAML file and returns company names map
+// Returns empty map when there is no such file
+func GetCompanyNames(ctx *Ctx, path string) CompanyNames {
+	data, err := ReadFile(ctx, path)
+	if os.IsNotExist(err) {
+		if ctx.Debug > 0 {
+			Printf("No companies aliases file %s, company names are not normalized\n", path)
+		}
+		return CompanyNames{}
+	}
+	FatalOnError(err)
+	var aliases CompanyAliases
+	FatalOnError(yaml.Unmarshal(data, &aliases))
+	names, err := aliases.Names()
+	if err != nil {
+		Fatalf("%s: %v", path, err)
+	}
+	return names
+}
+
+// companyNameSQLClean - SQL expression matching CleanCompanyName: trimmed, any whitespace sequence replaced with a single space
+// Leading and trailing whitespace is removed by regexp, because btrim only removes spaces and strings.Fields splits on any whitespace
+func companyNameSQLClean(column string) string {
+	return "regexp_replace(regexp_replace(" + column + ", '^\\s+|\\s+$', '', 'g'), '\\s+', ' ', 'g')"
+}
+
+// companyNameSQLKey - SQL expression matching CompanyNames keys: cleaned whitespace, lower case
+func companyNameSQLKey(column string) string {
+	return "lower(" + companyNameSQLClean(column) + ")"
+}
+
+// ApplyCompanyNames - saves company names map in `gha_companies_aliases` table and re-derives company names
+// of already imported affiliations from their original names (as spelled in affiliations file) using current aliases
+// Affiliations imported before original names were saved use their company name as original name
+// Returns IDs of actors whose affiliations were renamed, their companies attribution must be recomputed
+func ApplyCompanyNames(con *sql.DB, ctx *Ctx, names CompanyNames) (actorIDs []int64) {
+	tx, err := con.Begin()
+	FatalOnError(err)
+	ExecSQLTxWithErr(tx, ctx, "delete from gha_companies_aliases")
+	for alias, name := range names {
+		ExecSQLTxWithErr(
+			tx,
+			ctx,
+			"insert into gha_companies_aliases(alias, company_name) "+NValues(2),
+			TruncToBytes(alias, 160),
+			TruncToBytes(name, 160),
+		)
+	}
+	// Affiliations whose company name differs from the one derived from original name
+	// Renamed rows are removed first and then inserted with new names, so names can also be swapped
+	original := "coalesce(aa.original_company_name, aa.company_name)"
+	ExecSQLTxWithErr(
+		tx,
+		ctx,
+		"create temp table renamed_affiliations on commit drop as "+
+			"select * from (select aa.actor_id, aa.company_name, "+original+" as original_company_name, aa.dt_from, aa.dt_to, "+
+			"coalesce(ca.company_name, "+companyNameSQLClean(original)+") as new_name "+
+			"from gha_actors_affiliations aa left join gha_companies_aliases ca "+
+			"on "+companyNameSQLKey(original)+" = ca.alias) sub "+
+			"where company_name != new_name",
+	)
+	rows := QuerySQLTxWithErr(
+		tx,
+		ctx,
+		"delete from gha_actors_affiliations aa using renamed_affiliations r "+
+			"where aa.actor_id = r.actor_id and aa.company_name = r.company_name "+
+			"and aa.dt_from = r.dt_from and aa.dt_to = r.dt_to returning aa.actor_id",
+	)
+	actors := make(map[int64]struct{})
+	var aid int64
+	for rows.Next() {
+		FatalOnError(rows.Scan(&aid))
+		actors[aid] = struct{}{}
+	}
+	FatalOnError(rows.Err())
+	FatalOnError(rows.Close())
+	ExecSQLTxWithErr(
+		tx,
+		ctx,
+		"insert into gha_actors_affiliations(actor_id, company_name, original_company_name, dt_from, dt_to) "+
+			"select actor_id, new_name, original_company_name, dt_from, dt_to from renamed_affiliations "+
+			"on conflict do nothing",
+	)
+	// Companies: add all names used by affiliations (also names that are no longer aliased) and remove aliased ones
+	ExecSQLTxWithErr(
+		tx,
+		ctx,
+		"insert into gha_companies(name) select distinct company_name from gha_actors_affiliations "+
+			"on conflict do nothing",
+	)
+	ExecSQLTxWithErr(
+		tx,
+		ctx,
+		"delete from gha_companies c using gha_companies_aliases ca "+
+			"where "+companyNameSQLKey("c.name")+" = ca.alias and c.name != ca.company_name",
+	)
+	FatalOnError(tx.Commit())
+	actorIDs = []int64{}
+	for aid := range actors {
+		actorIDs = append(actorIDs, aid)
+	}
+	Printf("Saved %d company name aliases, renamed affiliations of %d actors\n", len(names), len(actorIDs))
+	return
+}
+
+// companyLegalSuffixes - legal entity suffixes ignored when looking for near-duplicate company names
+var companyLegalSuffixes = map[string]struct{}{
+	"inc": {}, "incorporated": {}, "llc": {}, "ltd": {}, "limited": {}, "corp": {}, "corporation": {},
+	"co": {}, "company": {}, "gmbh": {}, "ag": {}, "sa": {}, "bv": {}, "plc": {}, "srl": {}, "pty": {},
+}
+
+// CompanyNameKey - simplified company name used to find near-duplicates:
+// lower case words without punctuation and without trailing legal entity suffixes
+func CompanyNameKey(name string) string {
+	words := strings.FieldsFunc(
+		strings.ToLower(name),
+		func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) },
+	)
+	for len(words) > 1 {
+		if _, ok := companyLegalSuffixes[words[len(words)-1]]; !ok {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// levenshtein - edit distance of two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// minInt - returns smaller int
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// CompanyNamesSimilarity - similarity of two company names from 0 to 1, 1 means the same simplified names (see CompanyNameKey)
+func CompanyNamesSimilarity(name1, name2 string) float64 {
+	k1, k2 := []rune(CompanyNameKey(name1)), []rune(CompanyNameKey(name2))
+	maxLen := len(k1)
+	if len(k2) > maxLen {
+		maxLen = len(k2)
+	}
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(k1, k2))/float64(maxLen)
+}
+
+// CompanySimilarityThreshold - minimum similarity of company names reported as near-duplicates
+const CompanySimilarityThreshold = 0.85
+
+// SimilarCompanies - two different company names that are probably the same company
+type SimilarCompanies struct {
+	Name1      string
+	Name2      string
+	Similarity float64
+}
+
+// FindSimilarCompanies - returns pairs of company names with similarity >= threshold, most similar first
+// Only names whose simplified names start with the same letter are compared
+func FindSimilarCompanies(names []string, threshold float64) (similar []SimilarCompanies) {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	buckets := make(map[rune][]string)
+	for _, name := range sorted {
+		key := []rune(CompanyNameKey(name))
+		if len(key) == 0 {
+			continue
+		}
+		buckets[key[0]] = append(buckets[key[0]], name)
+	}
+	for _, bucket := range buckets {
+		for i := 0; i < len(bucket); i++ {
+			for j := i + 1; j < len(bucket); j++ {
+				if bucket[i] == bucket[j] {
+					continue
+				}
+				sim := CompanyNamesSimilarity(bucket[i], bucket[j])
+				if sim >= threshold {
+					similar = append(similar, SimilarCompanies{Name1: bucket[i], Name2: bucket[j], Similarity: sim})
+				}
+			}
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Similarity != similar[j].Similarity {
+			return similar[i].Similarity > similar[j].Similarity
+		}
+		if similar[i].Name1 != similar[j].Name1 {
+			return similar[i].Name1 < similar[j].Name1
+		}
+		return similar[i].Name2 < similar[j].Name2
+	})
+	return
+}
@@ -0,0 +1,128 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestCompanyNamesNormalize(t *testing.T) {
+	// Example aliases
+	aliases := lib.CompanyAliases{
+		Companies: []lib.CompanyAlias{
+			{Name: "Google", Aliases: []string{"Google Inc.", "Google  LLC"}},
+			{Name: "Red Hat", Aliases: []string{"RedHat", "Red Hat, Inc."}},
+		},
+	}
+	names, err := aliases.Names()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		name     string
+		expected string
+	}{
+		{name: "Google", expected: "Google"},
+		{name: "google", expected: "Google"},
+		{name: "Google Inc.", expected: "Google"},
+		{name: " Google\tLLC ", expected: "Google"},
+		{name: "GOOGLE INC.", expected: "Google"},
+		{name: "Google Cloud", expected: "Google Cloud"},
+		{name: "redhat", expected: "Red Hat"},
+		{name: "Red Hat, Inc.", expected: "Red Hat"},
+		{name: "  Independent  ", expected: "Independent"},
+		{name: "", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := names.Normalize(test.name)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s' for '%s'", index+1, test.expected, got, test.name)
+		}
+	}
+}
+
+func TestCompanyAliasesNames(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		companies []lib.CompanyAlias
+		err       bool
+	}{
+		{companies: []lib.CompanyAlias{}},
+		{companies: []lib.CompanyAlias{{Name: "Google", Aliases: []string{"Google Inc.", "google"}}, {Name: "IBM"}}},
+		{companies: []lib.CompanyAlias{{Name: " ", Aliases: []string{"X"}}}, err: true},
+		{companies: []lib.CompanyAlias{{Name: "Google", Aliases: []string{""}}}, err: true},
+		{companies: []lib.CompanyAlias{{Name: "Google"}, {Name: "google"}}, err: true},
+		{companies: []lib.CompanyAlias{{Name: "Google", Aliases: []string{"Alphabet"}}, {Name: "Alphabet"}}, err: true},
+		{companies: []lib.CompanyAlias{{Name: "A", Aliases: []string{"X"}}, {Name: "B", Aliases: []string{"x"}}}, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		aliases := lib.CompanyAliases{Companies: test.companies}
+		_, err := aliases.Names()
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error: %v, got: %v", index+1, test.err, err)
+		}
+	}
+}
+
+func TestCompanyNameKey(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		name     string
+		expected string
+	}{
+		{name: "Google", expected: "google"},
+		{name: "Google Inc.", expected: "google"},
+		{name: "Google, LLC", expected: "google"},
+		{name: "Red Hat, Inc.", expected: "red hat"},
+		{name: "SAP SE", expected: "sap se"},
+		{name: "Huawei Technologies Co., Ltd.", expected: "huawei technologies"},
+		{name: "Inc", expected: "inc"},
+		{name: "!!!", expected: ""},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.CompanyNameKey(test.name)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s' for '%s'", index+1, test.expected, got, test.name)
+		}
+	}
+}
+
+func TestFindSimilarCompanies(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		names    []string
+		expected []lib.SimilarCompanies
+	}{
+		{names: []string{}},
+		{names: []string{"Google", "IBM", "Microsoft"}},
+		{
+			names: []string{"Google LLC", "IBM", "Google", "Google Inc."},
+			expected: []lib.SimilarCompanies{
+				{Name1: "Google", Name2: "Google Inc.", Similarity: 1},
+				{Name1: "Google", Name2: "Google LLC", Similarity: 1},
+				{Name1: "Google Inc.", Name2: "Google LLC", Similarity: 1},
+			},
+		},
+		{
+			names: []string{"Mirantis", "Mirantis Inc", "Mirantys", "Microsoft"},
+			expected: []lib.SimilarCompanies{
+				{Name1: "Mirantis", Name2: "Mirantis Inc", Similarity: 1},
+				{Name1: "Mirantis", Name2: "Mirantys", Similarity: 0.875},
+				{Name1: "Mirantis Inc", Name2: "Mirantys", Similarity: 0.875},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.FindSimilarCompanies(test.names, lib.CompanySimilarityThreshold)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
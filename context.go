@@ -53,7 +53,11 @@ type Ctx struct {
 	TagsYaml            string          // From GHA2DB_TAGS_YAML idb_tags tool, set other idb_tags.yaml file, default is "metrics/{{project}}/idb_tags.yaml"
 	IVarsYaml           string          // From GHA2DB_IVARS_YAML idb_vars tool, set other idb_vars.yaml file, default is "metrics/{{project}}/idb_vars.yaml"
 	PVarsYaml           string          // From GHA2DB_PVARS_YAML pdb_vars tool, set other pdb_vars.yaml file, default is "metrics/{{project}}/pdb_vars.yaml"
-	GitHubOAuth         string          // From GHA2DB_GITHUB_OAUTH ghapi2db tool, if not set reads from /etc/github/oauth file, set to "-" to force public access.
+	AnnotationsYaml     string          // From GHA2DB_ANNOTATIONS_YAML annotations tool, set other annotations.yaml file (custom project events), default is "metrics/{{project}}/annotations.yaml"
+	QuickRangesYaml     string          // From GHA2DB_QUICK_RANGES_YAML annotations tool, set other quick_ranges.yaml file, default is "metrics/{{project}}/quick_ranges.yaml", `quick_ranges:` in `projects.yaml` has the highest priority
+	GitHubOAuth         string          // From GHA2DB_GITHUB_OAUTH ghapi2db tool, if not set reads from /etc/github/oauth file, set to "-" to force public access. Can be a comma separated list of tokens and/or files with tokens, the token with the most remaining API points is used for each request.
+	GitHubCache         string          // From GHA2DB_GITHUB_CACHE ghapi2db tool, cache GitHub API responses and use conditional requests (not modified responses don't cost API points): directory or "postgres" (`gha_ghapi_cache` table in `devstats` database), default "" (no cache)
+	GitHubCacheSize     int             // From GHA2DB_GITHUB_CACHE_SIZE ghapi2db tool, max number of responses cached in GHA2DB_GITHUB_CACHE directory, least recently used ones are removed, <= 0 means no limit, default 20000
 	ClearDBPeriod       string          // From GHA2DB_MAXLOGAGE gha2db_sync tool, maximum age of devstats.gha_logs entries, default "1 week"
 	Trials              []int           // From GHA2DB_TRIALS, all Postgres related tools, retry periods for "too many connections open" error
 	WebHookRoot         string          // From GHA2DB_WHROOT, webhook tool, default "/hook", must match .travis.yml notifications webhooks
@@ -69,14 +73,17 @@ type Ctx struct {
 	ExecFatal           bool            // default true, set this manually to false to avoid lib.ExecCommand calling os.Exit() on failure and return error instead
 	ExecQuiet           bool            // default false, set this manually to true to have quite exec failures (for example `get_repos` git-clones or git-pulls on errors).
 	ExecOutput          bool            // default false, set to true to capture commands STDOUT
+	ExecTimeout         time.Duration   // default 0 (no timeout), set this manually to kill commands (with their child processes) running longer than this
 	Project             string          // From GHA2DB_PROJECT, gha2db_sync default "", You should set it to something like "kubernetes", "prometheus" etc.
 	TestsYaml           string          // From GHA2DB_TESTS_YAML ./dbtest.sh tool, set other tests.yaml file, default is "tests.yaml"
 	ReposDir            string          // From GHA2DB_REPOS_DIR get_repos tool, default "~/devstats_repos/"
+	GitShell            bool            // From GHA2DB_GIT_SHELL, get_repos and annotations tools, use git shell scripts instead of go-git library, default false
 	ProcessRepos        bool            // From GHA2DB_PROCESS_REPOS get_repos tool, enable processing (cloning/pulling) all devstats repos, default false
 	ProcessCommits      bool            // From GHA2DB_PROCESS_COMMITS get_repos tool, enable update/create mapping table: commit - list of file that commit refers to, default false
 	ExternalInfo        bool            // From GHA2DB_EXTERNAL_INFO get_repos tool, enable outputing data needed by external tools (cncf/gitdm), default false
 	ProjectsCommits     string          // From GHA2DB_PROJECTS_COMMITS get_repos tool, set list of projects for commits analysis instead of analysing all, default "" - means all
 	ProjectsYaml        string          // From GHA2DB_PROJECTS_YAML, many tools - set main projects file, default "projects.yaml"
+	CompaniesYaml       string          // From GHA2DB_COMPANIES_YAML, import_affs tool - set company names aliases file, default "companies.yaml"
 	ProjectsOverride    map[string]bool // From GHA2DB_PROJECTS_OVERRIDE, get_repos and ./devstats tools - for example "-pro1,+pro2" means never sync pro1 and always sync pro2 (even if disabled in `projects.yaml`).
 	ExcludeRepos        map[string]bool // From GHA2DB_EXCLUDE_REPOS, gha2db tool, default "" - comma separated list of repos to exclude, example: "theupdateframework/notary,theupdateframework/other"
 	InputDBs            []string        // From GHA2DB_INPUT_DBS, merge_pdbs tool - list of input databases to merge, order matters - first one will insert on a clean DB, next will do insert ignore (to avoid constraints failure due to common data)
@@ -87,6 +94,10 @@ type Ctx struct {
 	MinGHAPIPoints      int             // From GHA2DB_MIN_GHAPI_POINTS, ghapi2db tool, minimum GitHub API points, before waiting for reset.
 	MaxGHAPIWaitSeconds int             // From GHA2DB_MAX_GHAPI_WAIT, ghapi2db tool, maximum wait time for GitHub API points reset (in seconds).
 	SkipGHAPI           bool            // From GHA2DB_GHAPISKIP, ghapi2db tool, if set then tool is not creating artificial events using GitHub API
+	GHAPIBackfill       bool            // From GHA2DB_GHAPI_BACKFILL, ghapi2db tool, check all issues/PRs (not only recently updated open ones) in resumable chunks, default false
+	GHAPIBackfillChunk  int             // From GHA2DB_GHAPI_BACKFILL_CHUNK, ghapi2db tool, number of issues/PRs checked in a single backfill chunk (cursor is saved after each chunk), default 1000
+	GHAPIGraphQL        bool            // From GHA2DB_GHAPI_GRAPHQL, ghapi2db tool, use GitHub GraphQL API (v4) to get issues milestones and labels, up to 100 issues per query, default false (REST API)
+	GHAPIReviews        bool            // From GHA2DB_GHAPI_REVIEWS, ghapi2db tool, also check recently updated PRs merge state and requested reviewers and save their reviews and review comments, default false
 	SkipArtificailClean bool            // From GHA2DB_AECLEANSKIP, ghapi2db tool, if set then tool is not attempting to clean unneeded artificial events
 	SkipGetRepos        bool            // From GHA2DB_GETREPOSSKIP, get_repos tool, if set then tool does nothing
 	OnlyIssues          []int64         // From GHA2DB_ONLY_ISSUES, ghapi2db tool, process a user provided list of issues "issue_id1,issue_id2,...,issue_idN", default "". This is for GH API debugging.
@@ -99,6 +110,31 @@ type Ctx struct {
 	ActorsAllow         *regexp.Regexp  // From GHA2DB_ACTORS_ALLOW, gha2db tool, process JSON if actor matches this regexp, default ""
 	ActorsForbid        *regexp.Regexp  // From GHA2DB_ACTORS_FORBID, gha2db tool, process JSON if actor matches this regexp, default ""
 	OnlyMetrics         map[string]bool // From GHA2DB_ONLY_METRICS, gha2db_sync tool, default "" - comma separated list of metrics to process, as fiven my "sql: name" in the "metrics.yaml" file. Only those metrics will be calculated.
+	GHAURL              string          // From GHA2DB_GHA_URL, gha2db tool, GHA archive source: URL template or local directory, "{{dt}}" is replaced with "YYYY-MM-DD-H", default "http://data.gharchive.org/{{dt}}.json.gz"
+	GHACacheDir         string          // From GHA2DB_GHA_CACHE_DIR, gha2db tool, if set - store downloaded GHA archives in this directory and reuse them, default ""
+	GHAOffline          bool            // From GHA2DB_GHA_OFFLINE, gha2db tool, if set - never download GHA archives, only use GHA2DB_GHA_CACHE_DIR or local GHA2DB_GHA_URL, default false
+	MissingHours        bool            // From GHA2DB_MISSING_HOURS, gha2db tool, only import hours from the given range that are not marked as imported in `gha_imported_hours` table, default false
+	SkipHeal            bool            // From GHA2DB_SKIP_HEAL, gha2db_sync tool, skip detecting and re-importing missing or failed GHA hours, default false
+	ParseWorkers        int             // From GHA2DB_PARSE_WORKERS, gha2db tool, number of go routines parsing JSONs of a single GHA hour, default 1 (parse in the hour's go routine)
+	Bulk                bool            // From GHA2DB_BULK, gha2db tool, collect all rows of a GHA hour and write them using multi-row inserts in a single transaction, default false (write row by row)
+	ProjectsParallel    int             // From GHA2DB_PROJECTS_PARALLEL, devstats tool, maximum number of projects synced at the same time (projects sharing Postgres or Influx database are never synced at the same time), default 1
+	ProjectTimeout      time.Duration   // From GHA2DB_PROJECT_TIMEOUT, devstats tool, kill project's sync when it takes longer (like "2h", "45m"), can be set per project in `projects.yaml` via `sync_timeout:`, default 0 (no timeout)
+	SyncRunID           int             // From GHA2DB_SYNC_RUN_ID, gha2db_sync tool, ID of parent `devstats` run in `gha_sync_runs` table, set by `devstats` tool, default 0 (no parent)
+	SyncYaml            string          // From GHA2DB_SYNC_YAML, gha2db_sync tool, set other sync phases file, default "sync.yaml"
+	SyncFrom            string          // From GHA2DB_SYNC_FROM, gha2db_sync tool, start sync from this phase (skip all phases before it), default "" (run all phases)
+	SyncResume          bool            // From GHA2DB_SYNC_RESUME, gha2db_sync tool, if last project's sync failed - start from its failed phase, default false
+	Incremental         bool            // From GHA2DB_INCREMENTAL, gha2db_sync, gha2db, ghapi2db tools, recompute all periods every run, but only intervals touched by events in `gha_dirty_ranges` (only recorded when set), default false (use time of day schedule)
+	DirtyMaxID          int             // From GHA2DB_DIRTY_MAX_ID, db2influx and z2influx tools, also compute intervals touched by `gha_dirty_ranges` with id <= this, set by gha2db_sync in incremental mode, default 0 (don't use dirty ranges)
+	PromListen          string          // From GHA2DB_PROM_LISTEN, gha2db, gha2db_sync, ghapi2db, devstats tools, serve operational metrics on "/metrics" HTTP endpoint on this address (like ":9101"), default "" (don't serve)
+	PromTextfileDir     string          // From GHA2DB_PROM_TEXTFILE_DIR, gha2db, gha2db_sync, ghapi2db, devstats tools, write operational metrics to node_exporter textfile collector directory when finished, default "" (don't write)
+	SeriesSinks         []string        // From GHA2DB_SERIES_SINKS, db2influx, z2influx, idb_tags, idb_vars, annotations, idb_backup tools, comma separated list of time series outputs: "influx", "postgres", "file", default "influx"
+	SeriesFile          string          // From GHA2DB_SERIES_FILE, line protocol file written by the "file" series sink, "{{project}}" is replaced with GHA2DB_PROJECT, default "series_{{project}}.lp"
+	SeriesDiff          bool            // From GHA2DB_DIFF, db2influx, gha2db_sync and other tools writing series, don't write series, compare computed points with existing InfluxDB points and output differences, default false
+	DiffJSON            bool            // From GHA2DB_DIFF_JSON, output series differences as JSON (one object per series per line), default false (text)
+	DiffTolerance       float64         // From GHA2DB_DIFF_TOLERANCE, numeric values differing by no more than this are considered equal, default 0
+	DiffFile            string          // From GHA2DB_DIFF_FILE, append series differences to this file, required by gha2db_sync in diff mode, default "" (stdout)
+	DiffSeries          string          // From GHA2DB_DIFF_SERIES, InfluxDB RegExp (like "/^reviewers_/"), existing series matching it and not computed anymore are reported as removed, default "" (only compare computed series)
+	LintExplain         bool            // From GHA2DB_LINT_EXPLAIN, metrics_lint tool, run EXPLAIN on all metrics and tags SQL queries using project's Postgres database, default false
 }
 
 // Init - get context from environment variables
@@ -106,6 +142,7 @@ func (ctx *Ctx) Init() {
 	ctx.ExecFatal = true
 	ctx.ExecQuiet = false
 	ctx.ExecOutput = false
+	ctx.ExecTimeout = 0
 
 	// Outputs
 	ctx.JSONOut = os.Getenv("GHA2DB_JSON") != ""
@@ -324,6 +361,8 @@ func (ctx *Ctx) Init() {
 	ctx.TagsYaml = os.Getenv("GHA2DB_TAGS_YAML")
 	ctx.IVarsYaml = os.Getenv("GHA2DB_IVARS_YAML")
 	ctx.PVarsYaml = os.Getenv("GHA2DB_PVARS_YAML")
+	ctx.AnnotationsYaml = os.Getenv("GHA2DB_ANNOTATIONS_YAML")
+	ctx.QuickRangesYaml = os.Getenv("GHA2DB_QUICK_RANGES_YAML")
 	if ctx.MetricsYaml == "" {
 		ctx.MetricsYaml = "metrics/" + proj + "metrics.yaml"
 	}
@@ -339,12 +378,28 @@ func (ctx *Ctx) Init() {
 	if ctx.PVarsYaml == "" {
 		ctx.PVarsYaml = "metrics/" + proj + "pdb_vars.yaml"
 	}
+	if ctx.AnnotationsYaml == "" {
+		ctx.AnnotationsYaml = "metrics/" + proj + "annotations.yaml"
+	}
+	if ctx.QuickRangesYaml == "" {
+		ctx.QuickRangesYaml = "metrics/" + proj + "quick_ranges.yaml"
+	}
 
 	// GitHub OAuth
 	ctx.GitHubOAuth = os.Getenv("GHA2DB_GITHUB_OAUTH")
 	if ctx.GitHubOAuth == "" {
 		ctx.GitHubOAuth = "/etc/github/oauth"
 	}
+	ctx.GitHubCache = os.Getenv("GHA2DB_GITHUB_CACHE")
+	if ctx.GitHubCache != "" && ctx.GitHubCache != "postgres" && ctx.GitHubCache[len(ctx.GitHubCache)-1:] != "/" {
+		ctx.GitHubCache += "/"
+	}
+	ctx.GitHubCacheSize = 20000
+	if os.Getenv("GHA2DB_GITHUB_CACHE_SIZE") != "" {
+		size, err := strconv.Atoi(os.Getenv("GHA2DB_GITHUB_CACHE_SIZE"))
+		FatalNoLog(err)
+		ctx.GitHubCacheSize = size
+	}
 
 	// Max DB logs age
 	ctx.ClearDBPeriod = os.Getenv("GHA2DB_MAXLOGAGE")
@@ -475,6 +530,12 @@ func (ctx *Ctx) Init() {
 		ctx.ProjectsYaml = "projects.yaml"
 	}
 
+	// Company names aliases file
+	ctx.CompaniesYaml = os.Getenv("GHA2DB_COMPANIES_YAML")
+	if ctx.CompaniesYaml == "" {
+		ctx.CompaniesYaml = "companies.yaml"
+	}
+
 	// `get_repos` repositories dir
 	ctx.ReposDir = os.Getenv("GHA2DB_REPOS_DIR")
 	if ctx.ReposDir == "" {
@@ -483,6 +544,7 @@ func (ctx *Ctx) Init() {
 	if ctx.ReposDir[len(ctx.ReposDir)-1:] != "/" {
 		ctx.ReposDir += "/"
 	}
+	ctx.GitShell = os.Getenv("GHA2DB_GIT_SHELL") != ""
 	// `get_repos`: process repos, process commits, external info
 	ctx.ProcessRepos = os.Getenv("GHA2DB_PROCESS_REPOS") != ""
 	ctx.ProcessCommits = os.Getenv("GHA2DB_PROCESS_COMMITS") != ""
@@ -518,8 +580,125 @@ func (ctx *Ctx) Init() {
 		ctx.RecentRange = "2 hours"
 	}
 
+	// ghapi2db GraphQL API
+	ctx.GHAPIGraphQL = os.Getenv("GHA2DB_GHAPI_GRAPHQL") != ""
+
+	// ghapi2db PR reviews sync
+	ctx.GHAPIReviews = os.Getenv("GHA2DB_GHAPI_REVIEWS") != ""
+
+	// ghapi2db backfill mode - check all issues in chunks
+	ctx.GHAPIBackfill = os.Getenv("GHA2DB_GHAPI_BACKFILL") != ""
+	ctx.GHAPIBackfillChunk = 1000
+	if os.Getenv("GHA2DB_GHAPI_BACKFILL_CHUNK") != "" {
+		chunk, err := strconv.Atoi(os.Getenv("GHA2DB_GHAPI_BACKFILL_CHUNK"))
+		FatalNoLog(err)
+		if chunk > 0 {
+			ctx.GHAPIBackfillChunk = chunk
+		}
+	}
+
 	ctx.CSVFile = os.Getenv("GHA2DB_CSVOUT")
 
+	// GHA archives source, cache & offline mode
+	ctx.GHAURL = os.Getenv("GHA2DB_GHA_URL")
+	if ctx.GHAURL == "" {
+		ctx.GHAURL = GHADefaultURL
+	}
+	ctx.GHACacheDir = os.Getenv("GHA2DB_GHA_CACHE_DIR")
+	if ctx.GHACacheDir != "" && ctx.GHACacheDir[len(ctx.GHACacheDir)-1:] != "/" {
+		ctx.GHACacheDir += "/"
+	}
+	ctx.GHAOffline = os.Getenv("GHA2DB_GHA_OFFLINE") != ""
+
+	// Import only missing hours, detect & heal missing hours
+	ctx.MissingHours = os.Getenv("GHA2DB_MISSING_HOURS") != ""
+	ctx.SkipHeal = os.Getenv("GHA2DB_SKIP_HEAL") != ""
+
+	// Number of JSON parse workers per GHA hour
+	ctx.ParseWorkers = 1
+	if os.Getenv("GHA2DB_PARSE_WORKERS") != "" {
+		workers, err := strconv.Atoi(os.Getenv("GHA2DB_PARSE_WORKERS"))
+		FatalNoLog(err)
+		if workers > 0 {
+			ctx.ParseWorkers = workers
+		}
+	}
+
+	// Bulk (multi-row) inserts
+	ctx.Bulk = os.Getenv("GHA2DB_BULK") != ""
+
+	// Parallel projects sync and per project timeout
+	ctx.ProjectsParallel = 1
+	if os.Getenv("GHA2DB_PROJECTS_PARALLEL") != "" {
+		parallel, err := strconv.Atoi(os.Getenv("GHA2DB_PROJECTS_PARALLEL"))
+		FatalNoLog(err)
+		if parallel > 0 {
+			ctx.ProjectsParallel = parallel
+		}
+	}
+	ctx.ProjectTimeout = 0
+	if os.Getenv("GHA2DB_PROJECT_TIMEOUT") != "" {
+		timeout, err := time.ParseDuration(os.Getenv("GHA2DB_PROJECT_TIMEOUT"))
+		FatalNoLog(err)
+		ctx.ProjectTimeout = timeout
+	}
+
+	// Parent sync run
+	ctx.SyncRunID = 0
+	if os.Getenv("GHA2DB_SYNC_RUN_ID") != "" {
+		runID, err := strconv.Atoi(os.Getenv("GHA2DB_SYNC_RUN_ID"))
+		FatalNoLog(err)
+		ctx.SyncRunID = runID
+	}
+
+	// Sync phases
+	ctx.SyncYaml = os.Getenv("GHA2DB_SYNC_YAML")
+	if ctx.SyncYaml == "" {
+		ctx.SyncYaml = "sync.yaml"
+	}
+	ctx.SyncFrom = os.Getenv("GHA2DB_SYNC_FROM")
+	ctx.SyncResume = os.Getenv("GHA2DB_SYNC_RESUME") != ""
+
+	// Incremental sync
+	ctx.Incremental = os.Getenv("GHA2DB_INCREMENTAL") != ""
+	ctx.DirtyMaxID = 0
+	if os.Getenv("GHA2DB_DIRTY_MAX_ID") != "" {
+		maxID, err := strconv.Atoi(os.Getenv("GHA2DB_DIRTY_MAX_ID"))
+		FatalNoLog(err)
+		ctx.DirtyMaxID = maxID
+	}
+
+	// Operational metrics
+	ctx.PromListen = os.Getenv("GHA2DB_PROM_LISTEN")
+	ctx.PromTextfileDir = os.Getenv("GHA2DB_PROM_TEXTFILE_DIR")
+
+	// Time series outputs
+	sinks := os.Getenv("GHA2DB_SERIES_SINKS")
+	if sinks == "" {
+		ctx.SeriesSinks = []string{SeriesSinkInflux}
+	} else {
+		ctx.SeriesSinks = strings.Split(sinks, ",")
+	}
+	ctx.SeriesFile = os.Getenv("GHA2DB_SERIES_FILE")
+	if ctx.SeriesFile == "" {
+		ctx.SeriesFile = "series_{{project}}.lp"
+	}
+
+	// Series diff mode
+	ctx.SeriesDiff = os.Getenv("GHA2DB_DIFF") != ""
+	ctx.DiffJSON = os.Getenv("GHA2DB_DIFF_JSON") != ""
+	ctx.DiffTolerance = 0
+	if os.Getenv("GHA2DB_DIFF_TOLERANCE") != "" {
+		tolerance, err := strconv.ParseFloat(os.Getenv("GHA2DB_DIFF_TOLERANCE"), 64)
+		FatalNoLog(err)
+		ctx.DiffTolerance = tolerance
+	}
+	ctx.DiffFile = os.Getenv("GHA2DB_DIFF_FILE")
+	ctx.DiffSeries = os.Getenv("GHA2DB_DIFF_SERIES")
+
+	// Metrics definitions linter
+	ctx.LintExplain = os.Getenv("GHA2DB_LINT_EXPLAIN") != ""
+
 	issues := os.Getenv("GHA2DB_ONLY_ISSUES")
 	if issues == "" {
 		ctx.OnlyIssues = []int64{}
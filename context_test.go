@@ -47,6 +47,11 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		SkipIDB:             in.SkipIDB,
 		SkipPDB:             in.SkipPDB,
 		SkipGHAPI:           in.SkipGHAPI,
+		GHAPIBackfill:       in.GHAPIBackfill,
+		GHAPIBackfillChunk:  in.GHAPIBackfillChunk,
+		GHAPIReviews:        in.GHAPIReviews,
+		GHAPIGraphQL:        in.GHAPIGraphQL,
+		GitShell:            in.GitShell,
 		SkipArtificailClean: in.SkipArtificailClean,
 		SkipGetRepos:        in.SkipGetRepos,
 		ResetIDB:            in.ResetIDB,
@@ -63,7 +68,11 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		TagsYaml:            in.TagsYaml,
 		IVarsYaml:           in.IVarsYaml,
 		PVarsYaml:           in.PVarsYaml,
+		AnnotationsYaml:     in.AnnotationsYaml,
+		QuickRangesYaml:     in.QuickRangesYaml,
 		GitHubOAuth:         in.GitHubOAuth,
+		GitHubCache:         in.GitHubCache,
+		GitHubCacheSize:     in.GitHubCacheSize,
 		ClearDBPeriod:       in.ClearDBPeriod,
 		Trials:              in.Trials,
 		LogTime:             in.LogTime,
@@ -83,11 +92,13 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		ExecFatal:           in.ExecFatal,
 		ExecQuiet:           in.ExecQuiet,
 		ExecOutput:          in.ExecOutput,
+		ExecTimeout:         in.ExecTimeout,
 		ProcessRepos:        in.ProcessRepos,
 		ProcessCommits:      in.ProcessCommits,
 		ExternalInfo:        in.ExternalInfo,
 		ProjectsCommits:     in.ProjectsCommits,
 		ProjectsYaml:        in.ProjectsYaml,
+		CompaniesYaml:       in.CompaniesYaml,
 		ProjectsOverride:    in.ProjectsOverride,
 		ExcludeRepos:        in.ExcludeRepos,
 		InputDBs:            in.InputDBs,
@@ -102,6 +113,31 @@ func copyContext(in *lib.Ctx) *lib.Ctx {
 		ActorsAllow:         in.ActorsAllow,
 		ActorsForbid:        in.ActorsForbid,
 		OnlyMetrics:         in.OnlyMetrics,
+		GHAURL:              in.GHAURL,
+		GHACacheDir:         in.GHACacheDir,
+		GHAOffline:          in.GHAOffline,
+		MissingHours:        in.MissingHours,
+		SkipHeal:            in.SkipHeal,
+		ParseWorkers:        in.ParseWorkers,
+		Bulk:                in.Bulk,
+		ProjectsParallel:    in.ProjectsParallel,
+		ProjectTimeout:      in.ProjectTimeout,
+		SyncRunID:           in.SyncRunID,
+		SyncYaml:            in.SyncYaml,
+		SyncFrom:            in.SyncFrom,
+		SyncResume:          in.SyncResume,
+		Incremental:         in.Incremental,
+		DirtyMaxID:          in.DirtyMaxID,
+		PromListen:          in.PromListen,
+		PromTextfileDir:     in.PromTextfileDir,
+		SeriesSinks:         in.SeriesSinks,
+		SeriesFile:          in.SeriesFile,
+		SeriesDiff:          in.SeriesDiff,
+		DiffJSON:            in.DiffJSON,
+		DiffTolerance:       in.DiffTolerance,
+		DiffFile:            in.DiffFile,
+		DiffSeries:          in.DiffSeries,
+		LintExplain:         in.LintExplain,
 	}
 	return &out
 }
@@ -136,6 +172,13 @@ func dynamicSetFields(t *testing.T, ctx *lib.Ctx, fields map[string]interface{})
 				return ctx
 			}
 			field.SetInt(int64(interfaceValue))
+		case float64:
+			// Check if types match
+			if fieldKind != reflect.Float64 {
+				t.Errorf("trying to set value %v, type %T for field \"%s\", type %v", interfaceValue, interfaceValue, fieldName, fieldKind)
+				return ctx
+			}
+			field.SetFloat(interfaceValue)
 		case bool:
 			// Check if types match
 			if fieldKind != reflect.Bool {
@@ -158,6 +201,14 @@ func dynamicSetFields(t *testing.T, ctx *lib.Ctx, fields map[string]interface{})
 				return ctx
 			}
 			field.Set(reflect.ValueOf(fieldValue))
+		case time.Duration:
+			// Check if types match
+			fieldType := field.Type()
+			if fieldType != reflect.TypeOf(time.Second) {
+				t.Errorf("trying to set value %v, type %T for field \"%s\", type %v", interfaceValue, interfaceValue, fieldName, fieldKind)
+				return ctx
+			}
+			field.Set(reflect.ValueOf(fieldValue))
 		case []int:
 			// Check if types match
 			fieldType := field.Type()
@@ -243,6 +294,11 @@ func TestInit(t *testing.T) {
 		SkipIDB:             false,
 		SkipPDB:             false,
 		SkipGHAPI:           false,
+		GHAPIBackfill:       false,
+		GHAPIBackfillChunk:  1000,
+		GHAPIReviews:        false,
+		GHAPIGraphQL:        false,
+		GitShell:            false,
 		SkipArtificailClean: false,
 		SkipGetRepos:        false,
 		ResetIDB:            false,
@@ -259,7 +315,11 @@ func TestInit(t *testing.T) {
 		TagsYaml:            "metrics/idb_tags.yaml",
 		IVarsYaml:           "metrics/idb_vars.yaml",
 		PVarsYaml:           "metrics/pdb_vars.yaml",
+		AnnotationsYaml:     "metrics/annotations.yaml",
+		QuickRangesYaml:     "metrics/quick_ranges.yaml",
 		GitHubOAuth:         "/etc/github/oauth",
+		GitHubCache:         "",
+		GitHubCacheSize:     20000,
 		ClearDBPeriod:       "1 week",
 		Trials:              []int{10, 30, 60, 120, 300, 600},
 		LogTime:             true,
@@ -279,11 +339,13 @@ func TestInit(t *testing.T) {
 		ExecFatal:           true,
 		ExecQuiet:           false,
 		ExecOutput:          false,
+		ExecTimeout:         0,
 		ProcessRepos:        false,
 		ProcessCommits:      false,
 		ExternalInfo:        false,
 		ProjectsCommits:     "",
 		ProjectsYaml:        "projects.yaml",
+		CompaniesYaml:       "companies.yaml",
 		ProjectsOverride:    map[string]bool{},
 		ExcludeRepos:        map[string]bool{},
 		InputDBs:            []string{},
@@ -298,6 +360,31 @@ func TestInit(t *testing.T) {
 		ActorsAllow:         nil,
 		ActorsForbid:        nil,
 		OnlyMetrics:         map[string]bool{},
+		GHAURL:              "http://data.gharchive.org/{{dt}}.json.gz",
+		GHACacheDir:         "",
+		GHAOffline:          false,
+		MissingHours:        false,
+		SkipHeal:            false,
+		ParseWorkers:        1,
+		Bulk:                false,
+		ProjectsParallel:    1,
+		ProjectTimeout:      0,
+		SyncRunID:           0,
+		SyncYaml:            "sync.yaml",
+		SyncFrom:            "",
+		SyncResume:          false,
+		Incremental:         false,
+		DirtyMaxID:          0,
+		PromListen:          "",
+		PromTextfileDir:     "",
+		SeriesSinks:         []string{"influx"},
+		SeriesFile:          "series_{{project}}.lp",
+		SeriesDiff:          false,
+		DiffJSON:            false,
+		DiffTolerance:       0,
+		DiffFile:            "",
+		DiffSeries:          "",
+		LintExplain:         false,
 	}
 
 	var nilRegexp *regexp.Regexp
@@ -693,21 +780,25 @@ func TestInit(t *testing.T) {
 		{
 			"Setting non standard YAML files",
 			map[string]string{
-				"GHA2DB_METRICS_YAML": "met.YAML",
-				"GHA2DB_GAPS_YAML":    "/gapz.yml",
-				"GHA2DB_TAGS_YAML":    "/t/g/s.yml",
-				"GHA2DB_IVARS_YAML":   "/vari.yml",
-				"GHA2DB_PVARS_YAML":   "/varp.yml",
+				"GHA2DB_METRICS_YAML":      "met.YAML",
+				"GHA2DB_GAPS_YAML":         "/gapz.yml",
+				"GHA2DB_TAGS_YAML":         "/t/g/s.yml",
+				"GHA2DB_IVARS_YAML":        "/vari.yml",
+				"GHA2DB_PVARS_YAML":        "/varp.yml",
+				"GHA2DB_ANNOTATIONS_YAML":  "/anno.yml",
+				"GHA2DB_QUICK_RANGES_YAML": "/qr.yml",
 			},
 			dynamicSetFields(
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"MetricsYaml": "met.YAML",
-					"GapsYaml":    "/gapz.yml",
-					"TagsYaml":    "/t/g/s.yml",
-					"IVarsYaml":   "/vari.yml",
-					"PVarsYaml":   "/varp.yml",
+					"MetricsYaml":     "met.YAML",
+					"GapsYaml":        "/gapz.yml",
+					"TagsYaml":        "/t/g/s.yml",
+					"IVarsYaml":       "/vari.yml",
+					"PVarsYaml":       "/varp.yml",
+					"AnnotationsYaml": "/anno.yml",
+					"QuickRangesYaml": "/qr.yml",
 				},
 			),
 		},
@@ -827,12 +918,14 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"GapsYaml":    "metrics/prometheus/gaps.yaml",
-					"TagsYaml":    "metrics/prometheus/idb_tags.yaml",
-					"IVarsYaml":   "metrics/prometheus/idb_vars.yaml",
-					"PVarsYaml":   "metrics/prometheus/pdb_vars.yaml",
+					"Project":         "prometheus",
+					"MetricsYaml":     "metrics/prometheus/metrics.yaml",
+					"GapsYaml":        "metrics/prometheus/gaps.yaml",
+					"TagsYaml":        "metrics/prometheus/idb_tags.yaml",
+					"IVarsYaml":       "metrics/prometheus/idb_vars.yaml",
+					"PVarsYaml":       "metrics/prometheus/pdb_vars.yaml",
+					"AnnotationsYaml": "metrics/prometheus/annotations.yaml",
+					"QuickRangesYaml": "metrics/prometheus/quick_ranges.yaml",
 				},
 			),
 		},
@@ -846,12 +939,14 @@ func TestInit(t *testing.T) {
 				t,
 				copyContext(&defaultContext),
 				map[string]interface{}{
-					"Project":     "prometheus",
-					"MetricsYaml": "metrics/prometheus/metrics.yaml",
-					"GapsYaml":    "/gapz.yml",
-					"TagsYaml":    "metrics/prometheus/idb_tags.yaml",
-					"IVarsYaml":   "metrics/prometheus/idb_vars.yaml",
-					"PVarsYaml":   "metrics/prometheus/pdb_vars.yaml",
+					"Project":         "prometheus",
+					"MetricsYaml":     "metrics/prometheus/metrics.yaml",
+					"GapsYaml":        "/gapz.yml",
+					"TagsYaml":        "metrics/prometheus/idb_tags.yaml",
+					"IVarsYaml":       "metrics/prometheus/idb_vars.yaml",
+					"PVarsYaml":       "metrics/prometheus/pdb_vars.yaml",
+					"AnnotationsYaml": "metrics/prometheus/annotations.yaml",
+					"QuickRangesYaml": "metrics/prometheus/quick_ranges.yaml",
 				},
 			),
 		},
@@ -881,6 +976,19 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting companies.yaml",
+			map[string]string{
+				"GHA2DB_COMPANIES_YAML": "/comp.yml",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"CompaniesYaml": "/comp.yml",
+				},
+			),
+		},
 		{
 			"Setting repos dir without ending '/'",
 			map[string]string{
@@ -1193,6 +1301,247 @@ func TestInit(t *testing.T) {
 				},
 			),
 		},
+		{
+			"Setting GHA archives source, cache and offline mode",
+			map[string]string{
+				"GHA2DB_GHA_URL":       "/data/gha",
+				"GHA2DB_GHA_CACHE_DIR": "/var/cache/gha",
+				"GHA2DB_GHA_OFFLINE":   "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GHAURL":      "/data/gha",
+					"GHACacheDir": "/var/cache/gha/",
+					"GHAOffline":  true,
+				},
+			),
+		},
+		{
+			"Setting parse workers",
+			map[string]string{"GHA2DB_PARSE_WORKERS": "4"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ParseWorkers": 4},
+			),
+		},
+		{
+			"Setting negative parse workers",
+			map[string]string{"GHA2DB_PARSE_WORKERS": "-2"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"ParseWorkers": 1},
+			),
+		},
+		{
+			"Setting missing hours and skip heal modes",
+			map[string]string{
+				"GHA2DB_MISSING_HOURS": "1",
+				"GHA2DB_SKIP_HEAL":     "y",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"MissingHours": true, "SkipHeal": true},
+			),
+		},
+		{
+			"Setting bulk inserts",
+			map[string]string{"GHA2DB_BULK": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"Bulk": true},
+			),
+		},
+		{
+			"Setting parallel projects sync and project timeout",
+			map[string]string{
+				"GHA2DB_PROJECTS_PARALLEL": "4",
+				"GHA2DB_PROJECT_TIMEOUT":   "1h30m",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"ProjectsParallel": 4,
+					"ProjectTimeout":   90 * time.Minute,
+				},
+			),
+		},
+		{
+			"Setting parent sync run",
+			map[string]string{"GHA2DB_SYNC_RUN_ID": "123"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"SyncRunID": 123},
+			),
+		},
+		{
+			"Setting sync phases",
+			map[string]string{
+				"GHA2DB_SYNC_YAML":   "my_sync.yaml",
+				"GHA2DB_SYNC_FROM":   "db2influx",
+				"GHA2DB_SYNC_RESUME": "1",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"SyncYaml":   "my_sync.yaml",
+					"SyncFrom":   "db2influx",
+					"SyncResume": true,
+				},
+			),
+		},
+		{
+			"Setting incremental sync",
+			map[string]string{
+				"GHA2DB_INCREMENTAL":  "1",
+				"GHA2DB_DIRTY_MAX_ID": "42",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"Incremental": true,
+					"DirtyMaxID":  42,
+				},
+			),
+		},
+		{
+			"Setting operational metrics",
+			map[string]string{
+				"GHA2DB_PROM_LISTEN":       ":9101",
+				"GHA2DB_PROM_TEXTFILE_DIR": "/var/lib/node_exporter",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"PromListen":      ":9101",
+					"PromTextfileDir": "/var/lib/node_exporter",
+				},
+			),
+		},
+		{
+			"Setting time series sinks",
+			map[string]string{
+				"GHA2DB_SERIES_SINKS": "influx,postgres,file",
+				"GHA2DB_SERIES_FILE":  "/tmp/series.lp",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"SeriesSinks": []string{"influx", "postgres", "file"},
+					"SeriesFile":  "/tmp/series.lp",
+				},
+			),
+		},
+		{
+			"Setting series diff mode",
+			map[string]string{
+				"GHA2DB_DIFF":           "1",
+				"GHA2DB_DIFF_JSON":      "1",
+				"GHA2DB_DIFF_TOLERANCE": "0.01",
+				"GHA2DB_DIFF_FILE":      "/tmp/diff.json",
+				"GHA2DB_DIFF_SERIES":    "/^reviewers_/",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"SeriesDiff":    true,
+					"DiffJSON":      true,
+					"DiffTolerance": 0.01,
+					"DiffFile":      "/tmp/diff.json",
+					"DiffSeries":    "/^reviewers_/",
+				},
+			),
+		},
+		{
+			"Setting metrics lint explain mode",
+			map[string]string{"GHA2DB_LINT_EXPLAIN": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"LintExplain": true},
+			),
+		},
+		{
+			"Setting ghapi2db backfill mode",
+			map[string]string{
+				"GHA2DB_GHAPI_BACKFILL":       "1",
+				"GHA2DB_GHAPI_BACKFILL_CHUNK": "250",
+			},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{
+					"GHAPIBackfill":      true,
+					"GHAPIBackfillChunk": 250,
+				},
+			),
+		},
+		{
+			"Setting ghapi2db reviews sync",
+			map[string]string{"GHA2DB_GHAPI_REVIEWS": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GHAPIReviews": true},
+			),
+		},
+		{
+			"Setting ghapi2db GraphQL API",
+			map[string]string{"GHA2DB_GHAPI_GRAPHQL": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GHAPIGraphQL": true},
+			),
+		},
+		{
+			"Setting git shell scripts mode",
+			map[string]string{"GHA2DB_GIT_SHELL": "1"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GitShell": true},
+			),
+		},
+		{
+			"Setting GitHub API cache directory",
+			map[string]string{"GHA2DB_GITHUB_CACHE": "/var/cache/ghapi"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GitHubCache": "/var/cache/ghapi/"},
+			),
+		},
+		{
+			"Setting GitHub API cache in Postgres",
+			map[string]string{"GHA2DB_GITHUB_CACHE": "postgres"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GitHubCache": "postgres"},
+			),
+		},
+		{
+			"Setting GitHub API cache directory size",
+			map[string]string{"GHA2DB_GITHUB_CACHE_SIZE": "500"},
+			dynamicSetFields(
+				t,
+				copyContext(&defaultContext),
+				map[string]interface{}{"GitHubCacheSize": 500},
+			),
+		},
 	}
 
 	// Context Init() is verbose when called with CtxDebug
@@ -0,0 +1,14 @@
+#!/bin/bash
+# Report slowest metrics and recent sync failures from `gha_sync_runs` and `gha_sync_phases` tables (in `devstats` database)
+# Usage: ./devel/sync_report.sh [project] [days], default all projects and 7 days
+if [ -z "${PG_PASS}" ]
+then
+  echo "You need to set PG_PASS environment variable to run this script"
+  exit 1
+fi
+proj="${1}"
+days="${2:-7}"
+echo "Slowest metrics in the last ${days} days:"
+GHA2DB_SKIPTIME=1 GHA2DB_SKIPLOG=1 PG_DB=devstats ./runq util_sql/sync_slowest_metrics.sql {{project}} "${proj}" {{days}} "${days}" || exit 2
+echo "Recent failures in the last ${days} days:"
+GHA2DB_SKIPTIME=1 GHA2DB_SKIPLOG=1 PG_DB=devstats ./runq util_sql/sync_recent_failures.sql {{project}} "${proj}" {{days}} "${days}" || exit 3
@@ -0,0 +1,171 @@
+package devstats
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeRange - [From, To] range of event dates
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// DirtyRange - collects min and max `created_at` of events written by `gha2db` or `ghapi2db`
+// Safe for concurrent use, zero value is ready to use
+type DirtyRange struct {
+	mtx sync.Mutex
+	rng *TimeRange
+}
+
+// Add - extends range to include given event date
+func (r *DirtyRange) Add(dt time.Time) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.rng == nil {
+		r.rng = &TimeRange{From: dt, To: dt}
+		return
+	}
+	if dt.Before(r.rng.From) {
+		r.rng.From = dt
+	}
+	if dt.After(r.rng.To) {
+		r.rng.To = dt
+	}
+}
+
+// Range - returns collected range or nil if no events were added
+func (r *DirtyRange) Range() *TimeRange {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.rng == nil {
+		return nil
+	}
+	rng := *r.rng
+	return &rng
+}
+
+// Save - records collected range in `gha_dirty_ranges` table (if any events were added) and resets it
+func (r *DirtyRange) Save(con *sql.DB, ctx *Ctx, source string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.rng == nil {
+		return
+	}
+	SaveDirtyRange(con, ctx, source, r.rng.From, r.rng.To)
+	r.rng = nil
+}
+
+// DirtyRangesEnabled - dirty ranges are only used in incremental mode (GHA2DB_INCREMENTAL) and when `gha_dirty_ranges` table exists
+// Without this table `gha2db_sync` falls back to recomputing periods using time of day schedule
+func DirtyRangesEnabled(con *sql.DB, ctx *Ctx) bool {
+	if !ctx.Incremental {
+		return false
+	}
+	return TableExists(con, ctx, "gha_dirty_ranges", "dirty_ranges_table.sql", "incremental mode is disabled")
+}
+
+// SaveDirtyRange - records that events in [from, to] range were added or changed (only when dirty ranges are enabled)
+// `gha2db_sync` in incremental mode only recomputes periods touched by such ranges
+func SaveDirtyRange(con *sql.DB, ctx *Ctx, source string, from, to time.Time) {
+	if !DirtyRangesEnabled(con, ctx) {
+		return
+	}
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_dirty_ranges(source, dt_from, dt_to, recorded_at) "+NValues(4),
+		source,
+		from,
+		to,
+		time.Now(),
+	)
+}
+
+// MaxDirtyRangeID - returns ID of the most recent dirty range, 0 when there are none (or dirty ranges are not enabled)
+func MaxDirtyRangeID(con *sql.DB, ctx *Ctx) int {
+	var id *int
+	if !DirtyRangesEnabled(con, ctx) {
+		return 0
+	}
+	FatalOnError(QueryRowSQL(con, ctx, "select max(id) from gha_dirty_ranges").Scan(&id))
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// GetDirtyRanges - returns all dirty ranges with ID not greater than maxID
+func GetDirtyRanges(con *sql.DB, ctx *Ctx, maxID int) (ranges []TimeRange) {
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select dt_from, dt_to from gha_dirty_ranges where id <= $1 order by dt_from",
+		maxID,
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var rng TimeRange
+	for rows.Next() {
+		FatalOnError(rows.Scan(&rng.From, &rng.To))
+		ranges = append(ranges, rng)
+	}
+	FatalOnError(rows.Err())
+	return
+}
+
+// ClearDirtyRanges - deletes dirty ranges with ID not greater than maxID (when all their periods were recomputed)
+func ClearDirtyRanges(con *sql.DB, ctx *Ctx, maxID int) {
+	ExecSQLWithErr(con, ctx, "delete from gha_dirty_ranges where id <= $1", maxID)
+}
+
+// IntervalStarts - returns start dates of all intervals in [from, to), from must be an interval start
+func IntervalStarts(from, to time.Time, nextIntervalStart func(time.Time) time.Time) (dts []time.Time) {
+	for dt := from; dt.Before(to); dt = nextIntervalStart(dt) {
+		dts = append(dts, dt)
+	}
+	return
+}
+
+// DirtyIntervals - returns start dates of all intervals before `limit` that are touched by given ranges
+// For n > 1 (like "d7" - 7 days moving) interval starting at dt covers n intervals ending at dt's next interval start
+// So a range also touches n - 1 intervals after the one it starts in
+// Dates in `dts` are included in the result, result is sorted and has no duplicates
+func DirtyIntervals(dts []time.Time, ranges []TimeRange, n int, intervalStart, nextIntervalStart, prevIntervalStart func(time.Time) time.Time, limit time.Time) []time.Time {
+	set := make(map[time.Time]struct{})
+	for _, dt := range dts {
+		set[dt] = struct{}{}
+	}
+	for _, rng := range ranges {
+		to := intervalStart(rng.To)
+		if n > 1 {
+			to = AddNIntervals(to, n-1, nextIntervalStart, prevIntervalStart)
+		}
+		for dt := intervalStart(rng.From); !dt.After(to) && dt.Before(limit); dt = nextIntervalStart(dt) {
+			set[dt] = struct{}{}
+		}
+	}
+	result := []time.Time{}
+	for dt := range set {
+		result = append(result, dt)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+// AddDirtyIntervals - when GHA2DB_DIRTY_MAX_ID is set, adds intervals touched by `gha_dirty_ranges` (up to this ID) to dts
+// Used by `db2influx` and `z2influx` called by `gha2db_sync` in incremental mode
+func AddDirtyIntervals(ctx *Ctx, dts []time.Time, n int, intervalStart, nextIntervalStart, prevIntervalStart func(time.Time) time.Time, limit time.Time) []time.Time {
+	if ctx.DirtyMaxID <= 0 {
+		return dts
+	}
+	con := PgConn(ctx)
+	defer func() { FatalOnError(con.Close()) }()
+	ranges := GetDirtyRanges(con, ctx, ctx.DirtyMaxID)
+	result := DirtyIntervals(dts, ranges, n, intervalStart, nextIntervalStart, prevIntervalStart, limit)
+	if ctx.Debug > 0 {
+		Printf("%d dirty ranges, %d intervals to compute (%d without dirty ranges)\n", len(ranges), len(result), len(dts))
+	}
+	return result
+}
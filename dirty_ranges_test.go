@@ -0,0 +1,114 @@
+package devstats
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestDirtyRange(t *testing.T) {
+	ft := testlib.YMDHMS
+	var dirty lib.DirtyRange
+	if dirty.Range() != nil {
+		t.Errorf("expected empty range, got %+v", dirty.Range())
+	}
+	dates := []time.Time{ft(2018, 1, 1, 12), ft(2018, 1, 1, 10, 30), ft(2018, 1, 1, 11), ft(2018, 1, 1, 12, 59, 59)}
+	var wg sync.WaitGroup
+	for _, dt := range dates {
+		wg.Add(1)
+		go func(dt time.Time) {
+			defer wg.Done()
+			dirty.Add(dt)
+		}(dt)
+	}
+	wg.Wait()
+	expected := lib.TimeRange{From: ft(2018, 1, 1, 10, 30), To: ft(2018, 1, 1, 12, 59, 59)}
+	got := dirty.Range()
+	if got == nil || *got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestDirtyIntervals(t *testing.T) {
+	ft := testlib.YMDHMS
+	// Test cases
+	var testCases = []struct {
+		period   string
+		dts      []time.Time
+		ranges   []lib.TimeRange
+		limit    time.Time
+		expected []time.Time
+	}{
+		{
+			period:   "d",
+			dts:      []time.Time{ft(2018, 2, 10)},
+			ranges:   nil,
+			limit:    ft(2018, 2, 11),
+			expected: []time.Time{ft(2018, 2, 10)},
+		},
+		{
+			period: "d",
+			dts:    []time.Time{ft(2018, 2, 10)},
+			ranges: []lib.TimeRange{
+				{From: ft(2018, 2, 1, 23, 10), To: ft(2018, 2, 2, 1)},
+				{From: ft(2018, 2, 10, 3), To: ft(2018, 2, 10, 4)},
+				{From: ft(2018, 1, 15, 3), To: ft(2018, 1, 15, 3)},
+			},
+			limit:    ft(2018, 2, 11),
+			expected: []time.Time{ft(2018, 1, 15), ft(2018, 2, 1), ft(2018, 2, 2), ft(2018, 2, 10)},
+		},
+		{
+			period:   "d7",
+			dts:      []time.Time{ft(2018, 2, 10)},
+			ranges:   []lib.TimeRange{{From: ft(2018, 2, 1, 12), To: ft(2018, 2, 1, 13)}},
+			limit:    ft(2018, 2, 11),
+			expected: []time.Time{ft(2018, 2, 1), ft(2018, 2, 2), ft(2018, 2, 3), ft(2018, 2, 4), ft(2018, 2, 5), ft(2018, 2, 6), ft(2018, 2, 7), ft(2018, 2, 10)},
+		},
+		{
+			period:   "d7",
+			dts:      []time.Time{ft(2018, 2, 10)},
+			ranges:   []lib.TimeRange{{From: ft(2018, 2, 8, 12), To: ft(2018, 2, 9, 13)}},
+			limit:    ft(2018, 2, 11),
+			expected: []time.Time{ft(2018, 2, 8), ft(2018, 2, 9), ft(2018, 2, 10)},
+		},
+		{
+			period:   "m",
+			dts:      []time.Time{ft(2018, 2)},
+			ranges:   []lib.TimeRange{{From: ft(2017, 11, 30, 23), To: ft(2017, 12, 1, 1)}},
+			limit:    ft(2018, 3),
+			expected: []time.Time{ft(2017, 11), ft(2017, 12), ft(2018, 2)},
+		},
+		{
+			period:   "h",
+			dts:      []time.Time{},
+			ranges:   []lib.TimeRange{{From: ft(2018, 2, 1, 10, 30), To: ft(2018, 2, 1, 10, 40)}},
+			limit:    ft(2018, 2, 1, 10),
+			expected: []time.Time{},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		_, n, intervalStart, nextIntervalStart, prevIntervalStart := lib.GetIntervalFunctions(test.period, false)
+		got := lib.DirtyIntervals(test.dts, test.ranges, n, intervalStart, nextIntervalStart, prevIntervalStart, test.limit)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestIntervalStarts(t *testing.T) {
+	ft := testlib.YMDHMS
+	got := lib.IntervalStarts(ft(2018, 1, 1, 22), ft(2018, 1, 2, 1), lib.NextHourStart)
+	expected := []time.Time{ft(2018, 1, 1, 22), ft(2018, 1, 1, 23), ft(2018, 1, 2)}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	got = lib.IntervalStarts(ft(2018, 1, 2), ft(2018, 1, 2), lib.NextHourStart)
+	if len(got) != 0 {
+		t.Errorf("expected no intervals, got %v", got)
+	}
+}
@@ -0,0 +1,171 @@
+package devstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EventFilterRule - single rule of project's event filter (`event_filter` list in `projects.yaml`)
+// All conditions given in the rule must match for the rule to match, conditions not given are ignored
+// Action is "include" or "exclude"
+// Types - list of GHA event types, for example [WatchEvent, ForkEvent]
+// Repo, Actor - regexps matched against full repo name (org/repo) and actor login
+// From, To - event's created_at window [From, To), for example when repo was moved between orgs
+// Field, Value - dotted path in the event JSON (like "payload.pull_request.draft") and its expected value
+type EventFilterRule struct {
+	Action string     `yaml:"action"`
+	Types  []string   `yaml:"types"`
+	Repo   string     `yaml:"repo"`
+	Actor  string     `yaml:"actor"`
+	From   *time.Time `yaml:"from"`
+	To     *time.Time `yaml:"to"`
+	Field  string     `yaml:"field"`
+	Value  string     `yaml:"value"`
+}
+
+// EventFilter - compiled list of event filter rules, first matching rule decides
+type EventFilter struct {
+	rules     []eventFilterRule
+	needsJSON bool
+}
+
+// eventFilterRule - compiled EventFilterRule
+type eventFilterRule struct {
+	include bool
+	types   map[string]struct{}
+	repo    *regexp.Regexp
+	actor   *regexp.Regexp
+	from    *time.Time
+	to      *time.Time
+	field   []string
+	value   string
+}
+
+// FilterEvent - event data that filter rules are evaluated on
+// JSON is the raw event JSON, it is only parsed when there are rules using payload fields
+type FilterEvent struct {
+	Type      string
+	Repo      string
+	Actor     string
+	CreatedAt time.Time
+	JSON      []byte
+	parsed    map[string]interface{}
+}
+
+// NewEventFilter - compiles event filter rules, returns nil filter if there are no rules
+func NewEventFilter(rules []EventFilterRule) (*EventFilter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	filter := &EventFilter{}
+	for i, rule := range rules {
+		var r eventFilterRule
+		switch strings.ToLower(strings.TrimSpace(rule.Action)) {
+		case "include":
+			r.include = true
+		case "exclude":
+			r.include = false
+		default:
+			return nil, fmt.Errorf("event filter rule #%d: unknown action '%s', allowed: include, exclude", i+1, rule.Action)
+		}
+		if len(rule.Types) > 0 {
+			r.types = make(map[string]struct{})
+			for _, typ := range rule.Types {
+				r.types[strings.TrimSpace(typ)] = struct{}{}
+			}
+		}
+		var err error
+		if rule.Repo != "" {
+			r.repo, err = regexp.Compile(rule.Repo)
+			if err != nil {
+				return nil, fmt.Errorf("event filter rule #%d: repo: %v", i+1, err)
+			}
+		}
+		if rule.Actor != "" {
+			r.actor, err = regexp.Compile(rule.Actor)
+			if err != nil {
+				return nil, fmt.Errorf("event filter rule #%d: actor: %v", i+1, err)
+			}
+		}
+		r.from = rule.From
+		r.to = rule.To
+		if rule.Field != "" {
+			r.field = strings.Split(rule.Field, ".")
+			r.value = rule.Value
+			filter.needsJSON = true
+		}
+		filter.rules = append(filter.rules, r)
+	}
+	return filter, nil
+}
+
+// Hit - returns true if event should be processed
+// First matching rule decides, when no rule matches `def` is returned (this is RepoHit and ActorHit result)
+// Nil filter always returns `def`
+func (f *EventFilter) Hit(ev *FilterEvent, def bool) bool {
+	if f == nil {
+		return def
+	}
+	if f.needsJSON && ev.parsed == nil && len(ev.JSON) > 0 {
+		// Keep numbers as they are in JSON, so big IDs can be compared with rule values
+		decoder := json.NewDecoder(bytes.NewReader(ev.JSON))
+		decoder.UseNumber()
+		if decoder.Decode(&ev.parsed) != nil {
+			ev.parsed = nil
+		}
+	}
+	for _, rule := range f.rules {
+		if rule.matches(ev) {
+			return rule.include
+		}
+	}
+	return def
+}
+
+// matches - do all conditions of the rule match given event?
+func (r *eventFilterRule) matches(ev *FilterEvent) bool {
+	if r.types != nil {
+		if _, ok := r.types[ev.Type]; !ok {
+			return false
+		}
+	}
+	if r.repo != nil && !r.repo.MatchString(ev.Repo) {
+		return false
+	}
+	if r.actor != nil && !r.actor.MatchString(ev.Actor) {
+		return false
+	}
+	if r.from != nil && ev.CreatedAt.Before(*r.from) {
+		return false
+	}
+	if r.to != nil && !ev.CreatedAt.Before(*r.to) {
+		return false
+	}
+	if r.field != nil {
+		value, ok := jsonField(ev.parsed, r.field)
+		if !ok || fmt.Sprintf("%v", value) != r.value {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonField - returns value at a given path in parsed JSON object, and false if there is no such path
+func jsonField(obj map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = obj
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,172 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestEventFilter(t *testing.T) {
+	// Example project's filter, using all rule types
+	moved := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	filter, err := lib.NewEventFilter(
+		[]lib.EventFilterRule{
+			{Action: "exclude", Types: []string{"WatchEvent", "ForkEvent"}},
+			{Action: "exclude", Actor: "^.*-bot$"},
+			{Action: "include", Repo: "^other/moved$", To: &moved},
+			{Action: "exclude", Repo: "^org/moved$", To: &moved},
+			{Action: "exclude", Field: "payload.pull_request.draft", Value: "true"},
+			{Action: "include", Repo: "^contrib/", Field: "payload.number", Value: "1234567890123"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before := time.Date(2017, 5, 31, 23, 0, 0, 0, time.UTC)
+	after := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test cases
+	var testCases = []struct {
+		ev       lib.FilterEvent
+		def      bool
+		expected bool
+	}{
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/repo", Actor: "user"}, def: true, expected: true},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/repo", Actor: "user"}, def: false, expected: false},
+		{ev: lib.FilterEvent{Type: "WatchEvent", Repo: "org/repo", Actor: "user"}, def: true, expected: false},
+		{ev: lib.FilterEvent{Type: "ForkEvent", Repo: "org/repo", Actor: "user"}, def: true, expected: false},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/repo", Actor: "k8s-ci-bot"}, def: true, expected: false},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/repo", Actor: "bot-user"}, def: true, expected: true},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "other/moved", CreatedAt: before}, def: false, expected: true},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "other/moved", CreatedAt: after}, def: false, expected: false},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/moved", CreatedAt: before}, def: true, expected: false},
+		{ev: lib.FilterEvent{Type: "PushEvent", Repo: "org/moved", CreatedAt: after}, def: true, expected: true},
+		{
+			ev: lib.FilterEvent{
+				Type: "PullRequestEvent",
+				Repo: "org/repo",
+				JSON: []byte(`{"payload":{"pull_request":{"draft":true}}}`),
+			},
+			def:      true,
+			expected: false,
+		},
+		{
+			ev: lib.FilterEvent{
+				Type: "PullRequestEvent",
+				Repo: "org/repo",
+				JSON: []byte(`{"payload":{"pull_request":{"draft":false}}}`),
+			},
+			def:      true,
+			expected: true,
+		},
+		{
+			ev: lib.FilterEvent{
+				Type: "PullRequestEvent",
+				Repo: "org/repo",
+				JSON: []byte(`{"payload":{"pull_request":"not an object"}}`),
+			},
+			def:      true,
+			expected: true,
+		},
+		{
+			ev: lib.FilterEvent{
+				Type: "IssuesEvent",
+				Repo: "contrib/repo",
+				JSON: []byte(`{"payload":{"number":1234567890123}}`),
+			},
+			def:      false,
+			expected: true,
+		},
+		{
+			ev: lib.FilterEvent{
+				Type: "IssuesEvent",
+				Repo: "contrib/repo",
+				JSON: []byte(`{"payload":{"number":1234567890124}}`),
+			},
+			def:      false,
+			expected: false,
+		},
+		{
+			ev:       lib.FilterEvent{Type: "IssuesEvent", Repo: "contrib/repo", JSON: []byte(`invalid`)},
+			def:      false,
+			expected: false,
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ev := test.ev
+		got := filter.Hit(&ev, test.def)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v, test case: %+v", index+1, test.expected, got, test)
+		}
+	}
+}
+
+func TestNilEventFilter(t *testing.T) {
+	filter, err := lib.NewEventFilter(nil)
+	if err != nil || filter != nil {
+		t.Errorf("expected nil filter without error, got %v, %v", filter, err)
+	}
+	ev := lib.FilterEvent{Type: "WatchEvent"}
+	if !filter.Hit(&ev, true) || filter.Hit(&ev, false) {
+		t.Errorf("nil filter should always return default")
+	}
+}
+
+func TestEventFilterErrors(t *testing.T) {
+	var testCases = [][]lib.EventFilterRule{
+		{{Action: "skip"}},
+		{{Action: "exclude", Repo: "("}},
+		{{Action: "include"}, {Action: "include", Actor: "[a-"}},
+	}
+	// Execute test cases
+	for index, rules := range testCases {
+		_, err := lib.NewEventFilter(rules)
+		if err == nil {
+			t.Errorf("test number %d, expected error, got nil, test case: %+v", index+1, rules)
+		}
+	}
+}
+
+func TestEventFilterYAML(t *testing.T) {
+	data := []byte(`
+projects:
+  proj:
+    command_line:
+      - org
+    event_filter:
+      - action: exclude
+        types: [WatchEvent]
+      - action: include
+        repo: '^old/repo$'
+        from: 2016-01-01T00:00:00Z
+        to: 2017-01-01T00:00:00Z
+      - action: exclude
+        field: payload.pull_request.draft
+        value: 'true'
+`)
+	var projects lib.AllProjects
+	err := yaml.Unmarshal(data, &projects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := projects.Projects["proj"].EventFilter
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %+v", rules)
+	}
+	from := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	if rules[0].Types[0] != "WatchEvent" || rules[1].From == nil || !rules[1].From.Equal(from) || rules[2].Value != "true" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+	filter, err := lib.NewEventFilter(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ev := lib.FilterEvent{Type: "PushEvent", Repo: "old/repo", CreatedAt: from.Add(time.Hour)}
+	if !filter.Hit(&ev, false) {
+		t.Errorf("expected moved repo event to be included")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -46,6 +48,11 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 	}
 	cmd := exec.Command(command, arguments...)
 
+	// With timeout, run command in its own process group, so it can be killed with all its children
+	if ctx.ExecTimeout > 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
 	// Environment setup (if any)
 	if len(env) > 0 {
 		newEnv := os.Environ()
@@ -75,6 +82,30 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 		cmd.Stdout = &stdOut
 	}
 
+	// Kill command's process group when it runs longer than timeout
+	// Timer is started right after the command starts, before reading its STDOUT pipe
+	var (
+		timedOut int32
+		timer    *time.Timer
+	)
+	startTimer := func() {
+		if ctx.ExecTimeout <= 0 {
+			return
+		}
+		timer = time.AfterFunc(
+			ctx.ExecTimeout,
+			func() {
+				atomic.StoreInt32(&timedOut, 1)
+				_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			},
+		)
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
 	// Pipe command's STDOUT during execution (if CmdDebug > 1)
 	// Or just starts command when no STDOUT debug
 	if ctx.CmdDebug > 1 {
@@ -96,6 +127,7 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 				return "", e
 			}
 		}
+		startTimer()
 		buffer := make([]byte, pipeSize, pipeSize)
 		nBytes, e := stdOutPipe.Read(buffer)
 		for e == nil && nBytes > 0 {
@@ -104,6 +136,9 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 			nBytes, e = stdOutPipe.Read(buffer)
 		}
 		if e != io.EOF {
+			if atomic.LoadInt32(&timedOut) != 0 {
+				e = fmt.Errorf("timeout after %v: %v", ctx.ExecTimeout, e)
+			}
 			logCommand(ctx, cmdAndArgs, env)
 			if ctx.ExecFatal {
 				FatalOnError(e)
@@ -121,9 +156,14 @@ func ExecCommand(ctx *Ctx, cmdAndArgs []string, env map[string]string) (string,
 				return "", e
 			}
 		}
+		startTimer()
 	}
+
 	// Wait for command to finish
 	err := cmd.Wait()
+	if err != nil && atomic.LoadInt32(&timedOut) != 0 {
+		err = fmt.Errorf("timeout after %v: %v", ctx.ExecTimeout, err)
+	}
 
 	// If error - then output STDOUT, STDERR and error info
 	if err != nil {
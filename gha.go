@@ -21,17 +21,23 @@ type AllProjects struct {
 
 // Project contain mapping from project name to its command line used to sync it
 type Project struct {
-	CommandLine      []string          `yaml:"command_line"`
-	StartDate        *time.Time        `yaml:"start_date"`
-	PDB              string            `yaml:"psql_db"`
-	IDB              string            `yaml:"influx_db"`
-	Disabled         bool              `yaml:"disabled"`
-	MainRepo         string            `yaml:"main_repo"`
-	AnnotationRegexp string            `yaml:"annotation_regexp"`
-	Order            int               `yaml:"order"`
-	JoinDate         *time.Time        `yaml:"join_date"`
-	FilesSkipPattern string            `yaml:"files_skip_pattern"`
-	Env              map[string]string `yaml:"env"`
+	CommandLine        []string          `yaml:"command_line"`
+	StartDate          *time.Time        `yaml:"start_date"`
+	PDB                string            `yaml:"psql_db"`
+	IDB                string            `yaml:"influx_db"`
+	Disabled           bool              `yaml:"disabled"`
+	MainRepo           string            `yaml:"main_repo"`
+	AnnotationRegexp   string            `yaml:"annotation_regexp"`
+	AnnotationRepos    []string          `yaml:"annotation_repos"`
+	AnnotationReleases bool              `yaml:"annotation_releases"`
+	Order              int               `yaml:"order"`
+	JoinDate           *time.Time        `yaml:"join_date"`
+	FilesSkipPattern   string            `yaml:"files_skip_pattern"`
+	Env                map[string]string `yaml:"env"`
+	EventFilter        []EventFilterRule `yaml:"event_filter"`
+	SyncTimeout        time.Duration     `yaml:"sync_timeout"`
+	SyncPhases         []SyncPhaseDef    `yaml:"sync_phases"`
+	QuickRanges        []QuickRange      `yaml:"quick_ranges"`
 }
 
 // AnyArray - holds array of interface{} - just a shortcut
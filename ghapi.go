@@ -2,12 +2,13 @@ package devstats
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
 )
 
 // I know global variables are bad, but sometimes GitHub is not returning it
@@ -15,9 +16,22 @@ import (
 var globalRL *github.RateLimits
 var globalRLMutex = &sync.Mutex{}
 
+// ghTransports - transports of GitHub clients created by GHClient
+var (
+	ghTransports      = make(map[*github.Client]*GHTransport)
+	ghTransportsMutex sync.Mutex
+)
+
 // GetRateLimits - returns all and remaining API points and duration to wait for reset
 // when core=true - returns Core limits, when core=false returns Search limits
+// When client uses multiple tokens, returns totals of all tokens and duration to wait for the nearest reset
 func GetRateLimits(gctx context.Context, gc *github.Client, core bool) (int, int, time.Duration) {
+	ghTransportsMutex.Lock()
+	transport := ghTransports[gc]
+	ghTransportsMutex.Unlock()
+	if transport != nil && transport.Tokens() > 1 {
+		return getMultiTokenRateLimits(gctx, gc, transport, core)
+	}
 	rl, _, err := gc.RateLimits(gctx)
 	if err != nil {
 		rl = globalRL
@@ -26,33 +40,89 @@ func GetRateLimits(gctx context.Context, gc *github.Client, core bool) (int, int
 		globalRL = rl
 		globalRLMutex.Unlock()
 	}
+	PromSet("devstats_github_api_points_remaining", "GitHub API points remaining.", float64(rl.Core.Remaining), "resource", "core")
+	PromSet("devstats_github_api_points_remaining", "GitHub API points remaining.", float64(rl.Search.Remaining), "resource", "search")
 	if core {
 		return rl.Core.Limit, rl.Core.Remaining, rl.Core.Reset.Time.Sub(time.Now()) + time.Duration(1)*time.Second
 	}
 	return rl.Search.Limit, rl.Search.Remaining, rl.Search.Reset.Time.Sub(time.Now()) + time.Duration(1)*time.Second
 }
 
-// GHClient - get GitHub client
-func GHClient(ctx *Ctx) (ghCtx context.Context, client *github.Client) {
-	// Get GitHub OAuth from env or from file
-	oAuth := ctx.GitHubOAuth
-	if strings.Contains(ctx.GitHubOAuth, "/") {
-		bytes, err := ReadFile(ctx, ctx.GitHubOAuth)
-		FatalOnError(err)
-		oAuth = strings.TrimSpace(string(bytes))
+// getMultiTokenRateLimits - gets API points of every token, returns totals
+func getMultiTokenRateLimits(gctx context.Context, gc *github.Client, transport *GHTransport, core bool) (int, int, time.Duration) {
+	now := time.Now()
+	searchLimit, searchRemaining := 0, 0
+	var searchReset time.Time
+	for i := 0; i < transport.Tokens(); i++ {
+		rl, _, err := gc.RateLimits(context.WithValue(gctx, ghTokenCtxKey{}, i))
+		if err != nil {
+			// Token that can't get its rate limits is not used until a next successful check
+			transport.Invalidate(i, err.Error())
+			continue
+		}
+		transport.SetRates(i, rl.Core.Limit, rl.Core.Remaining, rl.Core.Reset.Time)
+		searchLimit += rl.Search.Limit
+		searchRemaining += rl.Search.Remaining
+		if rl.Search.Reset.Time.After(now) && (searchReset.IsZero() || rl.Search.Reset.Time.Before(searchReset)) {
+			searchReset = rl.Search.Reset.Time
+		}
 	}
+	if transport.Usable() == 0 {
+		Fatalf("none of %d GitHub API tokens is usable", transport.Tokens())
+	}
+	limit, remaining, wait := transport.Rates()
+	PromSet("devstats_github_api_points_remaining", "GitHub API points remaining.", float64(remaining), "resource", "core")
+	PromSet("devstats_github_api_points_remaining", "GitHub API points remaining.", float64(searchRemaining), "resource", "search")
+	if core {
+		return limit, remaining, wait + time.Duration(1)*time.Second
+	}
+	return searchLimit, searchRemaining, searchReset.Sub(now) + time.Duration(1)*time.Second
+}
 
-	// GitHub authentication or use public access
-	ghCtx = context.Background()
-	if oAuth == "-" {
-		client = github.NewClient(nil)
-	} else {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: oAuth},
+// GHNotFound - checks if GitHub API error means that requested object doesn't exist (anymore): 404 Not Found or 410 Gone
+// This happens for issues in deleted, renamed or transferred repositories
+func GHNotFound(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return false
+	}
+	return errResp.Response.StatusCode == http.StatusNotFound || errResp.Response.StatusCode == http.StatusGone
+}
+
+// GitHubTokens - returns GitHub API tokens from GHA2DB_GITHUB_OAUTH, "-" means public access (no tokens)
+// It is a comma separated list of tokens or files (paths containing "/"), files contain tokens separated by new lines or commas
+func GitHubTokens(ctx *Ctx) (tokens []string) {
+	if ctx.GitHubOAuth == "-" {
+		return
+	}
+	for _, item := range strings.Split(ctx.GitHubOAuth, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			tokens = append(tokens, item)
+			continue
+		}
+		bytes, err := ReadFile(ctx, item)
+		FatalOnError(err)
+		tokens = append(
+			tokens,
+			strings.FieldsFunc(string(bytes), func(r rune) bool { return r == ',' || unicode.IsSpace(r) })...,
 		)
-		tc := oauth2.NewClient(ghCtx, ts)
-		client = github.NewClient(tc)
 	}
+	return
+}
 
+// GHClient - get GitHub client
+// Uses all tokens from GHA2DB_GITHUB_OAUTH (the one with the most remaining API points for each request)
+// and GHA2DB_GITHUB_CACHE for conditional requests
+func GHClient(ctx *Ctx) (ghCtx context.Context, client *github.Client) {
+	transport := NewGHTransport(GitHubTokens(ctx), NewGHCache(ctx))
+	ghCtx = context.Background()
+	client = github.NewClient(&http.Client{Transport: transport})
+	ghTransportsMutex.Lock()
+	ghTransports[client] = transport
+	ghTransportsMutex.Unlock()
 	return
 }
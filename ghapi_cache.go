@@ -0,0 +1,177 @@
+package devstats
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GHCacheEntry - cached GitHub API response, used to make conditional requests (304 responses don't cost API points)
+type GHCacheEntry struct {
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"last_modified"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// GHCache - storage of cached GitHub API responses, implementations must be safe for concurrent use
+type GHCache interface {
+	Get(key string) (*GHCacheEntry, bool)
+	Put(key string, entry *GHCacheEntry)
+}
+
+// NewGHCache - returns GitHub API responses cache configured by GHA2DB_GITHUB_CACHE
+// "postgres" - `gha_ghapi_cache` table in `devstats` database, other value is a directory, "" - no cache
+func NewGHCache(ctx *Ctx) GHCache {
+	switch ctx.GitHubCache {
+	case "":
+		return nil
+	case "postgres":
+		pctx := *ctx
+		pctx.PgDB = Devstats
+		return &ghPgCache{ctx: &pctx, con: PgConn(&pctx)}
+	default:
+		FatalOnError(os.MkdirAll(ctx.GitHubCache, 0755))
+		c := &ghDirCache{dir: ctx.GitHubCache, size: ctx.GitHubCacheSize}
+		c.count = len(c.files())
+		return c
+	}
+}
+
+// ghCacheKey - returns hash of cache key used as file name or table key
+func ghCacheKey(key string) string {
+	hash := sha1.Sum([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// ghDirCache - cache storing each response as a JSON file in a directory
+// When there are more than size files, the least recently used ones are removed (file modification time is updated on each hit)
+type ghDirCache struct {
+	dir   string
+	size  int
+	mtx   sync.Mutex
+	count int
+}
+
+// Get - returns cached response, errors (like missing or corrupted file) are cache misses
+func (c *ghDirCache) Get(key string) (*GHCacheEntry, bool) {
+	fn := c.dir + ghCacheKey(key) + ".json"
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, false
+	}
+	var entry GHCacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		return nil, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(fn, now, now)
+	return &entry, true
+}
+
+// Put - saves response, file is renamed when written, so concurrent readers never see partial data
+func (c *ghDirCache) Put(key string, entry *GHCacheEntry) {
+	data, err := json.Marshal(entry)
+	FatalOnError(err)
+	file, err := ioutil.TempFile(c.dir, "tmp")
+	FatalOnError(err)
+	_, err = file.Write(data)
+	FatalOnError(err)
+	FatalOnError(file.Close())
+	fn := c.dir + ghCacheKey(key) + ".json"
+	_, err = os.Stat(fn)
+	FatalOnError(os.Rename(file.Name(), fn))
+	if err == nil || c.size <= 0 {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.count++
+	if c.count > c.size {
+		c.evict()
+	}
+}
+
+// files - returns cached responses files, the least recently used first
+func (c *ghDirCache) files() (files []os.FileInfo) {
+	infos, err := ioutil.ReadDir(c.dir)
+	FatalOnError(err)
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), ".json") {
+			files = append(files, info)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	return
+}
+
+// evict - removes the least recently used responses, down to 90% of size, so it doesn't run on every Put
+// Other processes can share the directory, so files are counted again and removal errors are ignored
+func (c *ghDirCache) evict() {
+	files := c.files()
+	keep := c.size - c.size/10
+	for len(files) > keep {
+		_ = os.Remove(c.dir + files[0].Name())
+		files = files[1:]
+	}
+	c.count = len(files)
+}
+
+// ghPgCache - cache storing responses in `gha_ghapi_cache` table
+type ghPgCache struct {
+	ctx *Ctx
+	con *sql.DB
+}
+
+// Get - returns cached response
+func (c *ghPgCache) Get(key string) (*GHCacheEntry, bool) {
+	rows := QuerySQLWithErr(
+		c.con,
+		c.ctx,
+		"select etag, last_modified, header, body from gha_ghapi_cache where key = $1",
+		ghCacheKey(key),
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var (
+		entry  GHCacheEntry
+		header []byte
+		got    bool
+	)
+	for rows.Next() {
+		FatalOnError(rows.Scan(&entry.ETag, &entry.LastModified, &header, &entry.Body))
+		got = true
+	}
+	FatalOnError(rows.Err())
+	if !got || json.Unmarshal(header, &entry.Header) != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Put - saves response
+func (c *ghPgCache) Put(key string, entry *GHCacheEntry) {
+	header, err := json.Marshal(entry.Header)
+	FatalOnError(err)
+	ExecSQLWithErr(
+		c.con,
+		c.ctx,
+		"insert into gha_ghapi_cache(key, url, etag, last_modified, header, body, updated_at) "+NValues(7)+
+			" on conflict(key) do update set etag = excluded.etag, last_modified = excluded.last_modified, "+
+			"header = excluded.header, body = excluded.body, updated_at = excluded.updated_at",
+		ghCacheKey(key),
+		key,
+		entry.ETag,
+		entry.LastModified,
+		string(header),
+		entry.Body,
+		time.Now(),
+	)
+}
@@ -0,0 +1,289 @@
+package devstats
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// GHGraphQLURL - GitHub GraphQL (v4) API endpoint
+const GHGraphQLURL = "https://api.github.com/graphql"
+
+// GHGraphQLMaxIssues - maximum number of issues/PRs fetched by a single GraphQL query
+const GHGraphQLMaxIssues = 100
+
+// GHIssueKey - identifies issue/PR: repository name "org/repo" and issue number
+type GHIssueKey struct {
+	Repo   string
+	Number int
+}
+
+// GHGraphQL - GitHub GraphQL (v4) API client
+// It uses the same tokens as REST API client, but GraphQL API has its own rate limit (points are based on query cost)
+// Rate limit state is taken from the last response, so with multiple tokens it is the state of the last used token
+type GHGraphQL struct {
+	URL       string
+	Client    *http.Client
+	mtx       sync.Mutex
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewGHGraphQL - returns GitHub GraphQL API client using GHA2DB_GITHUB_OAUTH tokens and given endpoint
+func NewGHGraphQL(ctx *Ctx, url string) *GHGraphQL {
+	return &GHGraphQL{
+		URL:       url,
+		Client:    &http.Client{Transport: NewGHTransport(GitHubTokens(ctx), nil)},
+		remaining: -1,
+	}
+}
+
+// ghGraphQLIssueFields - issue/PR fields needed by `ghapi2db`
+// Issue can have at most 100 labels, so a single labels page is enough
+const ghGraphQLIssueFields = "databaseId number state closedAt locked comments { totalCount } " +
+	"milestone { id } labels(first: 100) { nodes { id name } } assignees(first: 100) { nodes { databaseId login } }"
+
+// ghGraphQLRateLimit - rate limit part of GraphQL response
+type ghGraphQLRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// ghGraphQLIssue - issue/PR part of GraphQL response
+type ghGraphQLIssue struct {
+	DatabaseID int64      `json:"databaseId"`
+	Number     int        `json:"number"`
+	State      string     `json:"state"`
+	ClosedAt   *time.Time `json:"closedAt"`
+	Locked     bool       `json:"locked"`
+	Comments   struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+	Milestone *struct {
+		ID string `json:"id"`
+	} `json:"milestone"`
+	Labels struct {
+		Nodes []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []struct {
+			DatabaseID int64  `json:"databaseId"`
+			Login      string `json:"login"`
+		} `json:"nodes"`
+	} `json:"assignees"`
+}
+
+// ghGraphQLError - error part of GraphQL response
+type ghGraphQLError struct {
+	Type    string        `json:"type"`
+	Path    []interface{} `json:"path"`
+	Message string        `json:"message"`
+}
+
+// GHNodeDatabaseID - returns database ID from legacy GitHub GraphQL node ID, for example "MDU6TGFiZWwxMjM=" ("05:Label123") -> 123
+// Legacy node IDs are requested using "X-Github-Next-Global-ID: 0" header
+func GHNodeDatabaseID(nodeID string) (int64, error) {
+	data, err := base64.StdEncoding.DecodeString(nodeID)
+	if err != nil {
+		return 0, fmt.Errorf("cannot decode node ID '%s': %v", nodeID, err)
+	}
+	ary := strings.SplitN(string(data), ":", 2)
+	if len(ary) != 2 {
+		return 0, fmt.Errorf("unsupported node ID '%s' (%s)", nodeID, string(data))
+	}
+	digits := strings.TrimLeft(ary[1], "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	id, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported node ID '%s' (%s)", nodeID, string(data))
+	}
+	return id, nil
+}
+
+// query - executes GraphQL query with variables, saves rate limit state and returns response data and errors
+func (g *GHGraphQL) query(gctx context.Context, query string, vars map[string]interface{}) (map[string]json.RawMessage, []ghGraphQLError, error) {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", g.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(gctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Github-Next-Global-ID", "0")
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	FatalOnError(resp.Body.Close())
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GraphQL query failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		Data   map[string]json.RawMessage `json:"data"`
+		Errors []ghGraphQLError           `json:"errors"`
+	}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+	if raw, ok := result.Data["rateLimit"]; ok {
+		var rl ghGraphQLRateLimit
+		if json.Unmarshal(raw, &rl) == nil {
+			g.mtx.Lock()
+			g.limit, g.remaining, g.reset = rl.Limit, rl.Remaining, rl.ResetAt
+			g.mtx.Unlock()
+		}
+	}
+	return result.Data, result.Errors, nil
+}
+
+// RateLimits - returns GraphQL API points limit, remaining points and duration to wait for reset
+// State is taken from the last query, the first call queries it
+func (g *GHGraphQL) RateLimits(gctx context.Context) (int, int, time.Duration, error) {
+	g.mtx.Lock()
+	known := g.remaining >= 0
+	g.mtx.Unlock()
+	if !known {
+		_, errs, err := g.query(gctx, "query { rateLimit { limit remaining resetAt } }", nil)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if len(errs) > 0 {
+			return 0, 0, 0, fmt.Errorf("GraphQL rate limit query failed: %s", errs[0].Message)
+		}
+	}
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.limit, g.remaining, g.reset.Sub(time.Now()) + time.Duration(1)*time.Second, nil
+}
+
+// Issues - returns issues/PRs as REST API issues with state, closed date, locked flag, comments count, milestone, labels and assignees set
+// Up to GHGraphQLMaxIssues issues are fetched by a single query, missing issues (deleted or transferred) are not returned
+func (g *GHGraphQL) Issues(gctx context.Context, keys []GHIssueKey) (map[GHIssueKey]*github.Issue, error) {
+	if len(keys) > GHGraphQLMaxIssues {
+		return nil, fmt.Errorf("too many issues in a single GraphQL query: %d > %d", len(keys), GHGraphQLMaxIssues)
+	}
+	issues := make(map[GHIssueKey]*github.Issue)
+	if len(keys) == 0 {
+		return issues, nil
+	}
+	params := []string{}
+	fields := []string{}
+	vars := make(map[string]interface{})
+	aliases := make(map[string]GHIssueKey)
+	for i, key := range keys {
+		ary := strings.Split(key.Repo, "/")
+		if len(ary) != 2 {
+			return nil, fmt.Errorf("wrong repository name: %s", key.Repo)
+		}
+		alias := fmt.Sprintf("r%d", i)
+		aliases[alias] = key
+		params = append(params, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+		fields = append(
+			fields,
+			fmt.Sprintf(
+				"%s: repository(owner: $o%d, name: $n%d) { issueOrPullRequest(number: %d) { "+
+					"... on Issue { %s } ... on PullRequest { %s } } }",
+				alias, i, i, key.Number, ghGraphQLIssueFields, ghGraphQLIssueFields,
+			),
+		)
+		vars[fmt.Sprintf("o%d", i)] = ary[0]
+		vars[fmt.Sprintf("n%d", i)] = ary[1]
+	}
+	query := "query(" + strings.Join(params, ", ") + ") { " + strings.Join(fields, " ") +
+		" rateLimit { limit remaining resetAt } }"
+	data, errs, err := g.query(gctx, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range errs {
+		if e.Type != "NOT_FOUND" {
+			return nil, fmt.Errorf("GraphQL query failed: %s", e.Message)
+		}
+	}
+	for alias, key := range aliases {
+		raw, ok := data[alias]
+		if !ok {
+			continue
+		}
+		var repo struct {
+			IssueOrPullRequest *ghGraphQLIssue `json:"issueOrPullRequest"`
+		}
+		if string(raw) == Null {
+			continue
+		}
+		err = json.Unmarshal(raw, &repo)
+		if err != nil {
+			return nil, err
+		}
+		if repo.IssueOrPullRequest == nil {
+			continue
+		}
+		issue, err := repo.IssueOrPullRequest.githubIssue()
+		if err != nil {
+			return nil, err
+		}
+		issues[key] = issue
+	}
+	return issues, nil
+}
+
+// githubIssue - converts GraphQL issue/PR to REST API issue, GraphQL states OPEN, CLOSED, MERGED are mapped to "open" and "closed"
+func (i *ghGraphQLIssue) githubIssue() (*github.Issue, error) {
+	state := "closed"
+	if i.State == "OPEN" {
+		state = "open"
+	}
+	issue := &github.Issue{
+		ID:       &i.DatabaseID,
+		Number:   &i.Number,
+		State:    &state,
+		ClosedAt: i.ClosedAt,
+		Locked:   &i.Locked,
+		Comments: &i.Comments.TotalCount,
+	}
+	if i.Milestone != nil {
+		id, err := GHNodeDatabaseID(i.Milestone.ID)
+		if err != nil {
+			return nil, err
+		}
+		issue.Milestone = &github.Milestone{ID: &id}
+	}
+	for _, node := range i.Labels.Nodes {
+		id, err := GHNodeDatabaseID(node.ID)
+		if err != nil {
+			return nil, err
+		}
+		name := node.Name
+		issue.Labels = append(issue.Labels, github.Label{ID: &id, Name: &name})
+	}
+	for _, node := range i.Assignees.Nodes {
+		id, login := node.DatabaseID, node.Login
+		issue.Assignees = append(issue.Assignees, &github.User{ID: &id, Login: &login})
+	}
+	if len(issue.Assignees) > 0 {
+		issue.Assignee = issue.Assignees[0]
+	}
+	return issue, nil
+}
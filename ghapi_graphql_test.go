@@ -0,0 +1,133 @@
+package devstats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	lib "devstats"
+)
+
+// fakeGraphQL - GitHub GraphQL API server returning issues from "org/repo#number" -> JSON map, other issues are not found
+type fakeGraphQL struct {
+	issues  map[string]string
+	queries int
+}
+
+func (f *fakeGraphQL) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.queries++
+	var req struct {
+		Query     string            `json:"query"`
+		Variables map[string]string `json:"variables"`
+	}
+	if r.Header.Get("X-Github-Next-Global-ID") != "0" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data := []string{`"rateLimit":{"limit":5000,"remaining":4990,"resetAt":"2030-01-01T00:00:00Z"}`}
+	errs := []string{}
+	re := regexp.MustCompile(`(r\d+): repository\(owner: \$(o\d+), name: \$(n\d+)\) \{ issueOrPullRequest\(number: (\d+)\)`)
+	for _, m := range re.FindAllStringSubmatch(req.Query, -1) {
+		key := fmt.Sprintf("%s/%s#%s", req.Variables[m[2]], req.Variables[m[3]], m[4])
+		issue, ok := f.issues[key]
+		if !ok {
+			data = append(data, fmt.Sprintf(`"%s":{"issueOrPullRequest":null}`, m[1]))
+			errs = append(errs, fmt.Sprintf(`{"type":"NOT_FOUND","path":["%s","issueOrPullRequest"],"message":"not found"}`, m[1]))
+			continue
+		}
+		data = append(data, fmt.Sprintf(`"%s":{"issueOrPullRequest":%s}`, m[1], issue))
+	}
+	_, _ = w.Write([]byte(`{"data":{` + strings.Join(data, ",") + `},"errors":[` + strings.Join(errs, ",") + `]}`))
+}
+
+func TestGHGraphQLIssues(t *testing.T) {
+	fake := &fakeGraphQL{
+		issues: map[string]string{
+			"org/repo#1": `{"databaseId":101,"number":1,"state":"OPEN","closedAt":null,"locked":false,"comments":{"totalCount":3},` +
+				`"milestone":{"id":"MDk6TWlsZXN0b25lNDI="},"labels":{"nodes":[{"id":"MDU6TGFiZWwxMjM=","name":"bug"}]},` +
+				`"assignees":{"nodes":[{"databaseId":7,"login":"john"}]}}`,
+			"org/other#2": `{"databaseId":102,"number":2,"state":"MERGED","closedAt":"2018-03-01T12:00:00Z","locked":true,"comments":{"totalCount":0},` +
+				`"milestone":null,"labels":{"nodes":[]},"assignees":{"nodes":[]}}`,
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	gql := lib.NewGHGraphQL(&lib.Ctx{GitHubOAuth: "-"}, server.URL)
+	gctx := context.Background()
+
+	// Rate limits are known after the first query
+	_, rem, _, err := gql.RateLimits(gctx)
+	if err != nil || rem != 4990 || fake.queries != 1 {
+		t.Errorf("expected 4990 points remaining after 1 query, got %d after %d queries, error: %v", rem, fake.queries, err)
+	}
+
+	// Issues
+	keys := []lib.GHIssueKey{{Repo: "org/repo", Number: 1}, {Repo: "org/other", Number: 2}, {Repo: "org/repo", Number: 3}}
+	issues, err := gql.Issues(gctx, keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.queries != 2 {
+		t.Errorf("expected all issues to be fetched by a single query, got %d queries", fake.queries-1)
+	}
+	got := map[lib.GHIssueKey]string{}
+	for key, issue := range issues {
+		milestone := lib.Null
+		if issue.Milestone != nil {
+			milestone = fmt.Sprintf("%d", *issue.Milestone.ID)
+		}
+		labels := []string{}
+		for _, label := range issue.Labels {
+			labels = append(labels, fmt.Sprintf("%d:%s", *label.ID, *label.Name))
+		}
+		assignees := []string{}
+		for _, assignee := range issue.Assignees {
+			assignees = append(assignees, fmt.Sprintf("%d:%s", *assignee.ID, *assignee.Login))
+		}
+		got[key] = fmt.Sprintf(
+			"%d %s closed=%v locked=%v comments=%d milestone=%s labels=%v assignees=%v",
+			*issue.ID, *issue.State, issue.ClosedAt != nil, *issue.Locked, *issue.Comments, milestone, labels, assignees,
+		)
+	}
+	expected := map[lib.GHIssueKey]string{
+		keys[0]: "101 open closed=false locked=false comments=3 milestone=42 labels=[123:bug] assignees=[7:john]",
+		keys[1]: "102 closed closed=true locked=true comments=0 milestone=null labels=[] assignees=[]",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Too many issues for a single query
+	_, err = gql.Issues(gctx, make([]lib.GHIssueKey, lib.GHGraphQLMaxIssues+1))
+	if err == nil {
+		t.Errorf("expected error for more than %d issues", lib.GHGraphQLMaxIssues)
+	}
+}
+
+func TestGHNodeDatabaseID(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		nodeID   string
+		expected int64
+		err      bool
+	}{
+		{nodeID: "MDU6TGFiZWwxMjM=", expected: 123},
+		{nodeID: "MDk6TWlsZXN0b25lNDI=", expected: 42},
+		{nodeID: "MDQ6VXNlcjU4MzIzMQ==", expected: 583231},
+		{nodeID: "LA_kwDOABCDEF", err: true},
+		{nodeID: "bm9jb2xvbg==", err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.GHNodeDatabaseID(test.nodeID)
+		if (err != nil) != test.err || got != test.expected {
+			t.Errorf("test number %d, expected %d (error: %v), got %d (error: %v)", index+1, test.expected, test.err, got, err)
+		}
+	}
+}
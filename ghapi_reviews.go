@@ -0,0 +1,110 @@
+package devstats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// ArtificialEventBase - artificial issue events created by `ghapi2db` have IDs: this (2^48) + ID of the source event
+// Source event can itself be an artificial issue event, so their IDs are 2^48 * n + ID of the source GHA event
+const ArtificialEventBase int64 = 281474976710656
+
+// PRArtificialEventBase - artificial pull request events created by `ghapi2db` have IDs >= this (2^62)
+// Artificial issue events stay below it, so artificial issue and pull request events never collide
+const PRArtificialEventBase int64 = 4611686018427387904
+
+// PRArtificialEventID - returns ID of an artificial pull request event created from a given source event
+// The first one uses 2^62 + ID of the source GHA event (GHA event IDs are below 2^48),
+// next ones (created from the previous artificial pull request event) add 2^48 to its ID
+func PRArtificialEventID(eid int64) int64 {
+	if eid >= PRArtificialEventBase {
+		return eid + ArtificialEventBase
+	}
+	return PRArtificialEventBase + eid
+}
+
+// PRState - pull request merge state and requested reviewers
+// `ghapi2db` compares state from the last pull request event in the database with the state from GitHub API
+type PRState struct {
+	State      string
+	Merged     bool
+	MergedAt   *time.Time
+	MergedByID *int64
+	ClosedAt   *time.Time
+	Reviewers  string
+}
+
+// PRStateFromGitHub - returns pull request state from GitHub API pull request and its requested reviewers (teams are skipped)
+func PRStateFromGitHub(pr *github.PullRequest, reviewers *github.Reviewers) (state PRState) {
+	state.State = pr.GetState()
+	state.Merged = pr.GetMerged()
+	state.MergedAt = pr.MergedAt
+	state.ClosedAt = pr.ClosedAt
+	if pr.MergedBy != nil {
+		state.MergedByID = pr.MergedBy.ID
+	}
+	if reviewers != nil {
+		ids := []int64{}
+		for _, user := range reviewers.Users {
+			if user.ID != nil {
+				ids = append(ids, *user.ID)
+			}
+		}
+		state.Reviewers = ReviewersString(ids)
+	}
+	return
+}
+
+// ReviewersString - returns sorted, comma separated requested reviewers IDs
+func ReviewersString(ids []int64) string {
+	sorted := Int64Ary(append([]int64{}, ids...))
+	sort.Sort(sorted)
+	strs := []string{}
+	for i, id := range sorted {
+		if i > 0 && id == sorted[i-1] {
+			continue
+		}
+		strs = append(strs, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(strs, ",")
+}
+
+// Diff - returns descriptions of differences between pull request state `s` (database) and `gh` (GitHub API), nil when they are the same
+func (s PRState) Diff(gh PRState) (diffs []string) {
+	timeStr := func(dt *time.Time) string {
+		if dt == nil {
+			return Null
+		}
+		return ToYMDHMSDate(*dt)
+	}
+	idStr := func(id *int64) string {
+		if id == nil {
+			return Null
+		}
+		return strconv.FormatInt(*id, 10)
+	}
+	if s.State != gh.State {
+		diffs = append(diffs, fmt.Sprintf("state: %s -> %s", s.State, gh.State))
+	}
+	if s.Merged != gh.Merged {
+		diffs = append(diffs, fmt.Sprintf("merged: %v -> %v", s.Merged, gh.Merged))
+	}
+	if timeStr(s.MergedAt) != timeStr(gh.MergedAt) {
+		diffs = append(diffs, fmt.Sprintf("merged_at: %s -> %s", timeStr(s.MergedAt), timeStr(gh.MergedAt)))
+	}
+	if idStr(s.MergedByID) != idStr(gh.MergedByID) {
+		diffs = append(diffs, fmt.Sprintf("merged_by_id: %s -> %s", idStr(s.MergedByID), idStr(gh.MergedByID)))
+	}
+	if timeStr(s.ClosedAt) != timeStr(gh.ClosedAt) {
+		diffs = append(diffs, fmt.Sprintf("closed_at: %s -> %s", timeStr(s.ClosedAt), timeStr(gh.ClosedAt)))
+	}
+	if s.Reviewers != gh.Reviewers {
+		diffs = append(diffs, fmt.Sprintf("requested reviewers: '%s' -> '%s'", s.Reviewers, gh.Reviewers))
+	}
+	return
+}
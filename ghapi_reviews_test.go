@@ -0,0 +1,107 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "devstats"
+
+	"github.com/google/go-github/github"
+)
+
+func TestReviewersString(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		ids      []int64
+		expected string
+	}{
+		{ids: nil, expected: ""},
+		{ids: []int64{7}, expected: "7"},
+		{ids: []int64{30, 4, 200}, expected: "4,30,200"},
+		{ids: []int64{2, 1, 2, 1}, expected: "1,2"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ReviewersString(test.ids)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%s', got '%s'", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPRStateDiff(t *testing.T) {
+	// Test data
+	id1, id2 := int64(1), int64(2)
+	merged := true
+	state := "closed"
+	dt := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	dt2 := time.Date(2018, 3, 2, 12, 0, 0, 0, time.UTC)
+	ghPR := &github.PullRequest{
+		State:    &state,
+		Merged:   &merged,
+		MergedAt: &dt,
+		ClosedAt: &dt,
+		MergedBy: &github.User{ID: &id1},
+	}
+	ghReviewers := &github.Reviewers{Users: []*github.User{{ID: &id2}, {ID: &id1}}}
+	gh := lib.PRStateFromGitHub(ghPR, ghReviewers)
+
+	// Test cases
+	var testCases = []struct {
+		db       lib.PRState
+		expected []string
+	}{
+		{
+			db:       lib.PRState{State: "closed", Merged: true, MergedAt: &dt, MergedByID: &id1, ClosedAt: &dt, Reviewers: "1,2"},
+			expected: nil,
+		},
+		{
+			db: lib.PRState{State: "open", Reviewers: "2"},
+			expected: []string{
+				"state: open -> closed",
+				"merged: false -> true",
+				"merged_at: null -> 2018-03-01 12:00:00",
+				"merged_by_id: null -> 1",
+				"closed_at: null -> 2018-03-01 12:00:00",
+				"requested reviewers: '2' -> '1,2'",
+			},
+		},
+		{
+			db: lib.PRState{State: "closed", Merged: true, MergedAt: &dt2, MergedByID: &id2, ClosedAt: &dt, Reviewers: "1,2"},
+			expected: []string{
+				"merged_at: 2018-03-02 12:00:00 -> 2018-03-01 12:00:00",
+				"merged_by_id: 2 -> 1",
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := test.db.Diff(gh)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestPRArtificialEventID(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		eid      int64
+		expected int64
+	}{
+		{eid: 7, expected: lib.PRArtificialEventBase + 7},
+		{eid: 9000000000, expected: lib.PRArtificialEventBase + 9000000000},
+		{eid: lib.ArtificialEventBase + 7, expected: lib.PRArtificialEventBase + lib.ArtificialEventBase + 7},
+		{eid: 2*lib.ArtificialEventBase + 7, expected: lib.PRArtificialEventBase + 2*lib.ArtificialEventBase + 7},
+		{eid: lib.PRArtificialEventBase + 7, expected: lib.PRArtificialEventBase + lib.ArtificialEventBase + 7},
+		{eid: lib.PRArtificialEventBase + lib.ArtificialEventBase + 7, expected: lib.PRArtificialEventBase + 2*lib.ArtificialEventBase + 7},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.PRArtificialEventID(test.eid)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %d, got %d", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package devstats
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	lib "devstats"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGHNotFound(t *testing.T) {
+	// Fake GitHub API: issue 1 exists, issue 2 was deleted, issue 3 is in a removed repository, issue 4 fails
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/org/repo/issues/1":
+				_, _ = w.Write([]byte(`{"id":1,"number":1}`))
+			case "/repos/org/repo/issues/2":
+				w.WriteHeader(http.StatusGone)
+				_, _ = w.Write([]byte(`{"message":"This issue was deleted"}`))
+			case "/repos/org/repo/issues/4":
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"message":"Server Error"}`))
+			default:
+				http.NotFound(w, r)
+			}
+		}),
+	)
+	defer server.Close()
+	gc := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gc.BaseURL = baseURL
+
+	// Test cases
+	var testCases = []struct {
+		number   int
+		err      bool
+		notFound bool
+	}{
+		{number: 1},
+		{number: 2, err: true, notFound: true},
+		{number: 3, err: true, notFound: true},
+		{number: 4, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		_, _, err := gc.Issues.Get(context.Background(), "org", "repo", test.number)
+		got := lib.GHNotFound(err)
+		if (err != nil) != test.err || got != test.notFound {
+			t.Errorf("test number %d, expected error %v, not found %v, got error %v, not found %v", index+1, test.err, test.notFound, err, got)
+		}
+	}
+}
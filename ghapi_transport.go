@@ -0,0 +1,277 @@
+package devstats
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ghTokenCtxKey - context key forcing GHTransport to use token with given index (used to get rate limits of every token)
+type ghTokenCtxKey struct{}
+
+// ghToken - GitHub API token and last known state of its core API points
+type ghToken struct {
+	token     string
+	limit     int
+	remaining int
+	reset     time.Time
+	invalid   bool
+}
+
+// GHTransport - GitHub API transport using multiple tokens and conditional requests
+// Each request uses the token with the most remaining core API points, tokens with unknown state are tried first
+// Tokens rejected by GitHub (401 or 403 not caused by rate limits) are skipped until their rate limits can be queried again,
+// requests without body rejected because of the token are retried with the next usable token
+// When cache is set, GET responses are cached with their ETag/Last-Modified and sent as conditional requests,
+// "304 Not Modified" responses don't cost API points and are returned as cached "200 OK" responses
+// Core rate limit headers of responses are replaced with totals of all tokens, so GitHub client stops only when all tokens are exhausted
+type GHTransport struct {
+	Base   http.RoundTripper
+	Cache  GHCache
+	mtx    sync.Mutex
+	tokens []*ghToken
+}
+
+// NewGHTransport - returns transport using given tokens (empty list means public access) and optional cache
+func NewGHTransport(tokens []string, cache GHCache) *GHTransport {
+	t := &GHTransport{Base: http.DefaultTransport, Cache: cache}
+	for _, token := range tokens {
+		t.tokens = append(t.tokens, &ghToken{token: token, remaining: -1})
+	}
+	return t
+}
+
+// pick - returns index of token to use, -1 for public access
+// When all tokens are unusable returns the first one, so the request fails with GitHub's reason
+func (t *GHTransport) pick(ctx context.Context) int {
+	if len(t.tokens) == 0 {
+		return -1
+	}
+	if i, ok := ctx.Value(ghTokenCtxKey{}).(int); ok && i >= 0 && i < len(t.tokens) {
+		return i
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := time.Now()
+	best, bestRemaining := 0, -2
+	for i, token := range t.tokens {
+		if token.invalid {
+			continue
+		}
+		remaining := token.remaining
+		if remaining < 0 {
+			// Unknown state, try it to learn it
+			return i
+		}
+		if now.After(token.reset) {
+			remaining = token.limit
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = i, remaining
+		}
+	}
+	return best
+}
+
+// SetRates - sets core API points state of token with given index, token becomes usable again
+func (t *GHTransport) SetRates(i, limit, remaining int, reset time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	token := t.tokens[i]
+	token.limit, token.remaining, token.reset, token.invalid = limit, remaining, reset, false
+}
+
+// Invalidate - marks token with given index as unusable, reason is logged when the token was usable
+func (t *GHTransport) Invalidate(i int, reason string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.tokens[i].invalid {
+		return
+	}
+	t.tokens[i].invalid = true
+	Printf("GitHub API token #%d is not usable, skipping it: %s\n", i+1, reason)
+}
+
+// Usable - returns number of tokens that are not marked as unusable
+func (t *GHTransport) Usable() (n int) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for _, token := range t.tokens {
+		if !token.invalid {
+			n++
+		}
+	}
+	return
+}
+
+// Rates - returns total core API points limit and remaining points of all usable tokens
+// and duration to wait for the nearest reset of a token that used some points
+func (t *GHTransport) Rates() (limit, remaining int, wait time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := time.Now()
+	var reset time.Time
+	for _, token := range t.tokens {
+		if token.invalid {
+			continue
+		}
+		limit += token.limit
+		if now.After(token.reset) {
+			remaining += token.limit
+			continue
+		}
+		if token.remaining > 0 {
+			remaining += token.remaining
+		}
+		if reset.IsZero() || token.reset.Before(reset) {
+			reset = token.reset
+		}
+	}
+	if !reset.IsZero() {
+		wait = reset.Sub(now)
+	}
+	return
+}
+
+// Tokens - returns number of tokens
+func (t *GHTransport) Tokens() int {
+	return len(t.tokens)
+}
+
+// updateRates - updates token state from core rate limit response headers and replaces them with totals of all tokens
+func (t *GHTransport) updateRates(i int, resp *http.Response) {
+	if i < 0 {
+		return
+	}
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource != "" && resource != "core" {
+		return
+	}
+	limit, err1 := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	t.SetRates(i, limit, remaining, time.Unix(reset, 0))
+	if len(t.tokens) < 2 {
+		return
+	}
+	limit, remaining, wait := t.Rates()
+	resp.Header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	resp.Header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(wait).Unix(), 10))
+}
+
+// ghTokenRejected - checks if response means that GitHub doesn't accept the token: bad credentials (401)
+// or forbidden (403) that is not caused by exhausted or secondary (abuse) rate limits
+func ghTokenRejected(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("X-RateLimit-Remaining") != "0" && resp.Header.Get("Retry-After") == ""
+	}
+	return false
+}
+
+// RoundTrip - executes request using the best token and the cache
+// When token is rejected, it is marked as unusable and request without body is retried with the next usable token
+func (t *GHTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, forced := req.Context().Value(ghTokenCtxKey{}).(int)
+	for {
+		i := t.pick(req.Context())
+		resp, rejected, err := t.roundTrip(req, i)
+		if err != nil || !rejected || forced || (req.Body != nil && req.Body != http.NoBody) || t.Usable() == 0 {
+			return resp, err
+		}
+		FatalOnError(resp.Body.Close())
+	}
+}
+
+// roundTrip - executes request using token with given index and the cache, returns if the token was rejected
+func (t *GHTransport) roundTrip(req *http.Request, i int) (*http.Response, bool, error) {
+	// Request must not be modified, use a copy with its own headers
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	if i >= 0 {
+		r.Header.Set("Authorization", "token "+t.tokens[i].token)
+	}
+
+	// Conditional request when we have cached response
+	var (
+		key    string
+		cached *GHCacheEntry
+	)
+	if t.Cache != nil && r.Method == "GET" {
+		// Responses are cached per token, because different tokens can see different data, token is only stored as a hash
+		key = r.URL.String() + " " + r.Header.Get("Accept") + " " + ghCacheKey(r.Header.Get("Authorization"))
+		if entry, ok := t.Cache.Get(key); ok {
+			cached = entry
+			if entry.ETag != "" {
+				r.Header.Set("If-None-Match", entry.ETag)
+			}
+			if entry.LastModified != "" {
+				r.Header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+	}
+	resp, err := t.Base.RoundTrip(r)
+	if err != nil {
+		return nil, false, err
+	}
+	if i >= 0 && ghTokenRejected(resp) {
+		t.Invalidate(i, resp.Status)
+		return resp, true, nil
+	}
+	t.updateRates(i, resp)
+	if key == "" {
+		return resp, false, nil
+	}
+
+	// Not modified, return cached response with current rate limit headers
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		FatalOnError(resp.Body.Close())
+		header := make(http.Header, len(cached.Header))
+		for k, v := range cached.Header {
+			header[k] = v
+		}
+		for _, h := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-RateLimit-Resource"} {
+			if v := resp.Header.Get(h); v != "" {
+				header.Set(h, v)
+			}
+		}
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, false, nil
+	}
+
+	// Cache successful responses that can be validated
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusOK && (etag != "" || lastModified != "") {
+		body, err := ioutil.ReadAll(resp.Body)
+		FatalOnError(resp.Body.Close())
+		if err != nil {
+			return nil, false, err
+		}
+		t.Cache.Put(key, &GHCacheEntry{ETag: etag, LastModified: lastModified, Header: resp.Header, Body: body})
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, false, nil
+}
@@ -0,0 +1,214 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+// fakeGitHub - GitHub API server counting API points of tokens, "/etag" returns 304 when "If-None-Match" matches
+// Tokens without points entry are rejected with "401 Unauthorized"
+type fakeGitHub struct {
+	mtx       sync.Mutex
+	remaining map[string]int
+	used      []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	token := r.Header.Get("Authorization")
+	if _, ok := f.remaining[token]; !ok {
+		f.used = append(f.used, token)
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	notModified := r.URL.Path == "/etag" && r.Header.Get("If-None-Match") == `"v1"`
+	if !notModified {
+		f.remaining[token]--
+	}
+	f.used = append(f.used, token)
+	w.Header().Set("X-RateLimit-Limit", "100")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(f.remaining[token]))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	if r.URL.Path == "/etag" {
+		w.Header().Set("ETag", `"v1"`)
+	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write([]byte("data"))
+}
+
+func TestGHTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_ghapi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	fake := &fakeGitHub{remaining: map[string]int{"token a": 10, "token b": 50}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := &http.Client{Transport: lib.NewGHTransport([]string{"a", "b"}, lib.NewGHCache(&lib.Ctx{GitHubCache: dir + "/"}))}
+
+	// Test cases
+	var testCases = []struct {
+		path      string
+		token     string
+		remaining string
+	}{
+		{path: "/a", token: "token a", remaining: "9"},
+		{path: "/b", token: "token b", remaining: "58"},
+		{path: "/etag", token: "token b", remaining: "57"},
+		{path: "/etag", token: "token b", remaining: "57"},
+		{path: "/c", token: "token b", remaining: "56"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		resp, err := client.Get(server.URL + test.path)
+		if err != nil {
+			t.Fatalf("test number %d, unexpected error: %v", index+1, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("test number %d, unexpected error: %v", index+1, err)
+		}
+		token := fake.used[len(fake.used)-1]
+		remaining := resp.Header.Get("X-RateLimit-Remaining")
+		if resp.StatusCode != http.StatusOK || string(body) != "data" || token != test.token || remaining != test.remaining {
+			t.Errorf(
+				"test number %d, expected 200 'data' using '%s' with %s points remaining, got %d '%s' using '%s' with %s points remaining",
+				index+1, test.token, test.remaining, resp.StatusCode, string(body), token, remaining,
+			)
+		}
+	}
+
+	// Responses are cached per token, other token sharing the cache doesn't get them
+	client = &http.Client{Transport: lib.NewGHTransport([]string{"a"}, lib.NewGHCache(&lib.Ctx{GitHubCache: dir + "/"}))}
+	resp, err := client.Get(server.URL + "/etag")
+	if err != nil {
+		t.Fatalf("other token, unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "8" {
+		t.Errorf("other token, expected 8 points remaining (not a conditional request), got %s", remaining)
+	}
+}
+
+func TestGHTransportInvalidToken(t *testing.T) {
+	fake := &fakeGitHub{remaining: map[string]int{"token a": 10}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	transport := lib.NewGHTransport([]string{"invalid", "a"}, nil)
+	client := &http.Client{Transport: transport}
+
+	// Invalid token is tried first (unknown state), request is retried with the valid one, then the invalid token is skipped
+	for index := 0; index < 2; index++ {
+		resp, err := client.Get(server.URL + "/a")
+		if err != nil {
+			t.Fatalf("test number %d, unexpected error: %v", index+1, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("test number %d, expected 200, got %d", index+1, resp.StatusCode)
+		}
+	}
+	expected := []string{"token invalid", "token a", "token a"}
+	if !reflect.DeepEqual(fake.used, expected) {
+		t.Errorf("expected tokens %v, got %v", expected, fake.used)
+	}
+	if usable := transport.Usable(); usable != 1 {
+		t.Errorf("expected 1 usable token, got %d", usable)
+	}
+	if limit, remaining, _ := transport.Rates(); limit != 100 || remaining != 8 {
+		t.Errorf("expected only valid token points 100/8, got %d/%d", limit, remaining)
+	}
+
+	// Token is usable again after its rate limits are set
+	transport.SetRates(0, 100, 100, time.Now().Add(time.Hour))
+	if usable := transport.Usable(); usable != 2 {
+		t.Errorf("expected 2 usable tokens, got %d", usable)
+	}
+}
+
+func TestGHDirCacheSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_ghapi")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	cache := lib.NewGHCache(&lib.Ctx{GitHubCache: dir + "/", GitHubCacheSize: 10})
+
+	// Fill the cache, use the oldest response, so "k1" and "k2" are the least recently used when the cache overflows
+	for i := 0; i < 11; i++ {
+		if i == 10 {
+			if _, ok := cache.Get("k0"); !ok {
+				t.Fatalf("expected k0 in the cache")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cache.Put("k"+strconv.Itoa(i), &lib.GHCacheEntry{ETag: strconv.Itoa(i)})
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 11; i++ {
+		key := "k" + strconv.Itoa(i)
+		_, ok := cache.Get(key)
+		expected := i != 1 && i != 2
+		if ok != expected {
+			t.Errorf("%s, expected cached %v, got %v", key, expected, ok)
+		}
+	}
+
+	// Cache created on existing directory counts its files
+	cache = lib.NewGHCache(&lib.Ctx{GitHubCache: dir + "/", GitHubCacheSize: 10})
+	cache.Put("k11", &lib.GHCacheEntry{ETag: "11"})
+	cache.Put("k12", &lib.GHCacheEntry{ETag: "12"})
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 9 {
+		t.Errorf("expected 9 cached responses after overflow, got %d", len(files))
+	}
+}
+
+func TestGitHubTokens(t *testing.T) {
+	file, err := ioutil.TempFile("", "devstats_oauth")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err = file.Write([]byte("t2\nt3,t4\n\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		oauth    string
+		expected []string
+	}{
+		{oauth: "-", expected: nil},
+		{oauth: "t1", expected: []string{"t1"}},
+		{oauth: "t1, " + file.Name() + ",t5", expected: []string{"t1", "t2", "t3", "t4", "t5"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.GitHubTokens(&lib.Ctx{GitHubOAuth: test.oauth})
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package devstats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GHADefaultURL - default GHA archive source, "{{dt}}" is replaced with YYYY-MM-DD-H
+const GHADefaultURL string = "http://data.gharchive.org/{{dt}}.json.gz"
+
+// isRemoteGHASource - is GHA archive source a http(s) URL template or a local directory/path template?
+func isRemoteGHASource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// GHAArchivePath - returns URL or local file path of GHA archive for a given hour
+// Source is taken from GHA2DB_GHA_URL, "{{dt}}" is replaced with GHA date (YYYY-MM-DD-H)
+// Source without "{{dt}}" is treated as a directory that holds "YYYY-MM-DD-H.json.gz" files
+func GHAArchivePath(ctx *Ctx, dt time.Time) string {
+	source := strings.TrimPrefix(ctx.GHAURL, "file://")
+	if !strings.Contains(source, "{{dt}}") {
+		source = strings.TrimRight(source, "/") + "/{{dt}}.json.gz"
+	}
+	return strings.Replace(source, "{{dt}}", ToGHADate(dt), -1)
+}
+
+// GHACachePath - returns path of GHA archive for a given hour in the local cache directory
+// Returns "" when no cache directory is configured
+func GHACachePath(ctx *Ctx, dt time.Time) string {
+	if ctx.GHACacheDir == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%s.json.gz", ctx.GHACacheDir, ToGHADate(dt))
+}
+
+// GetGHAArchive - returns reader of gzipped GHA archive for a given hour (it must be closed by caller)
+// It reads from local cache if present, then from local source, finally downloads from remote source
+// Downloaded files are saved in the cache directory (if configured) and reused next time
+// In offline mode (GHA2DB_GHA_OFFLINE) only local files are used
+// When there is no local file for a given hour or remote source returns 404, error satisfying os.IsNotExist is returned
+// Other non 200 HTTP responses (like 5xx) are returned as errors, so they can be retried
+func GetGHAArchive(ctx *Ctx, dt time.Time) (io.ReadCloser, error) {
+	// Check local cache first
+	cachePath := GHACachePath(ctx, dt)
+	if cachePath != "" {
+		file, err := os.Open(cachePath)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// Local mirror
+	path := GHAArchivePath(ctx, dt)
+	if !isRemoteGHASource(path) {
+		return os.Open(path)
+	}
+	if ctx.GHAOffline {
+		return nil, &os.PathError{Op: "offline", Path: path, Err: os.ErrNotExist}
+	}
+
+	// Get gzipped JSON array via HTTP
+	response, err := http.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		if response.StatusCode == http.StatusNotFound {
+			return nil, &os.PathError{Op: "get", Path: path, Err: os.ErrNotExist}
+		}
+		return nil, fmt.Errorf("%s: HTTP status %s", path, response.Status)
+	}
+	if cachePath == "" {
+		return response.Body, nil
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	// Save in cache, using temporary file renamed when complete
+	// so partially downloaded files are never used
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := fmt.Sprintf("%s.%d.tmp", cachePath, os.Getpid())
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, response.Body)
+	cErr := file.Close()
+	if err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, cachePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	return os.Open(cachePath)
+}
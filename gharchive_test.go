@@ -0,0 +1,164 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lib "devstats"
+)
+
+func TestGHAArchivePath(t *testing.T) {
+	dt := time.Date(2017, 8, 3, 8, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		source   string
+		expected string
+	}{
+		{source: lib.GHADefaultURL, expected: "http://data.gharchive.org/2017-08-03-8.json.gz"},
+		{source: "https://mirror/gha/{{dt}}.gz", expected: "https://mirror/gha/2017-08-03-8.gz"},
+		{source: "/data/gha", expected: "/data/gha/2017-08-03-8.json.gz"},
+		{source: "/data/gha/", expected: "/data/gha/2017-08-03-8.json.gz"},
+		{source: "file:///data/gha", expected: "/data/gha/2017-08-03-8.json.gz"},
+		{source: "file:///data/{{dt}}/x.json.gz", expected: "/data/2017-08-03-8/x.json.gz"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{GHAURL: test.source}
+		got := lib.GHAArchivePath(&ctx, dt)
+		if got != test.expected {
+			t.Errorf(
+				"test number %d, expected '%v', got '%v', test case: %+v",
+				index+1, test.expected, got, test,
+			)
+		}
+	}
+}
+
+func TestGHACachePath(t *testing.T) {
+	dt := time.Date(2017, 11, 8, 13, 0, 0, 0, time.UTC)
+	ctx := lib.Ctx{}
+	got := lib.GHACachePath(&ctx, dt)
+	if got != "" {
+		t.Errorf("expected no cache path, got '%v'", got)
+	}
+	ctx.GHACacheDir = "/cache/"
+	expected := "/cache/2017-11-08-13.json.gz"
+	got = lib.GHACachePath(&ctx, dt)
+	if got != expected {
+		t.Errorf("expected '%v', got '%v'", expected, got)
+	}
+}
+
+// readArchive - reads whole archive returned by GetGHAArchive
+func readArchive(t *testing.T, ctx *lib.Ctx, dt time.Time) (string, error) {
+	rc, err := lib.GetGHAArchive(ctx, dt)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Errorf("reading archive: %v", err)
+	}
+	return string(data), nil
+}
+
+func TestGetGHAArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gharchive")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	mirror := filepath.Join(dir, "mirror")
+	cache := filepath.Join(dir, "cache") + "/"
+	if err = os.Mkdir(mirror, 0755); err != nil {
+		t.Fatalf("cannot create mirror dir: %v", err)
+	}
+
+	// Fake GHA server: 2017-08-03-1 exists, 2017-08-03-3 returns 503, other hours do not exist, count requests
+	requests := 0
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.URL.Path == "/2017-08-03-3.json.gz" {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if r.URL.Path != "/2017-08-03-1.json.gz" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte("remote"))
+		}),
+	)
+	defer server.Close()
+	dt1 := time.Date(2017, 8, 3, 1, 0, 0, 0, time.UTC)
+	dt2 := time.Date(2017, 8, 3, 2, 0, 0, 0, time.UTC)
+	dt3 := time.Date(2017, 8, 3, 3, 0, 0, 0, time.UTC)
+
+	// Local mirror
+	err = ioutil.WriteFile(filepath.Join(mirror, "2017-08-03-1.json.gz"), []byte("local"), 0644)
+	if err != nil {
+		t.Fatalf("cannot write mirror file: %v", err)
+	}
+	ctx := lib.Ctx{GHAURL: mirror}
+	got, err := readArchive(t, &ctx, dt1)
+	if err != nil || got != "local" {
+		t.Errorf("local mirror: expected 'local', got '%v', error %v", got, err)
+	}
+	_, err = readArchive(t, &ctx, dt2)
+	if !os.IsNotExist(err) {
+		t.Errorf("local mirror: expected not exists error, got %v", err)
+	}
+
+	// Remote without cache
+	ctx = lib.Ctx{GHAURL: server.URL + "/{{dt}}.json.gz"}
+	got, err = readArchive(t, &ctx, dt1)
+	if err != nil || got != "remote" || requests != 1 {
+		t.Errorf("remote: expected 'remote' after 1 request, got '%v' after %d, error %v", got, requests, err)
+	}
+
+	// Server error is not reported as missing data
+	_, err = readArchive(t, &ctx, dt3)
+	if err == nil || os.IsNotExist(err) {
+		t.Errorf("remote server error: expected error other than not exists, got %v", err)
+	}
+
+	// Remote with cache (cache directory is created): 1st call downloads, 2nd uses cache
+	ctx.GHACacheDir = cache
+	for i := 0; i < 2; i++ {
+		got, err = readArchive(t, &ctx, dt1)
+		if err != nil || got != "remote" || requests != 3 {
+			t.Errorf("cache #%d: expected 'remote' after 3 requests, got '%v' after %d, error %v", i+1, got, requests, err)
+		}
+	}
+
+	// Missing hour is never cached
+	_, err = readArchive(t, &ctx, dt2)
+	if !os.IsNotExist(err) {
+		t.Errorf("missing remote hour: expected not exists error, got %v", err)
+	}
+	_, err = os.Stat(lib.GHACachePath(&ctx, dt2))
+	if !os.IsNotExist(err) {
+		t.Errorf("missing remote hour: should not be cached, got %v", err)
+	}
+
+	// Offline mode only uses cache
+	ctx.GHAOffline = true
+	requests = 0
+	got, err = readArchive(t, &ctx, dt1)
+	if err != nil || got != "remote" {
+		t.Errorf("offline cached: expected 'remote', got '%v', error %v", got, err)
+	}
+	_, err = readArchive(t, &ctx, dt2)
+	if !os.IsNotExist(err) {
+		t.Errorf("offline missing: expected not exists error, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("offline: expected no requests, got %d", requests)
+	}
+}
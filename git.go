@@ -0,0 +1,717 @@
+package devstats
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+)
+
+// GitFile - file changed by a commit, its size at that commit and numbers of added and removed lines
+// Size can be:
+// > 0 - normal file size
+// 0 - file created - no contenets
+// -1 - file referenced in the commit SHA but not found in this commit (means deleted)
+// -2 - some special file (submodule) or directory
+// Status is "A" (added), "M" (modified), "D" (deleted) or "R" (renamed from OldPath)
+// Added and removed lines are not counted for binary files
+type GitFile struct {
+	Path    string
+	OldPath string
+	Status  string
+	Size    int64
+	Added   int
+	Removed int
+	Binary  bool
+}
+
+// GitCommit - commit author and committer identity and files changed by the commit
+type GitCommit struct {
+	SHA            string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterDate  time.Time
+	Files          []GitFile
+}
+
+// GitTag - repository tag, its creator date and subject
+// For annotated tags it is tagger date and tag message subject, for lightweight tags committer date and commit message subject
+type GitTag struct {
+	Name    string
+	Date    time.Time
+	Subject string
+}
+
+// gitRepo - opened repository, go-git repositories are not safe for concurrent use
+type gitRepo struct {
+	mtx  sync.Mutex
+	repo *git.Repository
+}
+
+var (
+	gitRepos      = map[string]*gitRepo{}
+	gitReposMutex = &sync.Mutex{}
+)
+
+// gitRepoCacheSize - objects cache size of every opened repository
+// Opened repositories are kept for the whole run, go-git default (96 MiB per repository) would use too much memory
+const gitRepoCacheSize = 4 * cache.MiByte
+
+// plainOpenGitRepo - opens repository in a given directory (with .git subdirectory or bare) using a small objects cache
+// Other layouts (like .git file pointing to a different directory) are opened by git.PlainOpen with the default cache
+func plainOpenGitRepo(dir string) (*git.Repository, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	wt := osfs.New(dir)
+	fi, err := wt.Stat(git.GitDirName)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	dot := wt
+	if err == nil {
+		if !fi.IsDir() {
+			return git.PlainOpen(dir)
+		}
+		dot = osfs.New(filepath.Join(dir, git.GitDirName))
+	} else {
+		// Bare repository
+		wt = nil
+	}
+	if _, err := dot.Stat(""); err != nil {
+		if os.IsNotExist(err) {
+			return nil, git.ErrRepositoryNotExists
+		}
+		return nil, err
+	}
+	return git.Open(filesystem.NewStorage(dot, cache.NewObjectLRU(gitRepoCacheSize)), wt)
+}
+
+// openGitRepo - returns opened repository for a given directory, locked, caller must unlock it
+// Repositories are opened once, so packfile indexes are not read again for every commit
+func openGitRepo(dir string) (*gitRepo, error) {
+	gitReposMutex.Lock()
+	r, ok := gitRepos[dir]
+	if !ok {
+		repo, err := plainOpenGitRepo(dir)
+		if err != nil {
+			gitReposMutex.Unlock()
+			return nil, err
+		}
+		r = &gitRepo{repo: repo}
+		gitRepos[dir] = r
+	}
+	gitReposMutex.Unlock()
+	r.mtx.Lock()
+	return r, nil
+}
+
+// forgetGitRepo - removes repository from opened repositories, used when it is changed by shell scripts
+func forgetGitRepo(dir string) {
+	gitReposMutex.Lock()
+	delete(gitRepos, dir)
+	gitReposMutex.Unlock()
+}
+
+// gitSubject - returns subject of a commit/tag message: its first paragraph joined into a single line
+func gitSubject(message string) string {
+	message = strings.TrimSpace(strings.Replace(message, "\r", "", -1))
+	if i := strings.Index(message, "\n\n"); i >= 0 {
+		message = message[:i]
+	}
+	return strings.Replace(message, "\n", " ", -1)
+}
+
+// gitScriptsPrefix - returns path to git shell scripts
+func gitScriptsPrefix(ctx *Ctx) string {
+	// Local or cron mode?
+	if ctx.Local {
+		return LocalGitScripts
+	}
+	return ""
+}
+
+// GitClone - clones `url` repository into `dir` using go-git, falls back to `git clone` when it fails or when GHA2DB_GIT_SHELL is set
+func GitClone(ctx *Ctx, url, dir string) error {
+	if !ctx.GitShell {
+		_, err := git.PlainClone(dir, false, &git.CloneOptions{URL: url})
+		if err == nil {
+			return nil
+		}
+		if ctx.Debug > 0 {
+			Printf("go-git clone failed: %s: %v, falling back to git\n", url, err)
+		}
+		// Remove partially cloned repository
+		FatalOnError(os.RemoveAll(dir))
+	}
+	// We cannot chdir because this is a multithreaded app
+	// And all threads share CWD (current working directory)
+	_, err := ExecCommand(
+		ctx,
+		[]string{"git", "clone", url, dir},
+		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+	)
+	return err
+}
+
+// GitResetPull - resets `dir` repository working tree and pulls it using go-git,
+// falls back to `git_reset_pull.sh` when it fails (for example non fast-forward pull) or when GHA2DB_GIT_SHELL is set
+func GitResetPull(ctx *Ctx, dir string) error {
+	if !ctx.GitShell {
+		err := gogitResetPull(dir)
+		if err == nil {
+			return nil
+		}
+		if ctx.Debug > 0 {
+			Printf("go-git reset/pull failed: %s: %v, falling back to git_reset_pull.sh\n", dir, err)
+		}
+	}
+	forgetGitRepo(dir)
+	// Update repo using shell script that uses 'chdir'
+	// We cannot chdir because this is a multithreaded app
+	// And all threads share CWD (current working directory)
+	_, err := ExecCommand(
+		ctx,
+		[]string{gitScriptsPrefix(ctx) + "git_reset_pull.sh", dir},
+		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+	)
+	return err
+}
+
+// gogitResetPull - `git reset --hard` and `git pull` (fast-forward only) using go-git
+func gogitResetPull(dir string) error {
+	r, err := openGitRepo(dir)
+	if err != nil {
+		return err
+	}
+	defer r.mtx.Unlock()
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	err = wt.Reset(&git.ResetOptions{Mode: git.HardReset})
+	if err != nil {
+		return err
+	}
+	err = wt.Pull(&git.PullOptions{RemoteName: git.DefaultRemoteName})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return err
+}
+
+// GitRenameSimilarity - minimum similarity (percent) of deleted and added file to detect rename, the same as `git diff-tree -M7`
+const GitRenameSimilarity = 70
+
+// gitMaxRenameCandidates - maximum number of deleted x added files pairs compared to detect renames of modified files
+const gitMaxRenameCandidates = 1000
+
+// GitCommitFiles - returns author and committer identity and files changed by `sha` commit of `dir` repository
+// with their sizes, added and removed lines and renames
+// Uses go-git, falls back to `git_files.sh` and `git_commit_stats.sh` when it fails or when GHA2DB_GIT_SHELL is set
+func GitCommitFiles(ctx *Ctx, dir, sha string) (*GitCommit, error) {
+	if !ctx.GitShell {
+		commit, err := gogitCommitFiles(dir, sha)
+		if err == nil {
+			return commit, nil
+		}
+		if ctx.Debug > 1 {
+			Printf("go-git commit files failed: %s:%s: %v, falling back to git_files.sh\n", dir, sha, err)
+		}
+	}
+	return shellCommitFiles(ctx, dir, sha)
+}
+
+// gitBlob - file contents and its binary flag
+type gitBlob struct {
+	contents string
+	binary   bool
+}
+
+// gitLines - returns number of lines in a text
+func gitLines(text string) int {
+	if text == "" {
+		return 0
+	}
+	n := strings.Count(text, "\n")
+	if !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+// gitDiffLines - returns numbers of added and removed lines and similarity (percent of unchanged contents) of two texts
+func gitDiffLines(src, dst string) (added, removed, similarity int) {
+	same := 0
+	for _, d := range diff.Do(src, dst) {
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			added += gitLines(d.Text)
+		case diffmatchpatch.DiffDelete:
+			removed += gitLines(d.Text)
+		default:
+			same += len(d.Text)
+		}
+	}
+	size := len(src)
+	if len(dst) > size {
+		size = len(dst)
+	}
+	if size == 0 {
+		return added, removed, 100
+	}
+	return added, removed, same * 100 / size
+}
+
+// gogitCommitFiles - returns commit identity and changed files using go-git
+// Like `git diff-tree -r -M7` it returns files changed compared to the only parent, merge and root commits have no files
+// Renames of modified files are detected when at least GitRenameSimilarity percent of their lines are unchanged,
+// git uses a different similarity measure, so results can differ for borderline cases
+func gogitCommitFiles(dir, sha string) (*GitCommit, error) {
+	r, err := openGitRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.mtx.Unlock()
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(sha))
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	result := &GitCommit{
+		SHA:            commit.Hash.String(),
+		AuthorName:     commit.Author.Name,
+		AuthorEmail:    commit.Author.Email,
+		AuthorDate:     time.Unix(commit.Author.When.Unix(), 0),
+		CommitterName:  commit.Committer.Name,
+		CommitterEmail: commit.Committer.Email,
+		CommitterDate:  time.Unix(commit.Committer.When.Unix(), 0),
+		Files:          []GitFile{},
+	}
+	if commit.NumParents() != 1 {
+		return result, nil
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blobs contents are read once
+	blobs := make(map[plumbing.Hash]*gitBlob)
+	getBlob := func(hash plumbing.Hash) (*gitBlob, error) {
+		if b, ok := blobs[hash]; ok {
+			return b, nil
+		}
+		blob, err := r.repo.BlobObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(reader)
+		FatalOnError(reader.Close())
+		if err != nil {
+			return nil, err
+		}
+		sniff := data
+		if len(sniff) > 8000 {
+			sniff = sniff[:8000]
+		}
+		b := &gitBlob{contents: string(data), binary: bytes.IndexByte(sniff, 0) >= 0}
+		blobs[hash] = b
+		return b, nil
+	}
+	isBlob := func(entry object.TreeEntry) bool {
+		return entry.Mode != filemode.Submodule && entry.Mode != filemode.Dir
+	}
+
+	// Split changes into deleted, added and modified files
+	var deleted, added, modified []*object.Change
+	for _, change := range changes {
+		switch {
+		case change.To.Name == "":
+			deleted = append(deleted, change)
+		case change.From.Name == "":
+			added = append(added, change)
+		default:
+			modified = append(modified, change)
+		}
+	}
+
+	// Renames: exact first, then modified files with the best similarity
+	renamedFrom := make(map[*object.Change]*object.Change)
+	renamed := make(map[*object.Change]bool)
+	for _, a := range added {
+		for _, d := range deleted {
+			if !renamed[d] && a.To.TreeEntry.Hash == d.From.TreeEntry.Hash {
+				renamedFrom[a], renamed[d] = d, true
+				break
+			}
+		}
+	}
+	nDeleted, nAdded := len(deleted)-len(renamedFrom), len(added)-len(renamedFrom)
+	if nDeleted > 0 && nAdded > 0 && nDeleted*nAdded <= gitMaxRenameCandidates {
+		type candidate struct {
+			a, d       *object.Change
+			similarity int
+		}
+		candidates := []candidate{}
+		for _, a := range added {
+			if renamedFrom[a] != nil || !isBlob(a.To.TreeEntry) {
+				continue
+			}
+			ab, err := getBlob(a.To.TreeEntry.Hash)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range deleted {
+				if renamed[d] || !isBlob(d.From.TreeEntry) {
+					continue
+				}
+				db, err := getBlob(d.From.TreeEntry.Hash)
+				if err != nil {
+					return nil, err
+				}
+				if ab.binary || db.binary {
+					continue
+				}
+				_, _, similarity := gitDiffLines(db.contents, ab.contents)
+				if similarity >= GitRenameSimilarity {
+					candidates = append(candidates, candidate{a: a, d: d, similarity: similarity})
+				}
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].similarity > candidates[j].similarity })
+		for _, c := range candidates {
+			if renamedFrom[c.a] == nil && !renamed[c.d] {
+				renamedFrom[c.a], renamed[c.d] = c.d, true
+			}
+		}
+	}
+
+	// Files with sizes and lines
+	for _, change := range append(append(modified, added...), deleted...) {
+		if renamed[change] {
+			continue
+		}
+		file := GitFile{Path: change.To.Name, Status: "M"}
+		var from, to *object.TreeEntry
+		switch {
+		case change.To.Name == "":
+			file.Path, file.Status, file.Size = change.From.Name, "D", -1
+			from = &change.From.TreeEntry
+		case renamedFrom[change] != nil:
+			file.OldPath, file.Status = renamedFrom[change].From.Name, "R"
+			from, to = &renamedFrom[change].From.TreeEntry, &change.To.TreeEntry
+		case change.From.Name == "":
+			file.Status = "A"
+			to = &change.To.TreeEntry
+		default:
+			from, to = &change.From.TreeEntry, &change.To.TreeEntry
+		}
+		src, dst := &gitBlob{}, &gitBlob{}
+		if from != nil && isBlob(*from) {
+			src, err = getBlob(from.Hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if to != nil {
+			if isBlob(*to) {
+				dst, err = getBlob(to.Hash)
+				if err != nil {
+					return nil, err
+				}
+				file.Size = int64(len(dst.contents))
+			} else {
+				file.Size = -2
+			}
+		}
+		file.Binary = src.binary || dst.binary
+		if !file.Binary {
+			file.Added, file.Removed, _ = gitDiffLines(src.contents, dst.contents)
+		}
+		result.Files = append(result.Files, file)
+	}
+	sort.Slice(result.Files, func(i, j int) bool { return result.Files[i].Path < result.Files[j].Path })
+	return result, nil
+}
+
+// shellCommitFiles - returns commit identity and changed files using `git_files.sh` (sizes) and `git_commit_stats.sh` (identity, lines, renames)
+func shellCommitFiles(ctx *Ctx, dir, sha string) (*GitCommit, error) {
+	// We need this to capture 'git_files.sh' and 'git_commit_stats.sh' output.
+	ctx.ExecOutput = true
+
+	// Get files using shell script that does 'chdir'
+	// We cannot chdir because this is a multithreaded app
+	// And all threads share CWD (current working directory)
+	filesStr, err := ExecCommand(
+		ctx,
+		[]string{gitScriptsPrefix(ctx) + "git_files.sh", dir, sha},
+		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+	)
+	if err != nil {
+		return nil, err
+	}
+	statsStr, err := ExecCommand(
+		ctx,
+		[]string{gitScriptsPrefix(ctx) + "git_commit_stats.sh", dir, sha},
+		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+	)
+	if err != nil {
+		return nil, err
+	}
+	commit, stats, err := parseGitCommitStats(statsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit stats returned for repo: %s, sha: %s: %v", dir, sha, err)
+	}
+	commit.SHA = sha
+	lines := strings.Split(filesStr, "\n")
+	for _, data := range lines[1:] {
+		fileData := strings.TrimSpace(data)
+		if fileData == "" {
+			continue
+		}
+		// Use '♂♀' separator to avoid any character that can appear inside file name
+		fileDataAry := strings.Split(fileData, "♂♀")
+		if len(fileDataAry) != 2 {
+			return nil, fmt.Errorf("invalid file data returned for repo: %s, sha: %s: '%s'", dir, sha, fileData)
+		}
+		// File size returned as "-" from git ls-tree means some special file, directory
+		fileSize, err := strconv.ParseInt(fileDataAry[1], 10, 64)
+		if err != nil {
+			fileSize = -2
+		}
+		file := GitFile{Path: fileDataAry[0], Status: "M", Size: fileSize}
+		if stat, ok := stats[file.Path]; ok {
+			file.OldPath, file.Status, file.Added, file.Removed, file.Binary = stat.OldPath, stat.Status, stat.Added, stat.Removed, stat.Binary
+		}
+		commit.Files = append(commit.Files, file)
+	}
+	return commit, nil
+}
+
+// parseGitCommitStats - parses `git_commit_stats.sh` output:
+// first line: author name, email, date, committer name, email, date separated by '♂♀'
+// then `git diff-tree -r -M7 --numstat -z` and `--name-status -z` output, returns commit identity and file stats by path
+func parseGitCommitStats(output string) (*GitCommit, map[string]GitFile, error) {
+	i := strings.Index(output, "\n")
+	if i < 0 {
+		return nil, nil, fmt.Errorf("no commit identity")
+	}
+	ary := strings.Split(output[:i], "♂♀")
+	if len(ary) != 6 {
+		return nil, nil, fmt.Errorf("invalid commit identity: '%s'", output[:i])
+	}
+	authorDate, err := strconv.ParseInt(ary[2], 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	committerDate, err := strconv.ParseInt(ary[5], 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	commit := &GitCommit{
+		AuthorName:     ary[0],
+		AuthorEmail:    ary[1],
+		AuthorDate:     time.Unix(authorDate, 0),
+		CommitterName:  ary[3],
+		CommitterEmail: ary[4],
+		CommitterDate:  time.Unix(committerDate, 0),
+		Files:          []GitFile{},
+	}
+	stats := make(map[string]GitFile)
+	parts := strings.SplitN(output[i+1:], "♂♀\n", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("no name status separator")
+	}
+
+	// Numstat: "added\tremoved\tpath\0" or "added\tremoved\t\0old path\0new path\0", "-" for binary files
+	tokens := strings.Split(parts[0], "\x00")
+	for j := 0; j < len(tokens); j++ {
+		token := strings.TrimLeft(tokens[j], "\n")
+		if token == "" {
+			continue
+		}
+		fields := strings.SplitN(token, "\t", 3)
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("invalid numstat: '%s'", token)
+		}
+		file := GitFile{Path: fields[2]}
+		if file.Path == "" {
+			if j+2 >= len(tokens) {
+				return nil, nil, fmt.Errorf("invalid numstat rename: '%s'", token)
+			}
+			file.OldPath, file.Path = tokens[j+1], tokens[j+2]
+			j += 2
+		}
+		if fields[0] == "-" {
+			file.Binary = true
+		} else {
+			file.Added, err = strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			file.Removed, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		stats[file.Path] = file
+	}
+
+	// Name status: "status\0path\0" or "Rnnn\0old path\0new path\0"
+	tokens = strings.Split(parts[1], "\x00")
+	for j := 0; j < len(tokens); j++ {
+		status := strings.TrimLeft(tokens[j], "\n")
+		if status == "" {
+			continue
+		}
+		if j+1 >= len(tokens) {
+			return nil, nil, fmt.Errorf("invalid name status: '%s'", status)
+		}
+		path := tokens[j+1]
+		j++
+		if status[0] == 'R' || status[0] == 'C' {
+			if j+1 >= len(tokens) {
+				return nil, nil, fmt.Errorf("invalid name status rename: '%s'", status)
+			}
+			path = tokens[j+1]
+			j++
+		}
+		file := stats[path]
+		file.Status = status[:1]
+		if file.Status == "T" || file.Status == "C" {
+			file.Status = "M"
+		}
+		stats[path] = file
+	}
+	return commit, stats, nil
+}
+
+// GitTags - returns all tags of `dir` repository sorted by name
+// Uses go-git, falls back to `git_tags.sh` when it fails or when GHA2DB_GIT_SHELL is set
+func GitTags(ctx *Ctx, dir string) ([]GitTag, error) {
+	if !ctx.GitShell {
+		tags, err := gogitTags(dir)
+		if err == nil {
+			return tags, nil
+		}
+		if ctx.Debug > 0 {
+			Printf("go-git tags failed: %s: %v, falling back to git_tags.sh\n", dir, err)
+		}
+	}
+	return shellTags(ctx, dir)
+}
+
+// gogitTags - returns tags using go-git
+func gogitTags(dir string) ([]GitTag, error) {
+	r, err := openGitRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.mtx.Unlock()
+	refs, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tags := []GitTag{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		tag := GitTag{Name: ref.Name().Short()}
+		tagObj, err := r.repo.TagObject(ref.Hash())
+		switch err {
+		case nil:
+			tag.Date, tag.Subject = tagObj.Tagger.When, gitSubject(tagObj.Message)
+		case plumbing.ErrObjectNotFound:
+			// Lightweight tag
+			commit, err := r.repo.CommitObject(ref.Hash())
+			if err != nil {
+				return err
+			}
+			tag.Date, tag.Subject = commit.Committer.When, gitSubject(commit.Message)
+		default:
+			return err
+		}
+		tag.Date = time.Unix(tag.Date.Unix(), 0)
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// shellTags - returns tags using `git_tags.sh`
+func shellTags(ctx *Ctx, dir string) ([]GitTag, error) {
+	// We need this to capture 'git_tags.sh' output.
+	ctx.ExecOutput = true
+
+	// Get tags is using shell script that does 'chdir'
+	// We cannot chdir because this is a multithreaded app
+	// And all threads share CWD (current working directory)
+	tagsStr, err := ExecCommand(
+		ctx,
+		[]string{gitScriptsPrefix(ctx) + "git_tags.sh", dir},
+		map[string]string{"GIT_TERMINAL_PROMPT": "0"},
+	)
+	if err != nil {
+		return nil, err
+	}
+	tags := []GitTag{}
+	for _, tagData := range strings.Split(tagsStr, "\n") {
+		data := strings.TrimSpace(tagData)
+		if data == "" {
+			continue
+		}
+		// Use '♂♀' separator to avoid any character that can appear inside tag name or description
+		tagDataAry := strings.Split(data, "♂♀")
+		if len(tagDataAry) != 3 {
+			return nil, fmt.Errorf("invalid tag data returned for repo: %s: '%s'", dir, data)
+		}
+		unixTimeStamp, err := strconv.ParseInt(tagDataAry[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time returned for repo: %s, tag: %s: '%s'", dir, tagDataAry[0], data)
+		}
+		tags = append(tags, GitTag{Name: tagDataAry[0], Date: time.Unix(unixTimeStamp, 0), Subject: tagDataAry[2]})
+	}
+	return tags, nil
+}
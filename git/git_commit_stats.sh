@@ -0,0 +1,17 @@
+#!/bin/bash
+if [ -z "$1" ]
+then
+  echo "Arguments required: path sha, none given"
+  exit 1
+fi
+if [ -z "$2" ]
+then
+  echo "Arguments required: path sha, only path given"
+  exit 2
+fi
+
+cd "$1" || exit 3
+git show -s --format="%an♂♀%ae♂♀%at♂♀%cn♂♀%ce♂♀%ct" "$2" || exit 4
+git diff-tree --no-commit-id -M7 -r --numstat -z "$2" || exit 5
+echo "♂♀"
+git diff-tree --no-commit-id -M7 -r --name-status -z "$2" || exit 6
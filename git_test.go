@@ -0,0 +1,179 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	lib "devstats"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// gitTestRepo - creates test repository in a temporary directory, returns its path and commit SHAs
+func gitTestRepo(t *testing.T) (string, []string) {
+	dir, err := ioutil.TempDir("", "devstats_git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	shas := []string{}
+	commit := func(day int, message string, write map[string]string, remove []string) {
+		for file, contents := range write {
+			path := filepath.Join(dir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := wt.Add(file); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		for _, file := range remove {
+			if _, err := wt.Remove(file); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		sig := &object.Signature{Name: "John", Email: "john@example.com", When: time.Date(2018, 3, day, 12, 0, 0, 0, time.UTC)}
+		hash, err := wt.Commit(message, &git.CommitOptions{Author: sig})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		shas = append(shas, hash.String())
+	}
+	commit(1, "Initial commit", map[string]string{"a.txt": "aaa", "dir/b.txt": "bbbbb"}, nil)
+	commit(2, "Second commit\n\nWith description", map[string]string{"a.txt": "aaaaaa", "empty.txt": ""}, []string{"dir/b.txt"})
+	commit(3, "Rename", map[string]string{"c.txt": "aaaaaa"}, []string{"a.txt"})
+	commit(4, "Odd names", map[string]string{"file with spaces.txt": "x", "♂♀.txt": "yy"}, nil)
+	big := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	commit(5, "Binary", map[string]string{"big.txt": big, "bin.dat": "\x00\x01"}, nil)
+	commit(6, "Move", map[string]string{"moved/big.txt": strings.Replace(big, "5\n", "five\n", 1), "bin.dat": "\x00\x02\x03"}, []string{"big.txt"})
+
+	// Tags: lightweight and annotated
+	if _, err = repo.CreateTag("v1.0.0", plumbing.NewHash(shas[0]), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tagger := &object.Signature{Name: "John", Email: "john@example.com", When: time.Date(2018, 3, 5, 12, 0, 0, 0, time.UTC)}
+	opts := &git.CreateTagOptions{Tagger: tagger, Message: "Release v1.1.0\nsecond line\n\nDetails"}
+	if _, err = repo.CreateTag("v1.1.0", plumbing.NewHash(shas[1]), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return dir, shas
+}
+
+func TestGitCommitFiles(t *testing.T) {
+	dir, shas := gitTestRepo(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Compare with git shell scripts when git is available
+	modes := []bool{false}
+	if _, err := exec.LookPath("git"); err == nil {
+		modes = append(modes, true)
+	}
+
+	// Test cases
+	var testCases = []struct {
+		sha       int
+		gogitOnly bool
+		day       int
+		expected  []lib.GitFile
+	}{
+		{sha: 0, day: 1, expected: []lib.GitFile{}},
+		{
+			sha: 1,
+			day: 2,
+			expected: []lib.GitFile{
+				{Path: "a.txt", Status: "M", Size: 6, Added: 1, Removed: 1},
+				{Path: "dir/b.txt", Status: "D", Size: -1, Removed: 1},
+				{Path: "empty.txt", Status: "A", Size: 0},
+			},
+		},
+		{sha: 2, day: 3, expected: []lib.GitFile{{Path: "c.txt", OldPath: "a.txt", Status: "R", Size: 6}}},
+		{
+			sha:       3,
+			gogitOnly: true,
+			day:       4,
+			expected: []lib.GitFile{
+				{Path: "file with spaces.txt", Status: "A", Size: 1, Added: 1},
+				{Path: "♂♀.txt", Status: "A", Size: 2, Added: 1},
+			},
+		},
+		{
+			sha: 5,
+			day: 6,
+			expected: []lib.GitFile{
+				{Path: "bin.dat", Status: "M", Size: 3, Binary: true},
+				{Path: "moved/big.txt", OldPath: "big.txt", Status: "R", Size: 24, Added: 1, Removed: 1},
+			},
+		},
+	}
+	// Execute test cases
+	for _, shell := range modes {
+		ctx := lib.Ctx{Local: true, GitShell: shell}
+		for index, test := range testCases {
+			if shell && test.gogitOnly {
+				continue
+			}
+			commit, err := lib.GitCommitFiles(&ctx, dir, shas[test.sha])
+			if err != nil {
+				t.Errorf("test number %d (shell: %v), unexpected error: %v", index+1, shell, err)
+				continue
+			}
+			expectedDt := time.Date(2018, 3, test.day, 12, 0, 0, 0, time.UTC)
+			if !commit.CommitterDate.Equal(expectedDt) || !commit.AuthorDate.Equal(expectedDt) ||
+				commit.AuthorName != "John" || commit.CommitterEmail != "john@example.com" ||
+				!reflect.DeepEqual(commit.Files, test.expected) {
+				t.Errorf(
+					"test number %d (shell: %v), expected %v %+v, got %+v",
+					index+1, shell, expectedDt, test.expected, commit,
+				)
+			}
+		}
+	}
+}
+
+func TestGitTags(t *testing.T) {
+	dir, _ := gitTestRepo(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Compare with git shell scripts when git is available
+	modes := []bool{false}
+	if _, err := exec.LookPath("git"); err == nil {
+		modes = append(modes, true)
+	}
+	expected := []lib.GitTag{
+		{Name: "v1.0.0", Date: time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC), Subject: "Initial commit"},
+		{Name: "v1.1.0", Date: time.Date(2018, 3, 5, 12, 0, 0, 0, time.UTC), Subject: "Release v1.1.0 second line"},
+	}
+	for _, shell := range modes {
+		ctx := lib.Ctx{Local: true, GitShell: shell}
+		tags, err := lib.GitTags(&ctx, dir)
+		if err != nil {
+			t.Errorf("shell: %v, unexpected error: %v", shell, err)
+			continue
+		}
+		ok := len(tags) == len(expected)
+		for i := 0; ok && i < len(tags); i++ {
+			ok = tags[i].Name == expected[i].Name && tags[i].Date.Equal(expected[i].Date) && tags[i].Subject == expected[i].Subject
+		}
+		if !ok {
+			t.Errorf("shell: %v, expected %+v, got %+v", shell, expected, tags)
+		}
+	}
+}
@@ -37,6 +37,7 @@ func IDBAddPointN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN, pt *cli
 
 // IDBWritePointsN - writes batch points
 func IDBWritePointsN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err error) {
+	defer PromObserveSince("devstats_influx_write_duration_seconds", "InfluxDB batch points write duration.", PromQueryBuckets, time.Now())
 	for idx, bp := range points.fullBatches {
 		if ctx.Debug > 0 {
 			Printf("Batch #%d: writing %d points\n", idx+1, ctx.IDBMaxBatchPoints)
@@ -83,6 +84,7 @@ func IDBWritePointsN(ctx *Ctx, con *client.Client, points *IDBBatchPointsN) (err
 		Printf("10 trials failed\n.")
 		return err
 	}
+	PromAdd("devstats_influx_points_written_total", "InfluxDB points written.", float64(len(points.fullBatches)*ctx.IDBMaxBatchPoints+points.NPoints))
 	return nil
 }
 
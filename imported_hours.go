@@ -0,0 +1,100 @@
+package devstats
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ImportStarted - GHA hour import started (and not finished yet, or crashed)
+const ImportStarted string = "started"
+
+// ImportOK - GHA hour imported successfully
+const ImportOK string = "ok"
+
+// ImportFailed - GHA hour import failed (for example error while reading GHA archive)
+const ImportFailed string = "failed"
+
+// ImportNoData - GHA archive for this hour was not available
+const ImportNoData string = "nodata"
+
+// ImportNoDataGrace - hours marked as `nodata` are retried unless that status was set at least this long after the hour
+// GHA archives are often published with a delay, so a missing archive is only considered final after this period
+const ImportNoDataGrace time.Duration = 48 * time.Hour
+
+// ImportedHoursTableExists - checks (once per process) if `gha_imported_hours` table exists
+// Databases created before this table was introduced need util_sql/imported_hours_table.sql migration
+// Without it import checkpoints are not recorded and all hours are considered missing
+func ImportedHoursTableExists(con *sql.DB, ctx *Ctx) bool {
+	return TableExists(con, ctx, "gha_imported_hours", "imported_hours_table.sql", "import checkpoints are disabled")
+}
+
+// SetImportedHour - record GHA hour import status in `gha_imported_hours` table
+// jsons is number of JSONs read, found is number of matching JSONs, events is number of events written
+func SetImportedHour(con *sql.DB, ctx *Ctx, dt time.Time, status string, jsons, found, events int) {
+	if !ImportedHoursTableExists(con, ctx) {
+		return
+	}
+	ExecSQLWithErr(
+		con,
+		ctx,
+		"insert into gha_imported_hours(dt, status, jsons, found, events, updated_at) "+NValues(6)+
+			" on conflict(dt) do update set status = excluded.status, jsons = excluded.jsons, "+
+			"found = excluded.found, events = excluded.events, updated_at = excluded.updated_at",
+		HourStart(dt),
+		status,
+		jsons,
+		found,
+		events,
+		time.Now().UTC(),
+	)
+}
+
+// GetImportedHours - return set of hours from `gha_imported_hours` in [from, to] range that don't need to be imported again
+// Successfully imported hours and hours for which there was still no GHA archive ImportNoDataGrace after the hour are returned
+func GetImportedHours(con *sql.DB, ctx *Ctx, from, to time.Time) map[time.Time]struct{} {
+	imported := make(map[time.Time]struct{})
+	if !ImportedHoursTableExists(con, ctx) {
+		return imported
+	}
+	rows := QuerySQLWithErr(
+		con,
+		ctx,
+		"select dt from gha_imported_hours where dt >= $1 and dt <= $2 and "+
+			"(status = $3 or (status = $4 and updated_at >= dt + $5::int * interval '1 hour'))",
+		HourStart(from),
+		HourStart(to),
+		ImportOK,
+		ImportNoData,
+		int(ImportNoDataGrace/time.Hour),
+	)
+	defer func() { FatalOnError(rows.Close()) }()
+	var dt time.Time
+	for rows.Next() {
+		FatalOnError(rows.Scan(&dt))
+		imported[HourStart(dt)] = struct{}{}
+	}
+	FatalOnError(rows.Err())
+	return imported
+}
+
+// MissingHours - return all hours in [from, to] range that are not in the `imported` set
+func MissingHours(from, to time.Time, imported map[time.Time]struct{}) (missing []time.Time) {
+	to = HourStart(to)
+	for dt := HourStart(from); dt.Before(to) || dt.Equal(to); dt = dt.Add(time.Hour) {
+		if _, ok := imported[dt]; !ok {
+			missing = append(missing, dt)
+		}
+	}
+	return
+}
+
+// FirstImportedHour - return the oldest hour recorded in `gha_imported_hours` or nil if there are no records (or no table)
+// Hours imported before checkpoints were introduced have no records, so holes are only searched after this date
+func FirstImportedHour(con *sql.DB, ctx *Ctx) *time.Time {
+	var dt *time.Time
+	if !ImportedHoursTableExists(con, ctx) {
+		return dt
+	}
+	FatalOnError(QueryRowSQL(con, ctx, "select min(dt) from gha_imported_hours").Scan(&dt))
+	return dt
+}
@@ -0,0 +1,64 @@
+package devstats
+
+import (
+	"testing"
+	"time"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestMissingHours(t *testing.T) {
+	ft := testlib.YMDHMS
+	var testCases = []struct {
+		from     time.Time
+		to       time.Time
+		imported map[time.Time]struct{}
+		expected []time.Time
+	}{
+		{
+			from:     ft(2018, 1, 1, 10),
+			to:       ft(2018, 1, 1, 12),
+			imported: map[time.Time]struct{}{},
+			expected: []time.Time{ft(2018, 1, 1, 10), ft(2018, 1, 1, 11), ft(2018, 1, 1, 12)},
+		},
+		{
+			from:     ft(2018, 1, 1, 10, 30),
+			to:       ft(2018, 1, 1, 12, 15),
+			imported: map[time.Time]struct{}{ft(2018, 1, 1, 11): {}},
+			expected: []time.Time{ft(2018, 1, 1, 10), ft(2018, 1, 1, 12)},
+		},
+		{
+			from:     ft(2018, 1, 1, 23),
+			to:       ft(2018, 1, 2, 1),
+			imported: map[time.Time]struct{}{ft(2018, 1, 1, 23): {}, ft(2018, 1, 2): {}, ft(2018, 1, 2, 1): {}},
+			expected: []time.Time{},
+		},
+		{
+			from:     ft(2018, 1, 1, 12),
+			to:       ft(2018, 1, 1, 10),
+			imported: map[time.Time]struct{}{},
+			expected: []time.Time{},
+		},
+		{
+			from:     ft(2018, 1, 1, 12),
+			to:       ft(2018, 1, 1, 12),
+			imported: map[time.Time]struct{}{ft(2018, 1, 1, 11): {}},
+			expected: []time.Time{ft(2018, 1, 1, 12)},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.MissingHours(test.from, test.to, test.imported)
+		if len(got) != len(test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(test.expected[i]) {
+				t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+				break
+			}
+		}
+	}
+}
@@ -114,4 +114,9 @@ func ClearDBLogs() {
 	// Clear logs older that defined period
 	fmt.Printf("Clearing old DB logs.\n")
 	ExecSQLWithErr(c, &ctx, "delete from gha_logs where dt < now() - '"+ctx.ClearDBPeriod+"'::interval")
+
+	// Clear sync runs history older than defined period
+	// Sync runs tables may not exist yet (they're optional like the history itself), so ignore errors
+	_, _ = ExecSQL(c, &ctx, "delete from gha_sync_phases where started_at < now() - '"+ctx.ClearDBPeriod+"'::interval")
+	_, _ = ExecSQL(c, &ctx, "delete from gha_sync_runs where started_at < now() - '"+ctx.ClearDBPeriod+"'::interval")
 }
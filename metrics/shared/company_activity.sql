@@ -25,11 +25,9 @@ from (
     sum(case ev.type when 'CommitCommentEvent' then 1 else 0 end) as commit_comments
   from
     gha_events ev,
-    gha_actors_affiliations affs
+    gha_events_companies affs
   where
-    ev.actor_id = affs.actor_id
-    and affs.dt_from <= ev.created_at
-    and affs.dt_to > ev.created_at
+    affs.event_id = ev.id
     and ev.created_at >= '{{from}}'
     and ev.created_at < '{{to}}'
     and ev.type in (
@@ -51,7 +49,7 @@ from (
     sum(case ev.type when 'IssueCommentEvent' then 1 else 0 end) as issue_comments,
     sum(case ev.type when 'CommitCommentEvent' then 1 else 0 end) as commit_comments
   from
-    gha_actors_affiliations affs,
+    gha_events_companies affs,
     gha_repos r,
     gha_events ev
   left join
@@ -60,9 +58,7 @@ from (
     ecf.event_id = ev.id
   where
     r.id = ev.repo_id
-    and ev.actor_id = affs.actor_id
-    and affs.dt_from <= ev.created_at
-    and affs.dt_to > ev.created_at
+    and affs.event_id = ev.id
     and ev.created_at >= '{{from}}'
     and ev.created_at < '{{to}}'
     and ev.type in (
@@ -6,11 +6,9 @@ select
 from
   gha_events ev
 left join
-  gha_actors_affiliations affs
+  gha_events_companies affs
 on
-  ev.actor_id = affs.actor_id
-  and affs.dt_from <= ev.created_at
-  and affs.dt_to > ev.created_at
+  affs.event_id = ev.id
 where
   ev.created_at >= '{{from}}'
   and ev.created_at < '{{to}}'
@@ -26,11 +24,9 @@ from
   gha_repos r,
   gha_events ev
 left join
-  gha_actors_affiliations affs
+  gha_events_companies affs
 on
-  ev.actor_id = affs.actor_id
-  and affs.dt_from <= ev.created_at
-  and affs.dt_to > ev.created_at
+  affs.event_id = ev.id
 where
   r.name = ev.dup_repo_name
   and r.repo_group is not null
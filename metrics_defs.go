@@ -0,0 +1,102 @@
+package devstats
+
+// Series name functions (`series_name_or_func:` in metrics.yaml) handled by `db2influx`
+const (
+	SingleRowMultiColumn string = "single_row_multi_column"
+	MultiRowSingleColumn string = "multi_row_single_column"
+	MultiRowMultiColumn  string = "multi_row_multi_column"
+)
+
+// SeriesNameFuncs - all series name functions, any other `series_name_or_func:` value is a series name
+var SeriesNameFuncs = map[string]struct{}{
+	SingleRowMultiColumn: {},
+	MultiRowSingleColumn: {},
+	MultiRowMultiColumn:  {},
+}
+
+// AllGaps contain list of metrics to fill gaps (gaps.yaml)
+type AllGaps struct {
+	Metrics []MetricGap `yaml:"metrics"`
+}
+
+// MetricGap conain list of series names and periods to fill gaps
+// Series formula allows writing a lot of series name in a shorter way
+// Say we have series in this form prefix_{x}_{y}_{z}_suffix
+// and {x} can be a,b,c,d, {y} can be 1,2,3, z can be yes,no
+// Instead of listing all combinations prefix_a_1_yes_suffix, ..., prefix_d_3_no_suffix
+// Which is 4 * 3 * 2 = 24 items, You can write series formula:
+// "=prefix;suffix;_;a,b,c,d;1,2,3;yes,no"
+// format is "=prefix;suffix;join;list1item1,list1item2,...;list2item1,list2item2,...;..."
+// Values can be set the same way as Series, it is the array of series properties to clear
+// If not specified, ["value"] is assumed - it is used for multi-value series
+type MetricGap struct {
+	Name      string   `yaml:"name"`
+	Series    []string `yaml:"series"`
+	Periods   string   `yaml:"periods"`
+	Aggregate string   `yaml:"aggregate"`
+	Skip      string   `yaml:"skip"`
+	Desc      bool     `yaml:"desc"`
+	Values    []string `yaml:"values"`
+}
+
+// AllMetrics contain list of metrics to evaluate (metrics.yaml)
+type AllMetrics struct {
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Metric contain each metric data
+type Metric struct {
+	Name              string `yaml:"name"`
+	Periods           string `yaml:"periods"`
+	SeriesNameOrFunc  string `yaml:"series_name_or_func"`
+	MetricSQL         string `yaml:"sql"`
+	AddPeriodToName   bool   `yaml:"add_period_to_name"`
+	Histogram         bool   `yaml:"histogram"`
+	Aggregate         string `yaml:"aggregate"`
+	Skip              string `yaml:"skip"`
+	Desc              string `yaml:"desc"`
+	MultiValue        bool   `yaml:"multi_value"`
+	EscapeValueName   bool   `yaml:"escape_value_name"`
+	AnnotationsRanges bool   `yaml:"annotations_ranges"`
+}
+
+// AllTags contain list of InfluxDB tags (idb_tags.yaml)
+type AllTags struct {
+	Tags []Tag `yaml:"tags"`
+}
+
+// Tag contain each InfluxDB tag data
+type Tag struct {
+	Name       string `yaml:"name"`
+	SQLFile    string `yaml:"sql"`
+	SeriesName string `yaml:"series_name"`
+	NameTag    string `yaml:"name_tag"`
+	ValueTag   string `yaml:"value_tag"`
+}
+
+// AllIVars contain list of InfluxDB tag/value pairs (idb_vars.yaml)
+type AllIVars struct {
+	Vars []IVar `yaml:"vars"`
+}
+
+// IVar contain each InfluxDB tag data
+type IVar struct {
+	Tag     string   `yaml:"tag"`
+	Name    string   `yaml:"name"`
+	Value   string   `yaml:"value"`
+	Command []string `yaml:"command"`
+}
+
+// AllPVars contain list of Postgres variables to set (pdb_vars.yaml)
+type AllPVars struct {
+	Vars []PVar `yaml:"vars"`
+}
+
+// PVar contain each Postgres data
+type PVar struct {
+	Name     string     `yaml:"name"`
+	Type     string     `yaml:"type"`
+	Value    string     `yaml:"value"`
+	Command  []string   `yaml:"command"`
+	Replaces [][]string `yaml:"replaces"`
+}
@@ -0,0 +1,401 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LintIssue - single problem found in metrics definition files
+// Warnings are reported but don't make `metrics_lint` fail
+type LintIssue struct {
+	File    string
+	Item    string
+	Message string
+	Warning bool
+}
+
+// String - issue as a single line of text
+func (i LintIssue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	if i.Item == "" {
+		return fmt.Sprintf("%s: %s: %s", level, i.File, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", level, i.File, i.Item, i.Message)
+}
+
+// linter - collects issues found in a single file
+type linter struct {
+	file   string
+	issues []LintIssue
+}
+
+// errorf - adds an error
+func (l *linter) errorf(item, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{File: l.file, Item: item, Message: fmt.Sprintf(format, args...)})
+}
+
+// warnf - adds a warning
+func (l *linter) warnf(item, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{File: l.file, Item: item, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// unmarshal - strict YAML parse, unknown keys (typos) are errors
+func (l *linter) unmarshal(data []byte, out interface{}) bool {
+	err := yaml.UnmarshalStrict(data, out)
+	if err != nil {
+		l.errorf("", "%v", err)
+		return false
+	}
+	return true
+}
+
+// periods - checks `periods`, `aggregate` and `skip` the same way `gha2db_sync` uses them
+func (l *linter) periods(item, periods, aggregate, skip string) {
+	periodsArr := []string{}
+	if periods == "" {
+		l.errorf(item, "no periods given")
+	} else {
+		for _, period := range strings.Split(periods, ",") {
+			if period == "" {
+				l.errorf(item, "empty period in '%s'", periods)
+				continue
+			}
+			interval, _, _, _, _ := GetIntervalFunctions(period[0:1], true)
+			if interval == "" {
+				l.errorf(item, "unknown period '%s', allowed: h, d, w, m, q, y with optional number", period)
+				continue
+			}
+			if len(period) > 1 {
+				n, err := strconv.Atoi(period[1:])
+				if err != nil || n < 1 {
+					l.errorf(item, "period '%s' must be a period letter followed by a positive number", period)
+					continue
+				}
+			}
+			periodsArr = append(periodsArr, period)
+		}
+	}
+	if aggregate == "" {
+		aggregate = "1"
+	}
+	aggrSuffixes := []string{}
+	for _, aggrStr := range strings.Split(aggregate, ",") {
+		aggr, err := strconv.Atoi(aggrStr)
+		if err != nil || aggr < 1 {
+			l.errorf(item, "aggregate '%s' is not a positive integer", aggrStr)
+			continue
+		}
+		if aggr == 1 {
+			aggrStr = ""
+		}
+		aggrSuffixes = append(aggrSuffixes, aggrStr)
+	}
+	if skip == "" {
+		return
+	}
+	periodAggrs := make(map[string]struct{})
+	for _, aggrSuffix := range aggrSuffixes {
+		for _, period := range periodsArr {
+			periodAggrs[period+aggrSuffix] = struct{}{}
+		}
+	}
+	for _, periodAggr := range strings.Split(skip, ",") {
+		if _, ok := periodAggrs[periodAggr]; !ok {
+			l.warnf(item, "skip '%s' doesn't match any period and aggregate combination", periodAggr)
+		}
+	}
+}
+
+// sql - reads SQL file and renders it using sample variables values (the same variables as the tool using it)
+func (l *linter) sql(ctx *Ctx, dataPrefix, item, path string, vars *SQLVars) (string, string, bool) {
+	data, err := ReadFile(ctx, path)
+	if err != nil {
+		l.errorf(item, "cannot read SQL file: %v", err)
+		return "", "", false
+	}
+	sqlQuery := string(data)
+	rendered, err := RenderSQL(ctx, dataPrefix, sqlQuery, vars)
+	if err != nil {
+		l.errorf(item, "SQL file %s: %v", path, err)
+		return "", "", false
+	}
+	return sqlQuery, rendered, true
+}
+
+// MetricsLinter - checks metrics definition files of a single project: `metrics.yaml`, `gaps.yaml`, `idb_tags.yaml`, `idb_vars.yaml` and `pdb_vars.yaml`
+// SQL files are rendered with sample variables values: the last week or weekly period
+// When Con is set, EXPLAIN is executed for every rendered SQL query
+type MetricsLinter struct {
+	Ctx        *Ctx
+	DataPrefix string
+	Con        *sql.DB
+	now        time.Time
+}
+
+// LintProject - checks all definition files of ctx.Project, file paths are taken from ctx
+func (m *MetricsLinter) LintProject() (issues []LintIssue) {
+	m.now = time.Now()
+	files := []struct {
+		path string
+		lint func(string, []byte) []LintIssue
+	}{
+		{m.Ctx.MetricsYaml, m.LintMetrics},
+		{m.Ctx.GapsYaml, LintGaps},
+		{m.Ctx.TagsYaml, m.LintTags},
+		{m.Ctx.IVarsYaml, LintIVars},
+		{m.Ctx.PVarsYaml, LintPVars},
+	}
+	for _, file := range files {
+		path := m.DataPrefix + file.path
+		data, err := ReadFile(m.Ctx, path)
+		if err != nil {
+			issues = append(issues, LintIssue{File: path, Message: fmt.Sprintf("cannot read: %v", err)})
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && m.Ctx.Project != "" {
+			path = strings.Replace(path, "/"+m.Ctx.Project+"/", "/shared/", -1)
+		}
+		issues = append(issues, file.lint(path, data)...)
+	}
+	return
+}
+
+// sqlPath - returns project's SQL file path, ReadFile falls back to metrics/shared/
+func (m *MetricsLinter) sqlPath(name string) string {
+	dir := Metrics
+	if m.Ctx.Project != "" {
+		dir += m.Ctx.Project + "/"
+	}
+	return m.DataPrefix + dir + name + ".sql"
+}
+
+// LintMetrics - checks `metrics.yaml` definitions used by `gha2db_sync` and `db2influx`
+func (m *MetricsLinter) LintMetrics(file string, data []byte) []LintIssue {
+	l := linter{file: file}
+	var allMetrics AllMetrics
+	if !l.unmarshal(data, &allMetrics) {
+		return l.issues
+	}
+	for i, metric := range allMetrics.Metrics {
+		item := fmt.Sprintf("metric #%d '%s'", i+1, metric.Name)
+		if metric.Name == "" {
+			l.errorf(item, "no name given")
+		}
+		if metric.AnnotationsRanges {
+			if !metric.Histogram {
+				l.errorf(item, "annotations_ranges can only be used for histogram metrics")
+			}
+			if metric.Periods != "" || metric.Aggregate != "" || metric.Skip != "" {
+				l.warnf(item, "periods, aggregate and skip are ignored, annotations_ranges metrics are computed for quick ranges")
+			}
+		} else {
+			l.periods(item, metric.Periods, metric.Aggregate, metric.Skip)
+		}
+		if metric.SeriesNameOrFunc == "" {
+			l.errorf(item, "no series_name_or_func given")
+		} else if _, ok := SeriesNameFuncs[metric.SeriesNameOrFunc]; !ok {
+			if strings.Contains(metric.SeriesNameOrFunc, "_row") || strings.Contains(metric.SeriesNameOrFunc, "_column") {
+				l.warnf(item, "series_name_or_func '%s' looks like a misspelled series name function", metric.SeriesNameOrFunc)
+			}
+			if metric.MultiValue || metric.EscapeValueName {
+				l.warnf(item, "multi_value and escape_value_name are only used by series name functions")
+			}
+		}
+		if _, err := ValueDescs(metric.Desc); err != nil {
+			l.errorf(item, "%v", err)
+		}
+		if metric.MetricSQL == "" {
+			l.errorf(item, "no sql given")
+			continue
+		}
+		vars := MetricSQLVars(m.now.AddDate(0, 0, -7), m.now, 1)
+		if metric.AnnotationsRanges {
+			vars = QuickRangeSQLVars("1 week", "", "")
+		} else if metric.Histogram {
+			vars = HistogramSQLVars("week", 1)
+		}
+		path := m.sqlPath(metric.MetricSQL)
+		sqlQuery, rendered, ok := l.sql(m.Ctx, m.DataPrefix, item, path, vars)
+		if !ok {
+			continue
+		}
+		if !metric.Histogram && (!strings.Contains(sqlQuery, "{{from}}") || !strings.Contains(sqlQuery, "{{to}}")) {
+			l.warnf(item, "SQL file %s doesn't use {{from}} and {{to}}, all periods will have the same values", path)
+		}
+		if metric.AnnotationsRanges && !strings.Contains(sqlQuery, "{{period:") {
+			l.warnf(item, "SQL file %s doesn't use {{period:column}}, all quick ranges will have the same values", path)
+		}
+		m.explain(&l, item, path, rendered)
+	}
+	return l.issues
+}
+
+// LintGaps - checks `gaps.yaml` definitions used by `gha2db_sync`
+func LintGaps(file string, data []byte) []LintIssue {
+	l := linter{file: file}
+	var allGaps AllGaps
+	if !l.unmarshal(data, &allGaps) {
+		return l.issues
+	}
+	for i, gap := range allGaps.Metrics {
+		item := fmt.Sprintf("gap #%d '%s'", i+1, gap.Name)
+		if gap.Name == "" {
+			l.errorf(item, "no name given")
+		}
+		l.periods(item, gap.Periods, gap.Aggregate, gap.Skip)
+		if len(gap.Series) == 0 {
+			l.errorf(item, "no series given")
+		}
+		formulas := append([]string{}, gap.Series...)
+		formulas = append(formulas, gap.Values...)
+		for _, formula := range formulas {
+			if formula == "" {
+				l.errorf(item, "empty series or value name")
+			} else if formula[0:1] == "=" && len(strings.Split(formula[1:], ";")) < 4 {
+				l.errorf(item, "series formula '%s' must have at least 4 parameters: prefix, suffix, join, list", formula)
+			}
+		}
+	}
+	return l.issues
+}
+
+// LintTags - checks `idb_tags.yaml` definitions used by `idb_tags`
+func (m *MetricsLinter) LintTags(file string, data []byte) []LintIssue {
+	l := linter{file: file}
+	var allTags AllTags
+	if !l.unmarshal(data, &allTags) {
+		return l.issues
+	}
+	for i, tag := range allTags.Tags {
+		item := fmt.Sprintf("tag #%d '%s'", i+1, tag.Name)
+		if tag.Name == "" {
+			l.errorf(item, "no name given")
+		}
+		if tag.SeriesName == "" || tag.NameTag == "" {
+			l.errorf(item, "series_name and name_tag are required")
+		}
+		if tag.SQLFile == "" {
+			l.errorf(item, "no sql given")
+			continue
+		}
+		path := m.sqlPath(tag.SQLFile)
+		_, rendered, ok := l.sql(m.Ctx, m.DataPrefix, item, path, TagsSQLVars(69))
+		if !ok {
+			continue
+		}
+		m.explain(&l, item, path, rendered)
+	}
+	return l.issues
+}
+
+// LintIVars - checks `idb_vars.yaml` definitions used by `idb_vars`
+func LintIVars(file string, data []byte) []LintIssue {
+	l := linter{file: file}
+	var allVars AllIVars
+	if !l.unmarshal(data, &allVars) {
+		return l.issues
+	}
+	for i, va := range allVars.Vars {
+		item := fmt.Sprintf("var #%d '%s'", i+1, va.Name)
+		if va.Tag == "" || va.Name == "" {
+			l.errorf(item, "tag and name are required")
+		}
+		if va.Value == "" && len(va.Command) == 0 {
+			l.errorf(item, "value or command is required")
+		}
+	}
+	return l.issues
+}
+
+// LintPVars - checks `pdb_vars.yaml` definitions used by `pdb_vars`
+func LintPVars(file string, data []byte) []LintIssue {
+	l := linter{file: file}
+	var allVars AllPVars
+	if !l.unmarshal(data, &allVars) {
+		return l.issues
+	}
+	for i, va := range allVars.Vars {
+		item := fmt.Sprintf("var #%d '%s'", i+1, va.Name)
+		if va.Name == "" {
+			l.errorf(item, "no name given")
+		}
+		switch va.Type {
+		case "i", "f", "s", "dt":
+		default:
+			l.errorf(item, "unknown type '%s', allowed: i, f, s, dt (gha_vars value_* columns)", va.Type)
+		}
+		if va.Value == "" && len(va.Command) == 0 {
+			l.errorf(item, "value or command is required")
+		}
+		for _, repl := range va.Replaces {
+			if len(repl) != 2 {
+				l.errorf(item, "replacement definition should be array with 2 elements, got: %v", repl)
+			}
+		}
+	}
+	return l.issues
+}
+
+// lintStatementsRe - statements separator, semicolon at the end of line (names like 'prefix;name' use semicolons too)
+var lintStatementsRe = regexp.MustCompile(`;[ \t]*(\r?\n|$)`)
+
+// lintCreateAsRe - matches "as" followed by a query of "create table|materialized view ... as" statement
+var lintCreateAsRe = regexp.MustCompile(`\bas\s*\(?\s*(select|with|values)\b`)
+
+// ExplainQueries - returns queries used to check a single statement (in a transaction that is rolled back), nil means statement is skipped
+// Queries (select, with) and data changes (insert, update, delete) are only explained
+// Tables created from queries (create ... as select) are explained and then created "with no data", so next statements can use them
+func ExplainQueries(stmt string) []string {
+	lower := strings.ToLower(stmt)
+	switch {
+	case strings.HasPrefix(lower, "create") && lintCreateAsRe.MatchString(lower):
+		return []string{"explain " + stmt, stmt + " with no data"}
+	case strings.HasPrefix(lower, "select") || strings.HasPrefix(lower, "with") || strings.HasPrefix(lower, "("):
+		return []string{"explain " + stmt}
+	case strings.HasPrefix(lower, "insert") || strings.HasPrefix(lower, "update") || strings.HasPrefix(lower, "delete"):
+		return []string{"explain " + stmt}
+	}
+	return nil
+}
+
+// explain - executes EXPLAIN for all statements of a query in a transaction that is rolled back
+// Temporary tables are created "with no data", so statements using them can be explained too
+// Other statements are never executed: data changes (insert, update, delete) are only explained, other ones are skipped
+func (m *MetricsLinter) explain(l *linter, item, path, sqlQuery string) {
+	if m.Con == nil {
+		return
+	}
+	tx, err := m.Con.Begin()
+	FatalOnError(err)
+	defer func() { _ = tx.Rollback() }()
+	for _, stmt := range lintStatementsRe.Split(sqlQuery, -1) {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		queries := ExplainQueries(stmt)
+		if queries == nil && m.Ctx.Debug > 0 {
+			Printf("%s: skipping statement: %s\n", path, stmt)
+		}
+		for _, query := range queries {
+			rows, err := tx.Query(query)
+			if err == nil {
+				err = rows.Close()
+			}
+			if err != nil {
+				l.errorf(item, "EXPLAIN of %s failed on '%s' database: %v", path, m.Ctx.PgDB, err)
+				return
+			}
+		}
+	}
+}
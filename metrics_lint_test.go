@@ -0,0 +1,178 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestLintGaps(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		yaml     string
+		expected []string
+	}{
+		{
+			yaml:     "metrics:\n- name: g\n  periods: d,w7\n  aggregate: 1,7\n  skip: w7,d7\n  series: [a, '=p;s;_;x,y']\n",
+			expected: nil,
+		},
+		{
+			yaml: "metrics:\n- name: g\n  period: d\n",
+			expected: []string{
+				"error: gaps.yaml: yaml: unmarshal errors:\n  line 3: field period not found in type devstats.MetricGap",
+			},
+		},
+		{
+			yaml: "metrics:\n- periods: d,x,,dd\n  aggregate: 1,a\n  skip: w\n  series: ['=p;s;_']\n",
+			expected: []string{
+				"error: gaps.yaml: gap #1 '': no name given",
+				"error: gaps.yaml: gap #1 '': unknown period 'x', allowed: h, d, w, m, q, y with optional number",
+				"error: gaps.yaml: gap #1 '': empty period in 'd,x,,dd'",
+				"error: gaps.yaml: gap #1 '': period 'dd' must be a period letter followed by a positive number",
+				"error: gaps.yaml: gap #1 '': aggregate 'a' is not a positive integer",
+				"warning: gaps.yaml: gap #1 '': skip 'w' doesn't match any period and aggregate combination",
+				"error: gaps.yaml: gap #1 '': series formula '=p;s;_' must have at least 4 parameters: prefix, suffix, join, list",
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		var got []string
+		for _, issue := range lib.LintGaps("gaps.yaml", []byte(test.yaml)) {
+			got = append(got, issue.String())
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestLintVars(t *testing.T) {
+	ivars := "vars:\n- tag: t\n  name: n\n  value: v\n- tag: t\n  name: x\n"
+	pvars := "vars:\n- name: n\n  type: s\n  command: [hostname]\n  replaces: [[a, b], [c]]\n- name: x\n  type: str\n  value: v\n"
+	expected := []string{
+		"error: idb_vars.yaml: var #2 'x': value or command is required",
+		"error: pdb_vars.yaml: var #1 'n': replacement definition should be array with 2 elements, got: [c]",
+		"error: pdb_vars.yaml: var #2 'x': unknown type 'str', allowed: i, f, s, dt (gha_vars value_* columns)",
+	}
+	var got []string
+	issues := append(lib.LintIVars("idb_vars.yaml", []byte(ivars)), lib.LintPVars("pdb_vars.yaml", []byte(pvars))...)
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestLintMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_lint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	dataPrefix := dir + "/"
+	if err = os.MkdirAll(dataPrefix+"metrics/proj", 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = os.MkdirAll(dataPrefix+"metrics/shared", 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = os.MkdirAll(dataPrefix+"util_sql", 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := map[string]string{
+		"metrics/proj/ok.sql":       "select 'a;b', count(*) from t where d >= '{{from}}' and d < '{{to}}' and u {{exclude_bots}}",
+		"metrics/shared/shared.sql": "select 'a', count(*) / {{n}} from t where {{period:d}} and {{to}} and {{lim}}",
+		"util_sql/exclude_bots.sql": "not like '%-bot'",
+		"metrics/proj/static.sql":   "select 1",
+	}
+	for file, content := range files {
+		if err = ioutil.WriteFile(dataPrefix+file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	metrics := `---
+metrics:
+  - name: OK
+    series_name_or_func: multi_row_single_column
+    sql: ok
+    periods: d,w,m
+    aggregate: 1,7
+    skip: w7,m7
+    desc: time_diff_as_string
+  - name: Hist
+    series_name_or_func: multi_row_multi_colum
+    sql: shared
+    histogram: true
+    annotations_ranges: true
+    periods: d
+  - name: Missing
+    series_name_or_func: name
+    sql: missing
+    periods: d
+    desc: hours
+    multi_value: true
+  - name: Static
+    series_name_or_func: single_row_multi_column
+    sql: static
+    periods: d
+    annotations_ranges: true
+`
+	expected := []string{
+		"warning: metrics.yaml: metric #2 'Hist': periods, aggregate and skip are ignored, annotations_ranges metrics are computed for quick ranges",
+		"warning: metrics.yaml: metric #2 'Hist': series_name_or_func 'multi_row_multi_colum' looks like a misspelled series name function",
+		"error: metrics.yaml: metric #2 'Hist': SQL file " + dataPrefix + "metrics/proj/shared.sql: variable {{n}} is not set, variable {{lim}} is not set",
+		"warning: metrics.yaml: metric #3 'Missing': multi_value and escape_value_name are only used by series name functions",
+		"error: metrics.yaml: metric #3 'Missing': unknown value description function 'hours'",
+		"error: metrics.yaml: metric #3 'Missing': cannot read SQL file: open " + dataPrefix + "metrics/shared/missing.sql: no such file or directory",
+		"error: metrics.yaml: metric #4 'Static': annotations_ranges can only be used for histogram metrics",
+		"warning: metrics.yaml: metric #4 'Static': periods, aggregate and skip are ignored, annotations_ranges metrics are computed for quick ranges",
+		"warning: metrics.yaml: metric #4 'Static': SQL file " + dataPrefix + "metrics/proj/static.sql doesn't use {{from}} and {{to}}, all periods will have the same values",
+		"warning: metrics.yaml: metric #4 'Static': SQL file " + dataPrefix + "metrics/proj/static.sql doesn't use {{period:column}}, all quick ranges will have the same values",
+	}
+	linter := lib.MetricsLinter{Ctx: &lib.Ctx{Project: "proj"}, DataPrefix: dataPrefix}
+	var got []string
+	for _, issue := range linter.LintMetrics("metrics.yaml", []byte(metrics)) {
+		got = append(got, issue.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+func TestExplainQueries(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		stmt     string
+		expected []string
+	}{
+		{stmt: "select 1", expected: []string{"explain select 1"}},
+		{stmt: "with a as (select 1) select * from a", expected: []string{"explain with a as (select 1) select * from a"}},
+		{stmt: "(select 1) union (select 2)", expected: []string{"explain (select 1) union (select 2)"}},
+		{stmt: "delete from t where a = 1", expected: []string{"explain delete from t where a = 1"}},
+		{
+			stmt:     "create temp table t as select 1",
+			expected: []string{"explain create temp table t as select 1", "create temp table t as select 1 with no data"},
+		},
+		{
+			stmt:     "CREATE TABLE t AS\n(\n  WITH a AS (select 1) select * from a\n)",
+			expected: []string{"explain CREATE TABLE t AS\n(\n  WITH a AS (select 1) select * from a\n)", "CREATE TABLE t AS\n(\n  WITH a AS (select 1) select * from a\n) with no data"},
+		},
+		{stmt: "create index t_idx on t(a asc)", expected: nil},
+		{stmt: "create table t(a int, assignee text)", expected: nil},
+		{stmt: "create temp table t(a int) on commit drop", expected: nil},
+		{stmt: "drop table t", expected: nil},
+		{stmt: "analyze t", expected: nil},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ExplainQueries(test.stmt)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, got)
+		}
+	}
+}
@@ -381,7 +381,7 @@ func executeMetricTestCase(testMetric *metricTestCase, tests *metricTests, ctx *
 	return
 }
 
-// execute metric metrics/{{metric}}.sql with {{from}} and {{to}} replaced by from/YMDHMS, to/YMDHMS
+// execute metric metrics/{{metric}}.sql rendered like `db2influx` does, {{from}} and {{to}} are from/YMDHMS, to/YMDHMS
 // end result slice of slices of any type
 func executeMetric(c *sql.DB, ctx *lib.Ctx, metric, msql string, from, to time.Time, period string, n int, replaces [][]string) (result [][]interface{}, err error) {
 	// Metric file name
@@ -396,21 +396,6 @@ func executeMetric(c *sql.DB, ctx *lib.Ctx, metric, msql string, from, to time.T
 		return
 	}
 	sqlQuery := string(bytes)
-	if from.Year() >= 1980 {
-		sqlQuery = strings.Replace(sqlQuery, "{{from}}", lib.ToYMDHMSDate(from), -1)
-	}
-	if to.Year() >= 1980 {
-		sqlQuery = strings.Replace(sqlQuery, "{{to}}", lib.ToYMDHMSDate(to), -1)
-	}
-	sqlQuery = strings.Replace(sqlQuery, "{{period}}", period, -1)
-	sqlQuery = strings.Replace(sqlQuery, "{{n}}", strconv.Itoa(n)+".0", -1)
-	sqlQuery = strings.Replace(
-		sqlQuery,
-		"{{exclude_bots}}",
-		"not like all(array['googlebot', 'rktbot', 'coveralls', 'k8s-%', '%-bot', '%-robot', "+
-			"'bot-%', 'robot-%', '%[bot]%', '%-jenkins', '%-ci%bot', '%-testing', 'codecov-%'])",
-		-1,
-	)
 	for _, replace := range replaces {
 		if len(replace) != 2 {
 			err = fmt.Errorf("replace(s) should have length 2, invalid: %+v", replace)
@@ -426,7 +411,22 @@ func executeMetric(c *sql.DB, ctx *lib.Ctx, metric, msql string, from, to time.T
 	if to.Year() >= 1980 {
 		qrTo = lib.ToYMDHMSDate(to)
 	}
-	sqlQuery = lib.PrepareQuickRangeQuery(sqlQuery, period, qrFrom, qrTo)
+	vars := lib.NewSQLVars()
+	if strings.Contains(sqlQuery, "{{period:") {
+		vars.SetQuickRange(period, qrFrom, qrTo)
+	}
+	if qrFrom != "" {
+		vars.SetString(lib.SQLVarFrom, qrFrom)
+	}
+	if qrTo != "" {
+		vars.SetString(lib.SQLVarTo, qrTo)
+	}
+	vars.SetString(lib.SQLVarPeriod, period)
+	vars.Set(lib.SQLVarN, strconv.Itoa(n)+".0")
+	sqlQuery, err = lib.RenderSQL(ctx, "./", sqlQuery, vars)
+	if err != nil {
+		return
+	}
 
 	// Execute SQL
 	rows := lib.QuerySQLWithErr(c, ctx, sqlQuery)
@@ -1022,6 +1022,12 @@ func (metricTestCase) UpdateRepoAliasFromName(con *sql.DB, ctx *lib.Ctx, arg str
 	return
 }
 
+// Computes events and commits companies attribution, like `import_affs` does after importing affiliations
+func (metricTestCase) CompaniesAttribution(con *sql.DB, ctx *lib.Ctx, arg string) (err error) {
+	lib.UpdateCompaniesAttribution(con, ctx, nil)
+	return
+}
+
 // Create dynamic data for affiliations metric after loaded static YAML data
 func (metricTestCase) AffiliationsTestHelper(con *sql.DB, ctx *lib.Ctx, arg string) (err error) {
 	ft := testlib.YMDHMS
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq" // As suggested by lib/pq driver
@@ -47,6 +48,12 @@ func CreateTable(tdef string) string {
 	return "create table " + tdef
 }
 
+// SQL queries duration histogram name and description
+const (
+	promSQLDuration     = "devstats_sql_query_duration_seconds"
+	promSQLDurationHelp = "Postgres queries (op=query) and statements (op=exec) duration."
+)
+
 // Outputs query info
 func queryOut(query string, args ...interface{}) {
 	// Use fmt.Printf not lib.Printf here
@@ -63,6 +70,7 @@ func QueryRowSQL(con *sql.DB, ctx *Ctx, query string, args ...interface{}) *sql.
 	if ctx.QOut {
 		queryOut(query, args...)
 	}
+	defer PromObserveSince(promSQLDuration, promSQLDurationHelp, PromQueryBuckets, time.Now(), "op", "query")
 	return con.QueryRow(query, args...)
 }
 
@@ -71,6 +79,7 @@ func QuerySQL(con *sql.DB, ctx *Ctx, query string, args ...interface{}) (*sql.Ro
 	if ctx.QOut {
 		queryOut(query, args...)
 	}
+	defer PromObserveSince(promSQLDuration, promSQLDurationHelp, PromQueryBuckets, time.Now(), "op", "query")
 	return con.Query(query, args...)
 }
 
@@ -107,6 +116,7 @@ func QuerySQLTx(con *sql.Tx, ctx *Ctx, query string, args ...interface{}) (*sql.
 	if ctx.QOut {
 		queryOut(query, args...)
 	}
+	defer PromObserveSince(promSQLDuration, promSQLDurationHelp, PromQueryBuckets, time.Now(), "op", "query")
 	return con.Query(query, args...)
 }
 
@@ -143,6 +153,7 @@ func ExecSQL(con *sql.DB, ctx *Ctx, query string, args ...interface{}) (sql.Resu
 	if ctx.QOut {
 		queryOut(query, args...)
 	}
+	defer PromObserveSince(promSQLDuration, promSQLDurationHelp, PromQueryBuckets, time.Now(), "op", "exec")
 	return con.Exec(query, args...)
 }
 
@@ -179,6 +190,7 @@ func ExecSQLTx(con *sql.Tx, ctx *Ctx, query string, args ...interface{}) (sql.Re
 	if ctx.QOut {
 		queryOut(query, args...)
 	}
+	defer PromObserveSince(promSQLDuration, promSQLDurationHelp, PromQueryBuckets, time.Now(), "op", "exec")
 	return con.Exec(query, args...)
 }
 
@@ -316,6 +328,31 @@ func TruncStringOrNil(strPtr *string, maxLen int) interface{} {
 	return TruncToBytes(*strPtr, maxLen)
 }
 
+// tablesExist - cached results of TableExists, keys are "database.table"
+var (
+	tablesExist      = map[string]bool{}
+	tablesExistMutex sync.Mutex
+)
+
+// TableExists - checks (once per process and database) if a given table exists
+// Special tables added after a database was created need `util_sql/` migrations, tools use this to work without them
+// When table is missing, a warning with `migration` file name and `disabled` (what doesn't work without it) is printed
+func TableExists(con *sql.DB, ctx *Ctx, table, migration, disabled string) bool {
+	tablesExistMutex.Lock()
+	defer tablesExistMutex.Unlock()
+	key := ctx.PgDB + "." + table
+	if exists, ok := tablesExist[key]; ok {
+		return exists
+	}
+	exists := false
+	FatalOnError(QueryRowSQL(con, ctx, "select to_regclass('"+table+"') is not null").Scan(&exists))
+	if !exists {
+		Printf("Warning: no %s table in '%s' database, %s, apply util_sql/%s first\n", table, ctx.PgDB, disabled, migration)
+	}
+	tablesExist[key] = exists
+	return exists
+}
+
 // DatabaseExists - checks if database stored in context exists
 // If closeConn is true - then it closes connection after checking if database exists
 // If closeConn is false, then it returns open connection to default database "postgres"
@@ -2,6 +2,7 @@ package devstats
 
 import (
 	"database/sql"
+	"strconv"
 	"testing"
 	"time"
 
@@ -361,3 +362,129 @@ func getInts(c *sql.DB, ctx *lib.Ctx) []int {
 	lib.FatalOnError(rows.Err())
 	return arr
 }
+
+// bulkTestDB - creates "dbtest" database with test table, returns connection and cleanup function
+func bulkTestDB(tb testing.TB, ctx *lib.Ctx) (*sql.DB, func()) {
+	ctx.Init()
+	if ctx.PgDB != "dbtest" {
+		tb.Fatalf("tests can only be run on \"dbtest\" database")
+	}
+	lib.DropDatabaseIfExists(ctx)
+	if !lib.CreateDatabaseIfNeeded(ctx) {
+		tb.Fatalf("failed to create database \"%s\"", ctx.PgDB)
+	}
+	c := lib.PgConn(ctx)
+	lib.ExecSQLWithErr(
+		c,
+		ctx,
+		lib.CreateTable(
+			"test(an_int int, a_string text, a_dt {{ts}}, primary key(an_int))",
+		),
+	)
+	return c, func() {
+		lib.FatalOnError(c.Close())
+		lib.DropDatabaseIfExists(ctx)
+	}
+}
+
+func TestBulkInsertFlush(t *testing.T) {
+	var ctx lib.Ctx
+	c, cleanup := bulkTestDB(t, &ctx)
+	defer cleanup()
+
+	// Row that will conflict with bulk inserted row
+	lib.ExecSQLWithErr(
+		c,
+		&ctx,
+		"insert into test(an_int, a_string, a_dt) "+lib.NValues(3),
+		lib.AnyArray{1, "string", time.Now()}...,
+	)
+
+	// Bulk insert ignore rows, including conflicting ones
+	bulk := lib.NewBulkInsert()
+	for _, i := range []int{1, 2, 3, 2} {
+		bulk.Add("into test(an_int, a_string, a_dt)", true, i, "bulk", time.Now())
+	}
+	bulk.Flush(c, &ctx)
+	if bulk.Len() != 0 {
+		t.Errorf("expected empty bulk after flush, got %d rows", bulk.Len())
+	}
+	gotArr := getInts(c, &ctx)
+	expectedArr := []int{1, 2, 3}
+	if !testlib.CompareIntSlices(gotArr, expectedArr) {
+		t.Errorf("expected %v after bulk insert ignore, got %v", expectedArr, gotArr)
+	}
+
+	// Bulk insert more rows than fit in a single query
+	for i := 10; i < 10+lib.MaxQueryParams; i++ {
+		bulk.Add("into test(an_int, a_string, a_dt)", false, i, "bulk", time.Now())
+	}
+	bulk.Flush(c, &ctx)
+	n := 0
+	lib.FatalOnError(lib.QueryRowSQL(c, &ctx, "select count(*) from test").Scan(&n))
+	if n != 3+lib.MaxQueryParams {
+		t.Errorf("expected %d rows after big bulk insert, got %d", 3+lib.MaxQueryParams, n)
+	}
+
+	// Keys already in the DB are removed with their rows
+	for _, i := range []int{1, 5} {
+		key := strconv.Itoa(i)
+		bulk.AddKey(key)
+		bulk.AddFor(key, "into test(an_int, a_string, a_dt)", false, i, "bulk", time.Now())
+	}
+	removed := bulk.RemoveExisting(c, &ctx, "select an_int from test where an_int = any($1::int[])")
+	if removed != 1 || bulk.Len() != 1 {
+		t.Errorf("expected 1 existing key removed and 1 row left, got %d and %d", removed, bulk.Len())
+	}
+	bulk.Flush(c, &ctx)
+	lib.FatalOnError(lib.QueryRowSQL(c, &ctx, "select count(*) from test").Scan(&n))
+	if n != 4+lib.MaxQueryParams {
+		t.Errorf("expected %d rows after bulk insert of not existing keys, got %d", 4+lib.MaxQueryParams, n)
+	}
+}
+
+// benchmarkRows - number of rows inserted in each benchmark iteration
+const benchmarkRows = 2000
+
+// BenchmarkInsertPerRow - checks if row exists and inserts rows one by one, as gha2db does without GHA2DB_BULK
+func BenchmarkInsertPerRow(b *testing.B) {
+	var ctx lib.Ctx
+	c, cleanup := bulkTestDB(b, &ctx)
+	defer cleanup()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchmarkRows; i++ {
+			exists := 0
+			lib.FatalOnError(
+				lib.QueryRowSQL(c, &ctx, "select count(*) from test where an_int = $1", n*benchmarkRows+i).Scan(&exists),
+			)
+			if exists > 0 {
+				continue
+			}
+			lib.ExecSQLWithErr(
+				c,
+				&ctx,
+				lib.InsertIgnore("into test(an_int, a_string, a_dt) "+lib.NValues(3)),
+				lib.AnyArray{n*benchmarkRows + i, "per row", time.Now()}...,
+			)
+		}
+	}
+}
+
+// BenchmarkInsertBulk - removes existing rows with a single query and inserts rows using multi-row inserts, as gha2db does with GHA2DB_BULK
+func BenchmarkInsertBulk(b *testing.B) {
+	var ctx lib.Ctx
+	c, cleanup := bulkTestDB(b, &ctx)
+	defer cleanup()
+	bulk := lib.NewBulkInsert()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < benchmarkRows; i++ {
+			key := strconv.Itoa(n*benchmarkRows + i)
+			bulk.AddKey(key)
+			bulk.AddFor(key, "into test(an_int, a_string, a_dt)", true, n*benchmarkRows+i, "bulk", time.Now())
+		}
+		bulk.RemoveExisting(c, &ctx, "select an_int from test where an_int = any($1::int[])")
+		bulk.Flush(c, &ctx)
+	}
+}
@@ -0,0 +1,234 @@
+package devstats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PromQueryBuckets - histogram buckets (in seconds) for short operations like SQL queries and InfluxDB writes
+var PromQueryBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 60}
+
+// PromPhaseBuckets - histogram buckets (in seconds) for long operations like sync phases or GHA hours import
+var PromPhaseBuckets = []float64{1, 5, 15, 60, 300, 900, 1800, 3600, 7200}
+
+// promSeries - single series (metric with given label values)
+type promSeries struct {
+	labels string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+// promMetric - counter, gauge or histogram with all its series
+type promMetric struct {
+	name    string
+	help    string
+	typ     string
+	buckets []float64
+	series  map[string]*promSeries
+}
+
+// Operational metrics registry, metrics are only collected after PromStart was called
+// Guarded by the mutex because metrics are updated from multiple go routines
+var (
+	promEnabled     int32
+	promMutex       sync.Mutex
+	promMetrics     = make(map[string]*promMetric)
+	promConstLabels []string
+	promProg        string
+)
+
+// PromStart - enables collecting operational metrics (Prometheus/OpenMetrics text format) for program `prog`
+// When GHA2DB_PROM_LISTEN is set metrics are served on "/metrics" HTTP endpoint while the program runs
+// When GHA2DB_PROM_TEXTFILE_DIR is set, PromFinish writes them to a file for node_exporter's textfile collector
+// All metrics get `prog` and `project` (when GHA2DB_PROJECT is set) labels
+func PromStart(ctx *Ctx, prog string) {
+	if ctx.PromListen == "" && ctx.PromTextfileDir == "" {
+		return
+	}
+	promMutex.Lock()
+	promProg = prog
+	promConstLabels = []string{"prog", prog}
+	if ctx.Project != "" {
+		promConstLabels = append(promConstLabels, "project", ctx.Project)
+	}
+	promMutex.Unlock()
+	atomic.StoreInt32(&promEnabled, 1)
+	if ctx.PromListen == "" {
+		return
+	}
+	// Child processes (like gha2db called by gha2db_sync) only use the textfile, they would fail to listen on the same address
+	FatalOnError(os.Unsetenv("GHA2DB_PROM_LISTEN"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = PromWrite(w)
+	})
+	go func() {
+		err := http.ListenAndServe(ctx.PromListen, mux)
+		fmt.Fprintf(os.Stderr, "%v: metrics endpoint %s stopped: %v\n", time.Now(), ctx.PromListen, err)
+	}()
+}
+
+// PromFinish - writes collected metrics to GHA2DB_PROM_TEXTFILE_DIR/devstats_{{prog}}[_{{project}}].prom
+// File is written to a temporary file first and then renamed, so textfile collector never reads a partial file
+func PromFinish(ctx *Ctx) {
+	if atomic.LoadInt32(&promEnabled) == 0 || ctx.PromTextfileDir == "" {
+		return
+	}
+	name := "devstats_" + promProg
+	if ctx.Project != "" {
+		name += "_" + ctx.Project
+	}
+	path := strings.TrimSuffix(ctx.PromTextfileDir, "/") + "/" + name + ".prom"
+	var buf bytes.Buffer
+	FatalOnError(PromWrite(&buf))
+	tmp := path + ".tmp"
+	FatalOnError(ioutil.WriteFile(tmp, buf.Bytes(), 0644))
+	FatalOnError(os.Rename(tmp, path))
+}
+
+// PromAdd - adds value to a counter
+func PromAdd(name, help string, value float64, labels ...string) {
+	if atomic.LoadInt32(&promEnabled) == 0 {
+		return
+	}
+	promMutex.Lock()
+	defer promMutex.Unlock()
+	promGetSeries(name, help, "counter", nil, labels).value += value
+}
+
+// PromSet - sets gauge value
+func PromSet(name, help string, value float64, labels ...string) {
+	if atomic.LoadInt32(&promEnabled) == 0 {
+		return
+	}
+	promMutex.Lock()
+	defer promMutex.Unlock()
+	promGetSeries(name, help, "gauge", nil, labels).value = value
+}
+
+// PromObserve - adds observation to a histogram with given buckets (upper bounds)
+func PromObserve(name, help string, buckets []float64, value float64, labels ...string) {
+	if atomic.LoadInt32(&promEnabled) == 0 {
+		return
+	}
+	promMutex.Lock()
+	defer promMutex.Unlock()
+	series := promGetSeries(name, help, "histogram", buckets, labels)
+	for i, le := range buckets {
+		if value <= le {
+			series.counts[i]++
+		}
+	}
+	series.count++
+	series.value += value
+}
+
+// PromObserveSince - adds time passed since `dtStart` (in seconds) to a histogram
+func PromObserveSince(name, help string, buckets []float64, dtStart time.Time, labels ...string) {
+	if atomic.LoadInt32(&promEnabled) == 0 {
+		return
+	}
+	PromObserve(name, help, buckets, time.Now().Sub(dtStart).Seconds(), labels...)
+}
+
+// promGetSeries - returns (creates when needed) series with given labels, must be called with promMutex locked
+func promGetSeries(name, help, typ string, buckets []float64, labels []string) *promSeries {
+	metric, ok := promMetrics[name]
+	if !ok {
+		metric = &promMetric{name: name, help: help, typ: typ, buckets: buckets, series: make(map[string]*promSeries)}
+		promMetrics[name] = metric
+	} else if metric.typ != typ {
+		Fatalf("metric %s is a %s, cannot use it as a %s", name, metric.typ, typ)
+	}
+	key := promLabels(append(append([]string{}, promConstLabels...), labels...))
+	series, ok := metric.series[key]
+	if !ok {
+		series = &promSeries{labels: key}
+		if typ == "histogram" {
+			series.counts = make([]uint64, len(metric.buckets))
+		}
+		metric.series[key] = series
+	}
+	return series
+}
+
+// promLabels - formats label name, value pairs: {name1="value1",name2="value2"}
+func promLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		value := strings.Replace(labels[i+1], `\`, `\\`, -1)
+		value = strings.Replace(value, `"`, `\"`, -1)
+		value = strings.Replace(value, "\n", `\n`, -1)
+		pairs = append(pairs, labels[i]+`="`+value+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// promAddLabel - adds label to already formatted labels
+func promAddLabel(labels, name, value string) string {
+	if labels == "" {
+		return promLabels([]string{name, value})
+	}
+	return labels[:len(labels)-1] + "," + promLabels([]string{name, value})[1:]
+}
+
+// promFloat - formats float value
+func promFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// PromWrite - writes all collected metrics in Prometheus text exposition format
+// Metrics and series are sorted, so output is stable
+func PromWrite(w io.Writer) error {
+	promMutex.Lock()
+	defer promMutex.Unlock()
+	names := []string{}
+	for name := range promMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		metric := promMetrics[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, metric.help, name, metric.typ)
+		keys := []string{}
+		for key := range metric.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			series := metric.series[key]
+			if metric.typ != "histogram" {
+				fmt.Fprintf(&buf, "%s%s %s\n", name, key, promFloat(series.value))
+				continue
+			}
+			for i, le := range metric.buckets {
+				fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, promAddLabel(key, "le", promFloat(le)), series.counts[i])
+			}
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, promAddLabel(key, "le", "+Inf"), series.count)
+			fmt.Fprintf(&buf, "%s_sum%s %s\n", name, key, promFloat(series.value))
+			fmt.Fprintf(&buf, "%s_count%s %d\n", name, key, series.count)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,70 @@
+package devstats
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	lib "devstats"
+)
+
+func TestPromMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devstats_prom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	ctx := lib.Ctx{Project: "proj", PromTextfileDir: dir + "/"}
+
+	// Nothing is collected before PromStart
+	lib.PromAdd("test_prom_disabled_total", "Disabled.", 1)
+	lib.PromStart(&ctx, "test")
+	lib.PromAdd("test_prom_counter_total", "Test counter.", 2, "kind", `a"b\c`)
+	lib.PromAdd("test_prom_counter_total", "Test counter.", 3, "kind", `a"b\c`)
+	lib.PromAdd("test_prom_counter_total", "Test counter.", 1, "kind", "x")
+	lib.PromSet("test_prom_gauge", "Test gauge.", 7)
+	lib.PromSet("test_prom_gauge", "Test gauge.", 5.5)
+	buckets := []float64{1, 10}
+	lib.PromObserve("test_prom_histogram", "Test histogram.", buckets, 0.5)
+	lib.PromObserve("test_prom_histogram", "Test histogram.", buckets, 5)
+	lib.PromObserve("test_prom_histogram", "Test histogram.", buckets, 20)
+	lib.PromFinish(&ctx)
+
+	data, err := ioutil.ReadFile(dir + "/devstats_test_proj.prom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := string(data)
+	expected := []string{
+		"# HELP test_prom_counter_total Test counter.\n# TYPE test_prom_counter_total counter\n" +
+			`test_prom_counter_total{prog="test",project="proj",kind="a\"b\\c"} 5` + "\n" +
+			`test_prom_counter_total{prog="test",project="proj",kind="x"} 1` + "\n",
+		"# TYPE test_prom_gauge gauge\n" + `test_prom_gauge{prog="test",project="proj"} 5.5` + "\n",
+		"# TYPE test_prom_histogram histogram\n" +
+			`test_prom_histogram_bucket{prog="test",project="proj",le="1"} 1` + "\n" +
+			`test_prom_histogram_bucket{prog="test",project="proj",le="10"} 2` + "\n" +
+			`test_prom_histogram_bucket{prog="test",project="proj",le="+Inf"} 3` + "\n" +
+			`test_prom_histogram_sum{prog="test",project="proj"} 25.5` + "\n" +
+			`test_prom_histogram_count{prog="test",project="proj"} 3` + "\n",
+	}
+	for index, exp := range expected {
+		if !strings.Contains(got, exp) {
+			t.Errorf("test number %d, expected to contain:\n%s\ngot:\n%s", index+1, exp, got)
+		}
+	}
+	if strings.Contains(got, "test_prom_disabled_total") {
+		t.Errorf("metric added before PromStart should not be collected, got:\n%s", got)
+	}
+
+	// Endpoint and textfile output are the same
+	var buf bytes.Buffer
+	err = lib.PromWrite(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != got {
+		t.Errorf("expected:\n%s\ngot:\n%s", got, buf.String())
+	}
+}
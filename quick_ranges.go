@@ -0,0 +1,235 @@
+package devstats
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// QuickRange - single quick range (Grafana drop-down value used by `annotations_ranges` histogram metrics)
+// defined in `quick_ranges.yaml` or per project in `projects.yaml` (`quick_ranges:`)
+// Suffix - unique value used as series name suffix, "anno_" prefix is reserved for annotations ranges
+// Name - displayed name
+// Exactly one kind of range must be given:
+// Period - last period till now, passed to Postgres as interval, for example "1 week"
+// Align - calendar aligned range: day, week, month, quarter or year, Offset 0 means current one (to date), -1 previous one, etc.
+// From, To - fixed dates (YYYY-MM-DD or YYYY-MM-DD HH:MI:SS) or "start_date", "join_date" (project's dates) and "now"
+// Annotations - position of annotations ranges (anno_x_y, anno_x_now, anno_event_n) in the list, they are added at the end by default
+type QuickRange struct {
+	Suffix      string `yaml:"suffix"`
+	Name        string `yaml:"name"`
+	Period      string `yaml:"period"`
+	Align       string `yaml:"align"`
+	Offset      int    `yaml:"offset"`
+	From        string `yaml:"from"`
+	To          string `yaml:"to"`
+	Annotations bool   `yaml:"annotations"`
+}
+
+// QuickRanges - holds all quick ranges (`quick_ranges.yaml` file)
+type QuickRanges struct {
+	QuickRanges []QuickRange `yaml:"quick_ranges"`
+}
+
+// DefaultQuickRanges - quick ranges used when project has no quick ranges defined
+var DefaultQuickRanges = []QuickRange{
+	{Suffix: "d", Name: "Last day", Period: "1 day"},
+	{Suffix: "w", Name: "Last week", Period: "1 week"},
+	{Suffix: "d10", Name: "Last 10 days", Period: "10 days"},
+	{Suffix: "m", Name: "Last month", Period: "1 month"},
+	{Suffix: "q", Name: "Last quarter", Period: "3 months"},
+	{Suffix: "y", Name: "Last year", Period: "1 year"},
+	{Suffix: "y10", Name: "Last decade", Period: "10 years"},
+	{Annotations: true},
+	{Suffix: "cncf_before", Name: "Before joining CNCF", From: "start_date", To: "join_date"},
+	{Suffix: "cncf_now", Name: "Since joining CNCF", From: "join_date", To: "now"},
+}
+
+// quickRangeAligns - calendar units that can be used as `align:`
+var quickRangeAligns = map[string]struct{}{"day": {}, "week": {}, "month": {}, "quarter": {}, "year": {}}
+
+// parseQuickRangeDate - parses fixed quick range date
+func parseQuickRangeDate(dtStr string) (time.Time, error) {
+	for _, format := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z", "2006-01-02"} {
+		if dt, err := time.Parse(format, dtStr); err == nil {
+			return dt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date '%s'", dtStr)
+}
+
+// quickRangeDate - returns quick range `from:` or `to:` date, nil if it refers to missing project's date
+func quickRangeDate(dtStr string, startDate, joinDate *time.Time, now time.Time) (*time.Time, error) {
+	switch dtStr {
+	case "start_date":
+		return startDate, nil
+	case "join_date":
+		return joinDate, nil
+	case "now":
+		dt := NextDayStart(now)
+		return &dt, nil
+	}
+	dt, err := parseQuickRangeDate(dtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &dt, nil
+}
+
+// Validate - checks quick ranges definitions
+func (q *QuickRanges) Validate() error {
+	suffixes := make(map[string]struct{})
+	nAnnotations := 0
+	for i, qr := range q.QuickRanges {
+		item := fmt.Sprintf("quick range #%d '%s'", i+1, qr.Suffix)
+		if qr.Annotations {
+			if qr.Suffix != "" || qr.Name != "" || qr.Period != "" || qr.Align != "" || qr.From != "" || qr.To != "" {
+				return fmt.Errorf("%s: annotations entry cannot define a range", item)
+			}
+			nAnnotations++
+			continue
+		}
+		if qr.Suffix == "" || qr.Name == "" {
+			return fmt.Errorf("%s: suffix and name are required", item)
+		}
+		if strings.HasPrefix(qr.Suffix, "anno_") {
+			return fmt.Errorf("%s: suffix prefix 'anno_' is reserved for annotations ranges", item)
+		}
+		if _, ok := suffixes[qr.Suffix]; ok {
+			return fmt.Errorf("%s: duplicate suffix", item)
+		}
+		suffixes[qr.Suffix] = struct{}{}
+		for _, value := range []string{qr.Suffix, qr.Name, qr.Period, qr.From, qr.To} {
+			if strings.Contains(value, ";") {
+				return fmt.Errorf("%s: values cannot contain ';'", item)
+			}
+		}
+		kinds := 0
+		if qr.Period != "" {
+			kinds++
+		}
+		if qr.Align != "" {
+			kinds++
+			if _, ok := quickRangeAligns[qr.Align]; !ok {
+				return fmt.Errorf("%s: unknown align '%s'", item, qr.Align)
+			}
+			if qr.Offset > 0 {
+				return fmt.Errorf("%s: offset cannot be positive", item)
+			}
+		} else if qr.Offset != 0 {
+			return fmt.Errorf("%s: offset can only be used with align", item)
+		}
+		if qr.From != "" || qr.To != "" {
+			kinds++
+			if qr.From == "" || qr.To == "" {
+				return fmt.Errorf("%s: both from and to are required", item)
+			}
+			for _, dtStr := range []string{qr.From, qr.To} {
+				if _, err := quickRangeDate(dtStr, nil, nil, time.Now()); err != nil {
+					return fmt.Errorf("%s: %v", item, err)
+				}
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("%s: exactly one of period, align or from and to must be given", item)
+		}
+	}
+	if nAnnotations > 1 {
+		return fmt.Errorf("annotations entry can only be given once")
+	}
+	return nil
+}
+
+// Data - returns quick range data "suffix;period;from;to" at `now`, period is set for "last period" ranges, from and to for other ranges
+// Returns false when range cannot be computed: it refers to a missing project's date or it is empty
+func (qr *QuickRange) Data(startDate, joinDate *time.Time, now time.Time) (string, bool) {
+	if qr.Period != "" {
+		return qr.Suffix + ";" + qr.Period + ";;", true
+	}
+	var from, to *time.Time
+	if qr.Align != "" {
+		_, _, intervalStart, nextIntervalStart, prevIntervalStart := GetIntervalFunctions(qr.Align[0:1], false)
+		dtFrom := AddNIntervals(intervalStart(now), qr.Offset, nextIntervalStart, prevIntervalStart)
+		dtTo := NextDayStart(now)
+		if qr.Offset < 0 {
+			dtTo = nextIntervalStart(dtFrom)
+		}
+		from, to = &dtFrom, &dtTo
+	} else {
+		var err error
+		from, err = quickRangeDate(qr.From, startDate, joinDate, now)
+		FatalOnError(err)
+		to, err = quickRangeDate(qr.To, startDate, joinDate, now)
+		FatalOnError(err)
+	}
+	if from == nil || to == nil || !to.After(*from) {
+		return "", false
+	}
+	return fmt.Sprintf("%s;;%s;%s", qr.Suffix, ToYMDHMSDate(*from), ToYMDHMSDate(*to)), true
+}
+
+// GetQuickRanges - returns project's quick ranges from `projects.yaml` (`projRanges`),
+// or from quick ranges YAML file (project's or shared), or DefaultQuickRanges when none are defined
+func GetQuickRanges(ctx *Ctx, path string, projRanges []QuickRange) []QuickRange {
+	var qrs QuickRanges
+	if len(projRanges) > 0 {
+		qrs.QuickRanges = projRanges
+		path = ctx.ProjectsYaml
+	} else {
+		data, err := ReadFile(ctx, path)
+		if os.IsNotExist(err) {
+			if ctx.Debug > 0 {
+				Printf("No quick ranges file %s, using default quick ranges\n", path)
+			}
+			return DefaultQuickRanges
+		}
+		FatalOnError(err)
+		FatalOnError(yaml.Unmarshal(data, &qrs))
+	}
+	err := qrs.Validate()
+	if err != nil {
+		Fatalf("%s: %v", path, err)
+	}
+	return qrs.QuickRanges
+}
+
+// isDefaultQuickRangeSuffix - is this suffix one of DefaultQuickRanges suffixes or annotations ranges suffix?
+func isDefaultQuickRangeSuffix(sfx string) bool {
+	if strings.HasPrefix(sfx, "anno_") {
+		return true
+	}
+	for _, qr := range DefaultQuickRanges {
+		if !qr.Annotations && qr.Suffix == sfx {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeQuickRangeAtThisDate - decides if quick range given by its data "suffix;period;from;to" should be computed at this date
+// Default quick ranges suffixes and annotations ranges suffixes use ComputePeriodAtThisDate schedule
+// Other ranges are computed like annotations ranges: every 6 hours when they end now, once a day when they end in the past
+func ComputeQuickRangeAtThisDate(ctx *Ctx, data string, dt time.Time) bool {
+	ary := strings.Split(data, ";")
+	if len(ary) != 4 {
+		Fatalf("ComputeQuickRangeAtThisDate: invalid quick range data: '%s'", data)
+	}
+	sfx := ary[0]
+	if isDefaultQuickRangeSuffix(sfx) {
+		return ComputePeriodAtThisDate(ctx, sfx, dt)
+	}
+	if ctx.ComputeAll {
+		return true
+	}
+	h := (HourStart(dt).Hour() + ctx.TmOffset) % 24
+	if h < 0 {
+		h += 24
+	}
+	if ary[1] == "" && TimeParseAny(ary[3]).Before(HourStart(dt)) {
+		return h == 2
+	}
+	return h%6 == 1
+}
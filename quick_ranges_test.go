@@ -0,0 +1,139 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestQuickRangeData(t *testing.T) {
+	// Example data, now is Wednesday
+	ft := testlib.YMDHMS
+	now := ft(2018, 5, 16, 10, 30)
+	startDate := ft(2014, 6)
+	joinDate := ft(2016, 3, 10)
+
+	// Test cases
+	var testCases = []struct {
+		qr       lib.QuickRange
+		noDates  bool
+		expected string
+		ok       bool
+	}{
+		{qr: lib.QuickRange{Suffix: "w", Period: "1 week"}, expected: "w;1 week;;", ok: true},
+		{qr: lib.QuickRange{Suffix: "td", Align: "day"}, expected: "td;;2018-05-16 00:00:00;2018-05-17 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "tw", Align: "week"}, expected: "tw;;2018-05-14 00:00:00;2018-05-17 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "pw", Align: "week", Offset: -1}, expected: "pw;;2018-05-07 00:00:00;2018-05-14 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "tq", Align: "quarter"}, expected: "tq;;2018-04-01 00:00:00;2018-05-17 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "pq", Align: "quarter", Offset: -1}, expected: "pq;;2018-01-01 00:00:00;2018-04-01 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "ytd", Align: "year"}, expected: "ytd;;2018-01-01 00:00:00;2018-05-17 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "py2", Align: "year", Offset: -2}, expected: "py2;;2016-01-01 00:00:00;2017-01-01 00:00:00", ok: true},
+		{qr: lib.QuickRange{Suffix: "pm", Align: "month", Offset: -1}, expected: "pm;;2018-04-01 00:00:00;2018-05-01 00:00:00", ok: true},
+		{
+			qr:       lib.QuickRange{Suffix: "kc", From: "2018-05-02", To: "2018-05-04 18:00:00"},
+			expected: "kc;;2018-05-02 00:00:00;2018-05-04 18:00:00",
+			ok:       true,
+		},
+		{qr: lib.QuickRange{Suffix: "empty", From: "2018-05-02", To: "2018-05-02"}},
+		{
+			qr:       lib.QuickRange{Suffix: "cncf_before", From: "start_date", To: "join_date"},
+			expected: "cncf_before;;2014-06-01 00:00:00;2016-03-10 00:00:00",
+			ok:       true,
+		},
+		{
+			qr:       lib.QuickRange{Suffix: "cncf_now", From: "join_date", To: "now"},
+			expected: "cncf_now;;2016-03-10 00:00:00;2018-05-17 00:00:00",
+			ok:       true,
+		},
+		{qr: lib.QuickRange{Suffix: "cncf_now", From: "join_date", To: "now"}, noDates: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		start, join := &startDate, &joinDate
+		if test.noDates {
+			start, join = nil, nil
+		}
+		got, ok := test.qr.Data(start, join, now)
+		if got != test.expected || ok != test.ok {
+			t.Errorf("test number %d, expected '%s' (%v), got '%s' (%v)", index+1, test.expected, test.ok, got, ok)
+		}
+	}
+}
+
+func TestQuickRangesValidate(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		ranges []lib.QuickRange
+		err    bool
+	}{
+		{ranges: lib.DefaultQuickRanges},
+		{ranges: []lib.QuickRange{{Suffix: "ytd", Name: "YTD", Align: "year"}, {Suffix: "pq", Name: "Previous quarter", Align: "quarter", Offset: -1}}},
+		{ranges: []lib.QuickRange{{Suffix: "kc", Name: "KubeCon", From: "2018-05-02", To: "2018-05-05"}}},
+		{ranges: []lib.QuickRange{{Name: "No suffix", Period: "1 day"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "d", Period: "1 day"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "anno_1", Name: "Reserved", Period: "1 day"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "d", Name: "Day", Period: "1 day"}, {Suffix: "d", Name: "Day 2", Period: "2 days"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "d", Name: "Day;1", Period: "1 day"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "None"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "Both", Period: "1 day", Align: "day"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "Decade", Align: "decade"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "Next year", Align: "year", Offset: 1}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "Offset", Period: "1 day", Offset: -1}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "From", From: "2018-01-01"}}, err: true},
+		{ranges: []lib.QuickRange{{Suffix: "x", Name: "Bad date", From: "2018-01-01", To: "tomorrow"}}, err: true},
+		{ranges: []lib.QuickRange{{Annotations: true}, {Annotations: true}}, err: true},
+		{ranges: []lib.QuickRange{{Annotations: true, Suffix: "a"}}, err: true},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		qrs := lib.QuickRanges{QuickRanges: test.ranges}
+		err := qrs.Validate()
+		if (err != nil) != test.err {
+			t.Errorf("test number %d, expected error: %v, got: %v", index+1, test.err, err)
+		}
+	}
+}
+
+func TestComputeQuickRangeAtThisDate(t *testing.T) {
+	// Test cases
+	// default and annotations suffixes use ComputePeriodAtThisDate schedule
+	// other past ranges are calculated once (at 2 AM), ranges ending now at hours: 1, 7, 13, 19
+	var testCases = []struct {
+		data       string
+		dt         string
+		expected   bool
+		computeAll bool
+	}{
+		{data: "d;1 day;;", dt: "2018-05-16 10", expected: true},
+		{data: "w;1 week;;", dt: "2018-05-16 10", expected: false},
+		{data: "anno_0_now;;2018-01-01 00:00:00;2018-05-17 00:00:00", dt: "2018-05-16 13", expected: true},
+		{data: "cncf_now;;2016-03-10 00:00:00;2018-05-17 00:00:00", dt: "2018-05-16 3", expected: true},
+		{data: "tq;;2018-04-01 00:00:00;2018-05-17 00:00:00", dt: "2018-05-16 7", expected: true},
+		{data: "tq;;2018-04-01 00:00:00;2018-05-17 00:00:00", dt: "2018-05-16 2", expected: false},
+		{data: "pq;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 2", expected: true},
+		{data: "pq;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 7", expected: false},
+		{data: "pq;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 7", expected: true, computeAll: true},
+		{data: "l2w;2 weeks;;", dt: "2018-05-16 19", expected: true},
+		{data: "l2w;2 weeks;;", dt: "2018-05-16 20", expected: false},
+		{data: "d10;10 days;;", dt: "2018-05-16 9", expected: true},
+		{data: "d10;10 days;;", dt: "2018-05-16 10", expected: false},
+		{data: "anno_1_2;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 2", expected: true},
+		{data: "a;3 days;;", dt: "2018-05-16 7", expected: true},
+		{data: "ab;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 2", expected: true},
+		{data: "ab;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 7", expected: false},
+		{data: "c1;;2018-01-01 00:00:00;2018-04-01 00:00:00", dt: "2018-05-16 3", expected: false},
+		{data: "month;1 month;;", dt: "2018-05-16 13", expected: true},
+		{data: "month;1 month;;", dt: "2018-05-16 23", expected: false},
+		{data: "h24;24 hours;;", dt: "2018-05-16 20", expected: false},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		ctx := lib.Ctx{ComputeAll: test.computeAll}
+		dt := lib.TimeParseAny(test.dt)
+		got := lib.ComputeQuickRangeAtThisDate(&ctx, test.data, dt)
+		if got != test.expected {
+			t.Errorf("test number %d, expected %v, got %v for %s at %v", index+1, test.expected, got, test.data, dt)
+		}
+	}
+}
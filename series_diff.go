@@ -0,0 +1,331 @@
+package devstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+)
+
+// SeriesPoint - single time series point
+type SeriesPoint struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   time.Time
+}
+
+// key - series name, time and sorted tags, identifies a point like in InfluxDB
+func (p *SeriesPoint) key() string {
+	key := p.Name + "\x00" + ToYMDHMSDate(p.Time)
+	for _, tag := range sortedKeys(p.Tags) {
+		key += "\x00" + tag + "=" + p.Tags[tag]
+	}
+	return key
+}
+
+// SeriesFieldDiff - single field of a point that was added, removed or changed
+type SeriesFieldDiff struct {
+	Time  time.Time         `json:"time"`
+	Tags  map[string]string `json:"tags,omitempty"`
+	Field string            `json:"field"`
+	Old   interface{}       `json:"old,omitempty"`
+	New   interface{}       `json:"new,omitempty"`
+}
+
+// SeriesDiff - differences between computed and existing points of a single series
+type SeriesDiff struct {
+	Series  string            `json:"series"`
+	Added   []SeriesFieldDiff `json:"added,omitempty"`
+	Removed []SeriesFieldDiff `json:"removed,omitempty"`
+	Changed []SeriesFieldDiff `json:"changed,omitempty"`
+}
+
+// seriesFloat - returns numeric value as float64
+func seriesFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// seriesValuesEqual - compares values, numbers are equal when they differ by no more than tolerance
+func seriesValuesEqual(a, b interface{}, tolerance float64) bool {
+	fa, okA := seriesFloat(a)
+	fb, okB := seriesFloat(b)
+	if okA && okB {
+		return math.Abs(fa-fb) <= tolerance
+	}
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+// DiffSeriesPoints - compares computed points with existing ones, returns differences of changed series sorted by name
+// Field is added when computed point has it and existing doesn't, removed in the opposite case
+func DiffSeriesPoints(computed, existing []SeriesPoint, tolerance float64) []SeriesDiff {
+	existingMap := make(map[string]*SeriesPoint)
+	for i := range existing {
+		existingMap[existing[i].key()] = &existing[i]
+	}
+	computedMap := make(map[string]*SeriesPoint)
+	for i := range computed {
+		computedMap[computed[i].key()] = &computed[i]
+	}
+	keys := []string{}
+	for key := range computedMap {
+		keys = append(keys, key)
+	}
+	for key := range existingMap {
+		if _, ok := computedMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	diffs := make(map[string]*SeriesDiff)
+	getDiff := func(name string) *SeriesDiff {
+		diff, ok := diffs[name]
+		if !ok {
+			diff = &SeriesDiff{Series: name}
+			diffs[name] = diff
+		}
+		return diff
+	}
+	for _, key := range keys {
+		newPt, okNew := computedMap[key]
+		oldPt, okOld := existingMap[key]
+		pt := newPt
+		if !okNew {
+			pt = oldPt
+		}
+		newFields := map[string]interface{}{}
+		if okNew {
+			newFields = newPt.Fields
+		}
+		oldFields := map[string]interface{}{}
+		if okOld {
+			oldFields = oldPt.Fields
+		}
+		fields := []string{}
+		for field := range newFields {
+			fields = append(fields, field)
+		}
+		for field := range oldFields {
+			if _, ok := newFields[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			newValue, okNewValue := newFields[field]
+			oldValue, okOldValue := oldFields[field]
+			fieldDiff := SeriesFieldDiff{Time: pt.Time, Tags: pt.Tags, Field: field, Old: oldValue, New: newValue}
+			if len(fieldDiff.Tags) == 0 {
+				fieldDiff.Tags = nil
+			}
+			if !okOldValue {
+				diff := getDiff(pt.Name)
+				diff.Added = append(diff.Added, fieldDiff)
+			} else if !okNewValue {
+				diff := getDiff(pt.Name)
+				diff.Removed = append(diff.Removed, fieldDiff)
+			} else if !seriesValuesEqual(newValue, oldValue, tolerance) {
+				diff := getDiff(pt.Name)
+				diff.Changed = append(diff.Changed, fieldDiff)
+			}
+		}
+	}
+	names := []string{}
+	for name := range diffs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := []SeriesDiff{}
+	for _, name := range names {
+		result = append(result, *diffs[name])
+	}
+	return result
+}
+
+// FormatSeriesDiff - returns series differences as text, or as a single JSON line when `jsonOut` is set
+func FormatSeriesDiff(diff *SeriesDiff, jsonOut bool) string {
+	if jsonOut {
+		data, err := json.Marshal(diff)
+		FatalOnError(err)
+		return string(data) + "\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %d added, %d removed, %d changed\n", diff.Series, len(diff.Added), len(diff.Removed), len(diff.Changed)))
+	line := func(status string, fieldDiff *SeriesFieldDiff, value string) {
+		tags := ""
+		if len(fieldDiff.Tags) > 0 {
+			pairs := []string{}
+			for _, tag := range sortedKeys(fieldDiff.Tags) {
+				pairs = append(pairs, tag+"="+fieldDiff.Tags[tag])
+			}
+			tags = " {" + strings.Join(pairs, ",") + "}"
+		}
+		sb.WriteString(fmt.Sprintf("  %-7s %s%s %s: %s\n", status, ToYMDHMSDate(fieldDiff.Time), tags, fieldDiff.Field, value))
+	}
+	for i := range diff.Added {
+		line("added", &diff.Added[i], fmt.Sprintf("%v", diff.Added[i].New))
+	}
+	for i := range diff.Removed {
+		line("removed", &diff.Removed[i], fmt.Sprintf("%v", diff.Removed[i].Old))
+	}
+	for i := range diff.Changed {
+		line("changed", &diff.Changed[i], fmt.Sprintf("%v -> %v", diff.Changed[i].Old, diff.Changed[i].New))
+	}
+	return sb.String()
+}
+
+// seriesDiffMutex - serializes diff output from multiple go routines
+var seriesDiffMutex sync.Mutex
+
+// WriteSeriesDiffs - outputs differences to GHA2DB_DIFF_FILE (appends) or to stdout
+func WriteSeriesDiffs(ctx *Ctx, diffs []SeriesDiff) error {
+	if len(diffs) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	for i := range diffs {
+		sb.WriteString(FormatSeriesDiff(&diffs[i], ctx.DiffJSON))
+	}
+	seriesDiffMutex.Lock()
+	defer seriesDiffMutex.Unlock()
+	var out io.Writer = os.Stdout
+	if ctx.DiffFile != "" {
+		file, err := os.OpenFile(ctx.DiffFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		out = file
+	}
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
+
+// DiffSink - series sink used in GHA2DB_DIFF mode, it never writes series
+// Instead it reads existing InfluxDB points of the same series in the same time range and outputs differences
+type DiffSink struct {
+	ctx    *Ctx
+	con    client.Client
+	points []SeriesPoint
+}
+
+// NewDiffSink - connects to InfluxDB holding current series
+func NewDiffSink(ctx *Ctx) *DiffSink {
+	return &DiffSink{ctx: ctx, con: IDBConn(ctx)}
+}
+
+// AddPoint - remembers computed point, tags and fields are copied because callers reuse them
+// Empty tags are skipped, InfluxDB doesn't store them
+func (s *DiffSink) AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time) {
+	pt := SeriesPoint{Name: name, Tags: make(map[string]string), Fields: make(map[string]interface{}), Time: dt.UTC().Truncate(time.Hour)}
+	for k, v := range tags {
+		if v != "" {
+			pt.Tags[k] = v
+		}
+	}
+	for k, v := range fields {
+		pt.Fields[k] = v
+	}
+	s.points = append(s.points, pt)
+}
+
+// DeleteSeries - nothing is deleted in diff mode
+func (s *DiffSink) DeleteSeries(name string, suffixes map[string]string) {
+	if s.ctx.Debug > 0 {
+		Printf("Diff mode: not deleting '%s' series\n", name)
+	}
+}
+
+// Write - compares computed points with existing ones and outputs differences
+func (s *DiffSink) Write() error {
+	if len(s.points) == 0 {
+		return nil
+	}
+	diffs := DiffSeriesPoints(s.points, s.existing(), s.ctx.DiffTolerance)
+	if s.ctx.Debug > 0 {
+		Printf("Diff mode: %d points compared, %d series differ\n", len(s.points), len(diffs))
+	}
+	s.points = nil
+	return WriteSeriesDiffs(s.ctx, diffs)
+}
+
+// Close - closes InfluxDB connection
+func (s *DiffSink) Close() {
+	FatalOnError(s.con.Close())
+}
+
+// existing - returns existing InfluxDB points of computed series (and GHA2DB_DIFF_SERIES) in computed points time range
+func (s *DiffSink) existing() (points []SeriesPoint) {
+	set := make(map[string]struct{})
+	from, to := s.points[0].Time, s.points[0].Time
+	for _, pt := range s.points {
+		set[pt.Name] = struct{}{}
+		if pt.Time.Before(from) {
+			from = pt.Time
+		}
+		if pt.Time.After(to) {
+			to = pt.Time
+		}
+	}
+	names := []string{}
+	for name := range set {
+		names = append(names, "\""+strings.Replace(name, "\"", "\\\"", -1)+"\"")
+	}
+	sort.Strings(names)
+	where := " where time >= '" + ToIDBDate(from) + "' and time <= '" + ToIDBDate(to) + "' group by *"
+	froms := []string{}
+	for i := 0; i < len(names); i += 50 {
+		j := i + 50
+		if j > len(names) {
+			j = len(names)
+		}
+		froms = append(froms, strings.Join(names[i:j], ","))
+	}
+	if s.ctx.DiffSeries != "" {
+		froms = append(froms, s.ctx.DiffSeries)
+	}
+	for _, from := range froms {
+		for _, res := range QueryIDB(s.con, s.ctx, "select * from "+from+where) {
+			for _, row := range res.Series {
+				tags := make(map[string]string)
+				for k, v := range row.Tags {
+					if v != "" {
+						tags[k] = v
+					}
+				}
+				for _, values := range row.Values {
+					pt := SeriesPoint{Name: row.Name, Tags: tags, Fields: make(map[string]interface{})}
+					for i, column := range row.Columns {
+						if column == TimeCol {
+							pt.Time = TimeParseIDB(values[i].(string))
+						} else if values[i] != nil {
+							pt.Fields[column] = values[i]
+						}
+					}
+					points = append(points, pt)
+				}
+			}
+		}
+	}
+	return
+}
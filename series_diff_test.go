@@ -0,0 +1,114 @@
+package devstats
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestDiffSeriesPoints(t *testing.T) {
+	ft := testlib.YMDHMS
+	dt := ft(2018, 1, 1)
+	// Test cases
+	var testCases = []struct {
+		computed  []lib.SeriesPoint
+		existing  []lib.SeriesPoint
+		tolerance float64
+		expected  []lib.SeriesDiff
+	}{
+		{
+			computed:  []lib.SeriesPoint{{Name: "s", Fields: map[string]interface{}{"value": 1.0}, Time: dt}},
+			existing:  []lib.SeriesPoint{{Name: "s", Fields: map[string]interface{}{"value": json.Number("1")}, Time: dt}},
+			tolerance: 0,
+			expected:  []lib.SeriesDiff{},
+		},
+		{
+			computed:  []lib.SeriesPoint{{Name: "s", Fields: map[string]interface{}{"value": 1.005}, Time: dt}},
+			existing:  []lib.SeriesPoint{{Name: "s", Fields: map[string]interface{}{"value": json.Number("1")}, Time: dt}},
+			tolerance: 0.01,
+			expected:  []lib.SeriesDiff{},
+		},
+		{
+			computed: []lib.SeriesPoint{
+				{Name: "s", Fields: map[string]interface{}{"value": 2.0, "descr": "2 PRs"}, Time: dt},
+				{Name: "new", Fields: map[string]interface{}{"value": 3.0}, Time: dt},
+			},
+			existing: []lib.SeriesPoint{
+				{Name: "s", Fields: map[string]interface{}{"value": json.Number("1"), "descr": "2 PRs", "old": json.Number("5")}, Time: dt},
+				{Name: "gone", Tags: map[string]string{"repo": "r"}, Fields: map[string]interface{}{"value": json.Number("4")}, Time: dt},
+			},
+			tolerance: 0.01,
+			expected: []lib.SeriesDiff{
+				{
+					Series:  "gone",
+					Removed: []lib.SeriesFieldDiff{{Time: dt, Tags: map[string]string{"repo": "r"}, Field: "value", Old: json.Number("4")}},
+				},
+				{
+					Series: "new",
+					Added:  []lib.SeriesFieldDiff{{Time: dt, Field: "value", New: 3.0}},
+				},
+				{
+					Series:  "s",
+					Removed: []lib.SeriesFieldDiff{{Time: dt, Field: "old", Old: json.Number("5")}},
+					Changed: []lib.SeriesFieldDiff{{Time: dt, Field: "value", Old: json.Number("1"), New: 2.0}},
+				},
+			},
+		},
+		{
+			computed: []lib.SeriesPoint{
+				{Name: "h", Tags: map[string]string{"a": "1"}, Fields: map[string]interface{}{"name": "x"}, Time: dt},
+				{Name: "h", Tags: map[string]string{"a": "2"}, Fields: map[string]interface{}{"name": "y"}, Time: dt},
+			},
+			existing: []lib.SeriesPoint{
+				{Name: "h", Tags: map[string]string{"a": "1"}, Fields: map[string]interface{}{"name": "z"}, Time: dt},
+				{Name: "h", Tags: map[string]string{"a": "2"}, Fields: map[string]interface{}{"name": "y"}, Time: ft(2018, 1, 1, 1)},
+			},
+			tolerance: 0,
+			expected: []lib.SeriesDiff{
+				{
+					Series: "h",
+					Added:  []lib.SeriesFieldDiff{{Time: dt, Tags: map[string]string{"a": "2"}, Field: "name", New: "y"}},
+					Removed: []lib.SeriesFieldDiff{
+						{Time: ft(2018, 1, 1, 1), Tags: map[string]string{"a": "2"}, Field: "name", Old: "y"},
+					},
+					Changed: []lib.SeriesFieldDiff{{Time: dt, Tags: map[string]string{"a": "1"}, Field: "name", Old: "z", New: "x"}},
+				},
+			},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.DiffSeriesPoints(test.computed, test.existing, test.tolerance)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test number %d, expected %+v, got %+v", index+1, test.expected, got)
+		}
+	}
+}
+
+func TestFormatSeriesDiff(t *testing.T) {
+	ft := testlib.YMDHMS
+	diff := lib.SeriesDiff{
+		Series:  "s",
+		Added:   []lib.SeriesFieldDiff{{Time: ft(2018, 1, 1), Tags: map[string]string{"b": "2", "a": "1"}, Field: "value", New: 3.0}},
+		Removed: []lib.SeriesFieldDiff{{Time: ft(2018, 1, 2), Field: "descr", Old: "x"}},
+		Changed: []lib.SeriesFieldDiff{{Time: ft(2018, 1, 3, 4), Field: "value", Old: json.Number("1.5"), New: 2.0}},
+	}
+	expected := "s: 1 added, 1 removed, 1 changed\n" +
+		"  added   2018-01-01 00:00:00 {a=1,b=2} value: 3\n" +
+		"  removed 2018-01-02 00:00:00 descr: x\n" +
+		"  changed 2018-01-03 04:00:00 value: 1.5 -> 2\n"
+	got := lib.FormatSeriesDiff(&diff, false)
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	expected = `{"series":"s","added":[{"time":"2018-01-01T00:00:00Z","tags":{"a":"1","b":"2"},"field":"value","new":3}],` +
+		`"removed":[{"time":"2018-01-02T00:00:00Z","field":"descr","old":"x"}],` +
+		`"changed":[{"time":"2018-01-03T04:00:00Z","field":"value","old":1.5,"new":2}]}` + "\n"
+	got = lib.FormatSeriesDiff(&diff, true)
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,319 @@
+package devstats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+)
+
+// Time series sink types (GHA2DB_SERIES_SINKS values)
+const (
+	SeriesSinkInflux   string = "influx"
+	SeriesSinkPostgres string = "postgres"
+	SeriesSinkFile     string = "file"
+)
+
+// SeriesSink - time series output used by `db2influx`, `z2influx`, `idb_tags`, `idb_vars`, `idb_backup` and annotations
+// Points are buffered by AddPoint and written by Write, DeleteSeries is executed immediately
+// Sink is not safe for concurrent use, each go routine should use its own sink
+// Reading series (like checking already computed quick ranges) still uses InfluxDB directly
+type SeriesSink interface {
+	// AddPoint - adds point to write, points are stored with hour precision
+	AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time)
+	// DeleteSeries - deletes series points, when `suffixes` are given - only points with tag values ending with given suffix
+	DeleteSeries(name string, suffixes map[string]string)
+	// Write - writes all points added since the last Write
+	Write() error
+	// Close - closes connections
+	Close()
+}
+
+// NewSeriesSink - returns sink writing to all outputs given in GHA2DB_SERIES_SINKS (InfluxDB when not set)
+// In GHA2DB_DIFF mode returns sink that only outputs differences from existing InfluxDB series
+func NewSeriesSink(ctx *Ctx) SeriesSink {
+	if ctx.SeriesDiff {
+		return NewDiffSink(ctx)
+	}
+	types := ctx.SeriesSinks
+	if len(types) == 0 {
+		types = []string{SeriesSinkInflux}
+	}
+	sinks := []SeriesSink{}
+	for _, typ := range types {
+		switch strings.TrimSpace(typ) {
+		case SeriesSinkInflux:
+			sinks = append(sinks, NewInfluxSink(ctx))
+		case SeriesSinkPostgres:
+			sinks = append(sinks, NewPostgresSink(ctx))
+		case SeriesSinkFile:
+			sinks = append(sinks, NewFileSink(ctx))
+		default:
+			Fatalf("unknown series sink: '%s', allowed: %s, %s, %s", typ, SeriesSinkInflux, SeriesSinkPostgres, SeriesSinkFile)
+		}
+	}
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return &MultiSink{Sinks: sinks}
+}
+
+// InfluxSink - writes series to InfluxDB 1.x database GHA2DB_IDB_DB (batches of GHA2DB_IDB_MAXBATCHPOINTS points)
+type InfluxSink struct {
+	ctx *Ctx
+	con client.Client
+	pts IDBBatchPointsN
+}
+
+// NewInfluxSink - connects to InfluxDB
+func NewInfluxSink(ctx *Ctx) *InfluxSink {
+	sink := &InfluxSink{ctx: ctx, con: IDBConn(ctx)}
+	sink.reset()
+	return sink
+}
+
+// reset - starts a new batch
+func (s *InfluxSink) reset() {
+	bp := IDBBatchPoints(s.ctx, &s.con)
+	s.pts = IDBBatchPointsN{Points: &bp}
+}
+
+// AddPoint - adds point to the batch
+func (s *InfluxSink) AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time) {
+	IDBAddPointN(s.ctx, &s.con, &s.pts, IDBNewPointWithErr(s.ctx, name, tags, fields, dt))
+}
+
+// DeleteSeries - deletes series points
+func (s *InfluxSink) DeleteSeries(name string, suffixes map[string]string) {
+	query := "delete from \"" + name + "\""
+	conds := []string{}
+	for _, tag := range sortedKeys(suffixes) {
+		conds = append(conds, tag+" =~ /"+strings.Replace(regexp.QuoteMeta(suffixes[tag]), "/", `\/`, -1)+"$/")
+	}
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+	QueryIDB(s.con, s.ctx, query)
+}
+
+// Write - writes batch points
+func (s *InfluxSink) Write() error {
+	err := IDBWritePointsN(s.ctx, &s.con, &s.pts)
+	s.reset()
+	return err
+}
+
+// Close - closes InfluxDB connection
+func (s *InfluxSink) Close() {
+	FatalOnError(s.con.Close())
+}
+
+// seriesPoint - point buffered by Postgres sink
+type seriesPoint struct {
+	name   string
+	dt     time.Time
+	tags   string
+	fields map[string]interface{}
+}
+
+// PostgresSink - writes series to `gha_series_points` table (series name, time, tags and fields as jsonb)
+// The table can be converted to TimescaleDB hypertable, see `util_sql/series_points_table.sql`
+// Like in InfluxDB, writing a point with the same series, time and tags updates its fields
+type PostgresSink struct {
+	ctx    *Ctx
+	con    *sql.DB
+	points map[string]*seriesPoint
+	order  []string
+}
+
+// NewPostgresSink - connects to Postgres database GHA2DB_PG_DB
+func NewPostgresSink(ctx *Ctx) *PostgresSink {
+	return &PostgresSink{ctx: ctx, con: PgConn(ctx), points: make(map[string]*seriesPoint)}
+}
+
+// AddPoint - adds point to write, fields of the same point added again are merged
+func (s *PostgresSink) AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time) {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	jsonTags, err := json.Marshal(tags)
+	FatalOnError(err)
+	dt = dt.UTC().Truncate(time.Hour)
+	key := name + "\x00" + ToYMDHDate(dt) + "\x00" + string(jsonTags)
+	point, ok := s.points[key]
+	if !ok {
+		point = &seriesPoint{name: name, dt: dt, tags: string(jsonTags), fields: make(map[string]interface{})}
+		s.points[key] = point
+		s.order = append(s.order, key)
+	}
+	for field, value := range fields {
+		point.fields[field] = value
+	}
+}
+
+// DeleteSeries - deletes series points
+func (s *PostgresSink) DeleteSeries(name string, suffixes map[string]string) {
+	query := "delete from gha_series_points where series = $1"
+	args := []interface{}{name}
+	for _, tag := range sortedKeys(suffixes) {
+		args = append(args, tag, suffixes[tag])
+		value := NValue(len(args)) + "::text"
+		query += " and right(tags->>" + NValue(len(args)-1) + "::text, length(" + value + ")) = " + value
+	}
+	ExecSQLWithErr(s.con, s.ctx, query, args...)
+}
+
+// Write - upserts all points in a single transaction
+func (s *PostgresSink) Write() error {
+	if len(s.order) == 0 {
+		return nil
+	}
+	tx, err := s.con.Begin()
+	if err != nil {
+		return err
+	}
+	maxRows := MaxQueryParams / 4
+	for from := 0; from < len(s.order); from += maxRows {
+		to := from + maxRows
+		if to > len(s.order) {
+			to = len(s.order)
+		}
+		args := []interface{}{}
+		for _, key := range s.order[from:to] {
+			point := s.points[key]
+			jsonFields, err := json.Marshal(point.fields)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			args = append(args, point.name, point.dt, point.tags, string(jsonFields))
+		}
+		_, err = ExecSQLTx(
+			tx,
+			s.ctx,
+			"insert into gha_series_points(series, time, tags, fields) "+NMultiValues(to-from, 4)+
+				" on conflict(series, time, tags) do update set fields = gha_series_points.fields || excluded.fields",
+			args...,
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if s.ctx.Debug > 0 {
+		Printf("Written %d points to gha_series_points\n", len(s.order))
+	}
+	s.points = make(map[string]*seriesPoint)
+	s.order = []string{}
+	return tx.Commit()
+}
+
+// Close - closes Postgres connection
+func (s *PostgresSink) Close() {
+	FatalOnError(s.con.Close())
+}
+
+// seriesFileMutex - serializes appends to line protocol files from multiple go routines
+var seriesFileMutex sync.Mutex
+
+// FileSink - appends series to InfluxDB line protocol file GHA2DB_SERIES_FILE (nanosecond precision)
+// The file can be loaded using `influx -import` or Telegraf, deletes are not supported and ignored
+type FileSink struct {
+	ctx   *Ctx
+	path  string
+	lines []string
+}
+
+// NewFileSink - returns sink appending to GHA2DB_SERIES_FILE
+func NewFileSink(ctx *Ctx) *FileSink {
+	return &FileSink{ctx: ctx, path: strings.Replace(ctx.SeriesFile, "{{project}}", ctx.Project, -1)}
+}
+
+// AddPoint - adds point's line
+func (s *FileSink) AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time) {
+	pt := IDBNewPointWithErr(s.ctx, name, tags, fields, dt.Truncate(time.Hour))
+	s.lines = append(s.lines, pt.String())
+}
+
+// DeleteSeries - not supported by line protocol files
+func (s *FileSink) DeleteSeries(name string, suffixes map[string]string) {
+	if s.ctx.Debug > 0 {
+		Printf("File sink %s: ignoring delete of '%s' series\n", s.path, name)
+	}
+}
+
+// Write - appends all lines to the file
+func (s *FileSink) Write() error {
+	if len(s.lines) == 0 {
+		return nil
+	}
+	seriesFileMutex.Lock()
+	defer seriesFileMutex.Unlock()
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(strings.Join(s.lines, "\n") + "\n")
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.lines = []string{}
+	return file.Close()
+}
+
+// Close - nothing to close, file is only open while writing
+func (s *FileSink) Close() {
+}
+
+// MultiSink - writes series to all given sinks, used to run multiple backends side by side
+type MultiSink struct {
+	Sinks []SeriesSink
+}
+
+// AddPoint - adds point to all sinks
+func (s *MultiSink) AddPoint(name string, tags map[string]string, fields map[string]interface{}, dt time.Time) {
+	for _, sink := range s.Sinks {
+		sink.AddPoint(name, tags, fields, dt)
+	}
+}
+
+// DeleteSeries - deletes series points in all sinks
+func (s *MultiSink) DeleteSeries(name string, suffixes map[string]string) {
+	for _, sink := range s.Sinks {
+		sink.DeleteSeries(name, suffixes)
+	}
+}
+
+// Write - writes to all sinks, returns the first error
+func (s *MultiSink) Write() (err error) {
+	for _, sink := range s.Sinks {
+		e := sink.Write()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// Close - closes all sinks
+func (s *MultiSink) Close() {
+	for _, sink := range s.Sinks {
+		sink.Close()
+	}
+}
+
+// sortedKeys - returns map keys sorted
+func sortedKeys(m map[string]string) (keys []string) {
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
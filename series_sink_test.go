@@ -0,0 +1,54 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestFileSink(t *testing.T) {
+	ft := testlib.YMDHMS
+	dir, err := ioutil.TempDir("", "devstats_series")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	ctx := lib.Ctx{
+		Project:     "proj",
+		SeriesSinks: []string{"file", "file"},
+		SeriesFile:  dir + "/series_{{project}}.lp",
+	}
+
+	// Two file sinks (multi sink) write each point twice, points are written with hour precision
+	sink := lib.NewSeriesSink(&ctx)
+	sink.AddPoint("reviewers_d", nil, map[string]interface{}{"value": 2.5}, ft(2018, 1, 1, 10, 30))
+	sink.AddPoint("quick_ranges", map[string]string{"quick_ranges_suffix": "w"}, map[string]interface{}{"name": "Last week"}, ft(2014))
+	sink.DeleteSeries("quick_ranges", map[string]string{"quick_ranges_suffix": "_now"})
+	err = sink.Write()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.AddPoint("reviewers_d", nil, map[string]interface{}{"value": 3.0}, ft(2018, 1, 2))
+	err = sink.Write()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.Close()
+
+	data, err := ioutil.ReadFile(dir + "/series_proj.lp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "reviewers_d value=2.5 1514800800000000000\n" +
+		"quick_ranges,quick_ranges_suffix=w name=\"Last week\" 1388534400000000000\n" +
+		"reviewers_d value=2.5 1514800800000000000\n" +
+		"quick_ranges,quick_ranges_suffix=w name=\"Last week\" 1388534400000000000\n" +
+		"reviewers_d value=3 1514851200000000000\n" +
+		"reviewers_d value=3 1514851200000000000\n"
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, string(data))
+	}
+}
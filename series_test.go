@@ -413,7 +413,7 @@ func TestProcessAnnotations(t *testing.T) {
 	// Execute test cases
 	for index, test := range testCases {
 		// Execute annotations & quick ranges call
-		lib.ProcessAnnotations(&ctx, &test.annotations, test.startDate, test.joinDate)
+		lib.ProcessAnnotations(&ctx, &test.annotations, nil, test.startDate, test.joinDate)
 
 		// Check annotations created
 		gotAnnotations := getIDBResult(lib.QueryIDB(con, &ctx, "select * from annotations"))
@@ -0,0 +1,224 @@
+package devstats
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQL template variables available in metrics SQL files
+const (
+	SQLVarFrom        string = "from"
+	SQLVarTo          string = "to"
+	SQLVarN           string = "n"
+	SQLVarPeriod      string = "period"
+	SQLVarExcludeBots string = "exclude_bots"
+	SQLVarLim         string = "lim"
+	SQLVarProject     string = "project"
+	SQLVarRepoGroup   string = "repo_group"
+	SQLVarAfter       string = "after"
+)
+
+// SQLTemplateVars - declared SQL template variables and their descriptions
+// Tools can set other variables too (`runq` sets any "{{name}}" given on command line)
+var SQLTemplateVars = map[string]string{
+	SQLVarFrom:        "interval start 'YYYY-MM-DD HH:MI:SS' (`db2influx`), quick range start SQL expression (histograms using annotations ranges)",
+	SQLVarTo:          "interval end 'YYYY-MM-DD HH:MI:SS' (`db2influx`), quick range end SQL expression (histograms using annotations ranges)",
+	SQLVarN:           "number of periods in the interval, like 7.0 for 7 days moving average",
+	SQLVarPeriod:      "histogram period, like '1 week' or '3 month'",
+	SQLVarExcludeBots: "bots exclusion condition, defaults to util_sql/exclude_bots.sql",
+	SQLVarLim:         "maximum number of rows (`idb_tags` tag values, `ghapi2db` backfill chunk)",
+	SQLVarProject:     "project name, defaults to GHA2DB_PROJECT",
+	SQLVarRepoGroup:   "repository group name",
+	SQLVarAfter:       "only return rows with ID greater than this (`ghapi2db` backfill cursor)",
+}
+
+// sqlTemplateIncludes - variables defaulting to SQL fragment files
+var sqlTemplateIncludes = map[string]string{
+	SQLVarExcludeBots: "util_sql/exclude_bots.sql",
+}
+
+// sqlTemplateRe - matches "{{...}}"
+var sqlTemplateRe = regexp.MustCompile(`{{([^{}]*)}}`)
+
+// sqlTemplateMaxDepth - maximum nesting of includes
+const sqlTemplateMaxDepth = 8
+
+// SQLVars - values of SQL template variables
+// Values are inserted as given (they are SQL fragments), use SetString for values that are used inside single quotes
+type SQLVars struct {
+	values      map[string]string
+	quickRange  bool
+	rangePeriod string
+	rangeFrom   string
+	rangeTo     string
+}
+
+// NewSQLVars - returns empty variables
+func NewSQLVars() *SQLVars {
+	return &SQLVars{values: make(map[string]string)}
+}
+
+// Set - sets variable to SQL fragment
+func (v *SQLVars) Set(name, value string) *SQLVars {
+	v.values[name] = value
+	return v
+}
+
+// SetString - sets variable used inside single quoted SQL string, escapes single quotes
+func (v *SQLVars) SetString(name, value string) *SQLVars {
+	v.values[name] = strings.Replace(value, "'", "''", -1)
+	return v
+}
+
+// SetQuickRange - sets "{{period:column}}" conditions and {{from}}, {{to}} SQL expressions
+// Either `period` (last period till now, like '1 week') or `from` and `to` dates are used
+func (v *SQLVars) SetQuickRange(period, from, to string) *SQLVars {
+	escape := func(s string) string { return strings.Replace(s, "'", "''", -1) }
+	v.quickRange = true
+	v.rangePeriod, v.rangeFrom, v.rangeTo = escape(period), escape(from), escape(to)
+	if period != "" {
+		v.values[SQLVarFrom] = "(now() -'" + v.rangePeriod + "'::interval)"
+		v.values[SQLVarTo] = "(now())"
+	} else {
+		v.values[SQLVarFrom] = "'" + v.rangeFrom + "'"
+		v.values[SQLVarTo] = "'" + v.rangeTo + "'"
+	}
+	return v
+}
+
+// quickRangeCondition - returns condition on column for "{{period:column}}"
+func quickRangeCondition(column, period, from, to string) string {
+	if period != "" {
+		return " (" + column + " >= now() - '" + period + "'::interval) "
+	}
+	return " (" + column + " >= '" + from + "' and " + column + " < '" + to + "') "
+}
+
+// MetricSQLVars - variables of `db2influx` metric computed for [from, to) range, `n` is number of periods in the range
+func MetricSQLVars(from, to time.Time, n int) *SQLVars {
+	return NewSQLVars().
+		SetString(SQLVarFrom, ToYMDHMSDate(from)).
+		SetString(SQLVarTo, ToYMDHMSDate(to)).
+		Set(SQLVarN, strconv.Itoa(n)+".0")
+}
+
+// HistogramSQLVars - variables of `db2influx` histogram computed for the last `n` intervals
+func HistogramSQLVars(interval string, n int) *SQLVars {
+	period := fmt.Sprintf("%d %s", n, interval)
+	if interval == Quarter {
+		period = fmt.Sprintf("%d month", n*3)
+	}
+	return NewSQLVars().
+		SetString(SQLVarPeriod, period).
+		Set(SQLVarN, strconv.Itoa(n)+".0")
+}
+
+// QuickRangeSQLVars - variables of `db2influx` histogram computed for a quick range (annotations ranges)
+func QuickRangeSQLVars(period, from, to string) *SQLVars {
+	return NewSQLVars().SetQuickRange(period, from, to)
+}
+
+// TagsSQLVars - variables of `idb_tags` tag query returning up to `lim` values
+func TagsSQLVars(lim int) *SQLVars {
+	return NewSQLVars().Set(SQLVarLim, strconv.Itoa(lim))
+}
+
+// sqlIncludes - cache of included files
+var (
+	sqlIncludes      = make(map[string]string)
+	sqlIncludesMutex sync.Mutex
+)
+
+// readSQLInclude - reads included SQL fragment (once), path is relative to dataPrefix, "/proj/" falls back to "/shared/"
+func readSQLInclude(ctx *Ctx, dataPrefix, path string) (string, error) {
+	path = dataPrefix + path
+	sqlIncludesMutex.Lock()
+	defer sqlIncludesMutex.Unlock()
+	data, ok := sqlIncludes[path]
+	if ok {
+		return data, nil
+	}
+	bytes, err := ReadFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	data = strings.TrimRight(string(bytes), "\n")
+	sqlIncludes[path] = data
+	return data, nil
+}
+
+// RenderSQL - replaces SQL template placeholders:
+// "{{name}}" - variable value, variables not set use defaults: {{exclude_bots}} includes util_sql/exclude_bots.sql, {{project}} is GHA2DB_PROJECT
+// "{{period:column}}" - quick range condition on column, requires SQLVars.SetQuickRange
+// "{{include:path}}" - SQL fragment from file (relative to data directory), includes can use placeholders too
+// Returns error listing all unknown variables and declared variables that are not set
+func RenderSQL(ctx *Ctx, dataPrefix, sqlTemplate string, vars *SQLVars) (string, error) {
+	errs := []string{}
+	res := renderSQL(ctx, dataPrefix, sqlTemplate, vars, 0, &errs)
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return res, nil
+}
+
+// renderSQL - replaces placeholders, adds unique errors to `errs`
+func renderSQL(ctx *Ctx, dataPrefix, sqlTemplate string, vars *SQLVars, depth int, errs *[]string) string {
+	addError := func(msg string) {
+		for _, e := range *errs {
+			if e == msg {
+				return
+			}
+		}
+		*errs = append(*errs, msg)
+	}
+	include := func(path string) string {
+		if depth >= sqlTemplateMaxDepth {
+			addError("includes nested too deep: " + path)
+			return ""
+		}
+		data, err := readSQLInclude(ctx, dataPrefix, path)
+		if err != nil {
+			addError(fmt.Sprintf("cannot include %s: %v", path, err))
+			return ""
+		}
+		return renderSQL(ctx, dataPrefix, data, vars, depth+1, errs)
+	}
+	return sqlTemplateRe.ReplaceAllStringFunc(
+		sqlTemplate,
+		func(placeholder string) string {
+			name := placeholder[2 : len(placeholder)-2]
+			if value, ok := vars.values[name]; ok {
+				return value
+			}
+			if strings.HasPrefix(name, "include:") {
+				return include(name[8:])
+			}
+			if strings.HasPrefix(name, "period:") {
+				if name == "period:" {
+					addError(placeholder + " requires a column name")
+				} else if !vars.quickRange {
+					addError(placeholder + " requires a quick range (histograms using annotations ranges, runq qr parameter)")
+				} else if vars.rangePeriod == "" && (vars.rangeFrom == "" || vars.rangeTo == "") {
+					addError(placeholder + " requires either non-empty period or non-empty from and to")
+				}
+				return quickRangeCondition(name[7:], vars.rangePeriod, vars.rangeFrom, vars.rangeTo)
+			}
+			if path, ok := sqlTemplateIncludes[name]; ok {
+				return include(path)
+			}
+			if name == SQLVarProject && ctx.Project != "" {
+				return strings.Replace(ctx.Project, "'", "''", -1)
+			}
+			if _, ok := SQLTemplateVars[name]; ok {
+				addError("variable " + placeholder + " is not set")
+			} else {
+				addError("unknown variable " + placeholder)
+			}
+			return placeholder
+		},
+	)
+}
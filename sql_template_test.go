@@ -0,0 +1,115 @@
+package devstats
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	lib "devstats"
+	testlib "devstats/test"
+)
+
+func TestRenderSQL(t *testing.T) {
+	ft := testlib.YMDHMS
+	dir, err := ioutil.TempDir("", "devstats_sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	dataPrefix := dir + "/"
+	if err = os.MkdirAll(dataPrefix+"util_sql", 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := map[string]string{
+		"util_sql/exclude_bots.sql": "not like all(array['%-bot'])\n",
+		"util_sql/recent.sql":       "created_at >= '{{from}}' and login {{exclude_bots}}",
+		"util_sql/loop.sql":         "{{include:util_sql/loop.sql}}",
+	}
+	for file, content := range files {
+		if err = ioutil.WriteFile(dataPrefix+file, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	ctx := lib.Ctx{Project: "proj"}
+
+	// Test cases
+	var testCases = []struct {
+		sql      string
+		vars     *lib.SQLVars
+		expected string
+		err      string
+	}{
+		{
+			sql:      "select 1",
+			vars:     lib.NewSQLVars(),
+			expected: "select 1",
+		},
+		{
+			sql:      "where d >= '{{from}}' and d < '{{to}}' and c / {{n}} and a {{exclude_bots}}",
+			vars:     lib.MetricSQLVars(ft(2018, 1, 1), ft(2018, 2, 1), 7),
+			expected: "where d >= '2018-01-01 00:00:00' and d < '2018-02-01 00:00:00' and c / 7.0 and a not like all(array['%-bot'])",
+		},
+		{
+			sql:      "where d >= now() - '{{period}}'::interval and {{n}}",
+			vars:     lib.HistogramSQLVars(lib.Quarter, 2),
+			expected: "where d >= now() - '6 month'::interval and 2.0",
+		},
+		{
+			sql:      "where {{period:e.created_at}} and d < {{to}} and {{exclude_bots}}",
+			vars:     lib.QuickRangeSQLVars("1 week", "", ""),
+			expected: "where  (e.created_at >= now() - '1 week'::interval)  and d < (now()) and not like all(array['%-bot'])",
+		},
+		{
+			sql:      "where {{period:e.created_at}} and d < {{to}}",
+			vars:     lib.QuickRangeSQLVars("", "2018-01-01", "2018-02-01"),
+			expected: "where  (e.created_at >= '2018-01-01' and e.created_at < '2018-02-01')  and d < '2018-02-01'",
+		},
+		{
+			sql:      "and ({{period:a.b}} and x is null) or {{period:c.d}} and {{from}}",
+			vars:     lib.QuickRangeSQLVars("3 months", "1982-07-16", "2017-12-01"),
+			expected: "and ( (a.b >= now() - '3 months'::interval)  and x is null) or  (c.d >= now() - '3 months'::interval)  and (now() -'3 months'::interval)",
+		},
+		{
+			sql:      "where {{include:util_sql/recent.sql}} limit {{lim}}",
+			vars:     lib.TagsSQLVars(69).SetString(lib.SQLVarFrom, "2018"),
+			expected: "where created_at >= '2018' and login not like all(array['%-bot']) limit 69",
+		},
+		{
+			sql:      "where p = '{{project}}' and g = '{{repo_group}}' and c in ({{companies}})",
+			vars:     lib.NewSQLVars().SetString(lib.SQLVarRepoGroup, "O'Reilly").Set("companies", "'A', 'B'"),
+			expected: "where p = 'proj' and g = 'O''Reilly' and c in ('A', 'B')",
+		},
+		{
+			sql:  "where '{{from}}' and {{lim}} and {{from}} and {{unknown}}",
+			vars: lib.NewSQLVars(),
+			err:  "variable {{from}} is not set, variable {{lim}} is not set, unknown variable {{unknown}}",
+		},
+		{
+			sql:  "where {{period:a}}",
+			vars: lib.MetricSQLVars(ft(2018), ft(2019), 1),
+			err:  "{{period:a}} requires a quick range (histograms using annotations ranges, runq qr parameter)",
+		},
+		{
+			sql:  "where {{period:a}} and {{period:}}",
+			vars: lib.QuickRangeSQLVars("", "", ""),
+			err:  "{{period:a}} requires either non-empty period or non-empty from and to, {{period:}} requires a column name",
+		},
+		{
+			sql:  "{{include:util_sql/missing.sql}} {{include:util_sql/loop.sql}}",
+			vars: lib.NewSQLVars(),
+			err: "cannot include util_sql/missing.sql: open " + dataPrefix + "util_sql/missing.sql: no such file or directory, " +
+				"includes nested too deep: util_sql/loop.sql",
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.RenderSQL(&ctx, dataPrefix, test.sql, test.vars)
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if got != test.expected || gotErr != test.err {
+			t.Errorf("test number %d, expected '%v' (error '%v'), got '%v' (error '%v')", index+1, test.expected, test.err, got, gotErr)
+		}
+	}
+}
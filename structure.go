@@ -109,7 +109,28 @@ func Structure(ctx *Ctx) {
 		)
 	}
 
+	// gha_companies_aliases: this is filled by `import_affs` tool from `companies.yaml`
+	// alias is a lower case company name spelling, company_name is its canonical name
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_companies_aliases")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_companies_aliases("+
+					"alias varchar(160) not null, "+
+					"company_name varchar(160) not null, "+
+					"primary key(alias)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index companies_aliases_company_name_idx on gha_companies_aliases(company_name)")
+	}
+
 	// gha_actors_affiliations: this is filled by `import_affs` tool, that uses cncf/gitdm:github_users.json
+	// company_name is normalized using companies aliases, original_company_name is the name from github_users.json
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_actors_affiliations")
 		ExecSQLWithErr(
@@ -119,6 +140,7 @@ func Structure(ctx *Ctx) {
 				"gha_actors_affiliations("+
 					"actor_id bigint not null, "+
 					"company_name varchar(160) not null, "+
+					"original_company_name varchar(160), "+
 					"dt_from {{ts}} not null, "+
 					"dt_to {{ts}} not null, "+
 					"primary key(actor_id, company_name, dt_from, dt_to)"+
@@ -851,6 +873,76 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index pull_requests_dupn_merged_by_login_idx on gha_pull_requests(dupn_merged_by_login)")
 	}
 
+	// gha_reviews, gha_reviews_comments
+	// Pull request reviews and review comments from GitHub API, written by `ghapi2db` with GHA2DB_GHAPI_REVIEWS
+	// const
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_reviews")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_reviews("+
+					"id bigint not null, "+
+					"pull_request_id bigint not null, "+
+					"number int not null, "+
+					"user_id bigint, "+
+					"state varchar(20) not null, "+
+					"body text, "+
+					"commit_id varchar(40), "+
+					"submitted_at {{ts}}, "+
+					"synced_at {{ts}} not null, "+
+					"dup_repo_id bigint not null, "+
+					"dup_repo_name varchar(160) not null, "+
+					"dup_user_login varchar(120) not null, "+
+					"primary key(id)"+
+					")",
+			),
+		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_reviews_comments")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_reviews_comments("+
+					"id bigint not null, "+
+					"pull_request_id bigint not null, "+
+					"number int not null, "+
+					"user_id bigint, "+
+					"body text, "+
+					"path text, "+
+					"position int, "+
+					"original_position int, "+
+					"commit_id varchar(40), "+
+					"original_commit_id varchar(40), "+
+					"in_reply_to bigint, "+
+					"created_at {{ts}} not null, "+
+					"updated_at {{ts}} not null, "+
+					"synced_at {{ts}} not null, "+
+					"dup_repo_id bigint not null, "+
+					"dup_repo_name varchar(160) not null, "+
+					"dup_user_login varchar(120) not null, "+
+					"primary key(id)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index reviews_pull_request_id_idx on gha_reviews(pull_request_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_user_id_idx on gha_reviews(user_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_state_idx on gha_reviews(state)")
+		ExecSQLWithErr(c, ctx, "create index reviews_submitted_at_idx on gha_reviews(submitted_at)")
+		ExecSQLWithErr(c, ctx, "create index reviews_dup_repo_id_idx on gha_reviews(dup_repo_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_dup_repo_name_idx on gha_reviews(dup_repo_name)")
+		ExecSQLWithErr(c, ctx, "create index reviews_dup_user_login_idx on gha_reviews(dup_user_login)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_pull_request_id_idx on gha_reviews_comments(pull_request_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_user_id_idx on gha_reviews_comments(user_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_created_at_idx on gha_reviews_comments(created_at)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_dup_repo_id_idx on gha_reviews_comments(dup_repo_id)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_dup_repo_name_idx on gha_reviews_comments(dup_repo_name)")
+		ExecSQLWithErr(c, ctx, "create index reviews_comments_dup_user_login_idx on gha_reviews_comments(dup_user_login)")
+	}
+
 	// gha_branches
 	// Table details and analysis in `analysis/analysis.txt` and `analysis/branch_*.json`
 	// Nullable keys: forkee: repo_id, actor: user_id
@@ -1001,6 +1093,41 @@ func Structure(ctx *Ctx) {
 					")",
 			),
 		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_commits_files_lines")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_commits_files_lines("+
+					"sha varchar(40) not null, "+
+					"path text not null, "+
+					"old_path text, "+
+					"status varchar(1) not null, "+
+					"added int, "+
+					"removed int, "+
+					"is_binary boolean not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(sha, path)"+
+					")",
+			),
+		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_commits_identities")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_commits_identities("+
+					"sha varchar(40) not null, "+
+					"author_name varchar(160) not null, "+
+					"author_email varchar(160) not null, "+
+					"author_date {{ts}} not null, "+
+					"committer_name varchar(160) not null, "+
+					"committer_email varchar(160) not null, "+
+					"committer_date {{ts}} not null, "+
+					"primary key(sha)"+
+					")",
+			),
+		)
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_skip_commits")
 		ExecSQLWithErr(
 			c,
@@ -1029,9 +1156,155 @@ func Structure(ctx *Ctx) {
 		ExecSQLWithErr(c, ctx, "create index events_commits_files_dup_repo_name_idx on gha_events_commits_files(dup_repo_name)")
 		ExecSQLWithErr(c, ctx, "create index events_commits_files_dup_type_idx on gha_events_commits_files(dup_type)")
 		ExecSQLWithErr(c, ctx, "create index events_commits_files_dup_created_at_idx on gha_events_commits_files(dup_created_at)")
+		ExecSQLWithErr(c, ctx, "create index commits_files_lines_sha_idx on gha_commits_files_lines(sha)")
+		ExecSQLWithErr(c, ctx, "create index commits_files_lines_path_idx on gha_commits_files_lines(path)")
+		ExecSQLWithErr(c, ctx, "create index commits_files_lines_status_idx on gha_commits_files_lines(status)")
+		ExecSQLWithErr(c, ctx, "create index commits_files_lines_dt_idx on gha_commits_files_lines(dt)")
+		ExecSQLWithErr(c, ctx, "create index commits_identities_author_email_idx on gha_commits_identities(author_email)")
+		ExecSQLWithErr(c, ctx, "create index commits_identities_author_date_idx on gha_commits_identities(author_date)")
+		ExecSQLWithErr(c, ctx, "create index commits_identities_committer_email_idx on gha_commits_identities(committer_email)")
+		ExecSQLWithErr(c, ctx, "create index commits_identities_committer_date_idx on gha_commits_identities(committer_date)")
 		ExecSQLWithErr(c, ctx, "create index skip_commits_sha_idx on gha_skip_commits(sha)")
 	}
 
+	// Resolved company per event and per commit, so metrics don't need to join affiliations date ranges
+	// gha_events_companies is filled by `util_sql/postprocess_companies.sql` postprocess script for every imported GHA hour
+	// gha_events_companies_hours holds `gha_imported_hours` update dates already attributed
+	// gha_commits_companies is filled by `get_repos` tool
+	// Both are recomputed by `import_affs` tool for actors with changed affiliations
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_events_companies")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_events_companies("+
+					"event_id bigint not null, "+
+					"actor_id bigint not null, "+
+					"company_name varchar(160) not null, "+
+					"created_at {{ts}} not null, "+
+					"primary key(event_id, company_name)"+
+					")",
+			),
+		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_events_companies_hours")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_events_companies_hours("+
+					"dt {{ts}} not null, "+
+					"imported_at {{ts}} not null, "+
+					"primary key(dt)"+
+					")",
+			),
+		)
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_commits_companies")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_commits_companies("+
+					"sha varchar(40) not null, "+
+					"actor_id bigint not null, "+
+					"author_email varchar(160) not null, "+
+					"company_name varchar(160) not null, "+
+					"dt {{ts}} not null, "+
+					"primary key(sha, actor_id, company_name)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index events_companies_event_id_idx on gha_events_companies(event_id)")
+		ExecSQLWithErr(c, ctx, "create index events_companies_actor_id_idx on gha_events_companies(actor_id)")
+		ExecSQLWithErr(c, ctx, "create index events_companies_company_name_idx on gha_events_companies(company_name)")
+		ExecSQLWithErr(c, ctx, "create index events_companies_created_at_idx on gha_events_companies(created_at)")
+		ExecSQLWithErr(c, ctx, "create index commits_companies_sha_idx on gha_commits_companies(sha)")
+		ExecSQLWithErr(c, ctx, "create index commits_companies_actor_id_idx on gha_commits_companies(actor_id)")
+		ExecSQLWithErr(c, ctx, "create index commits_companies_author_email_idx on gha_commits_companies(author_email)")
+		ExecSQLWithErr(c, ctx, "create index commits_companies_company_name_idx on gha_commits_companies(company_name)")
+		ExecSQLWithErr(c, ctx, "create index commits_companies_dt_idx on gha_commits_companies(dt)")
+	}
+
+	// GHA hours import checkpoints, used by `gha2db` and `gha2db_sync` tools to detect & heal missing hours
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_imported_hours")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_imported_hours("+
+					"dt {{ts}} not null, "+
+					"status varchar(16) not null, "+
+					"jsons int not null, "+
+					"found int not null, "+
+					"events int not null, "+
+					"updated_at {{ts}} not null, "+
+					"primary key(dt)"+
+					")",
+			),
+		)
+	}
+	if ctx.Index {
+		ExecSQLWithErr(c, ctx, "create index imported_hours_status_idx on gha_imported_hours(status)")
+	}
+
+	// Ranges of events added or changed by `gha2db` and `ghapi2db`, `gha2db_sync` recomputes only periods touched by them
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_dirty_ranges")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_dirty_ranges("+
+					"id {{pkauto}}, "+
+					"source varchar(16) not null, "+
+					"dt_from {{ts}} not null, "+
+					"dt_to {{ts}} not null, "+
+					"recorded_at {{ts}} not null, "+
+					"primary key(id)"+
+					")",
+			),
+		)
+	}
+
+	// Resumable backfills cursors, `ghapi2db` with GHA2DB_GHAPI_BACKFILL saves the last checked issue ID here
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_backfill_cursors")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_backfill_cursors("+
+					"source varchar(16) not null, "+
+					"last_id bigint not null, "+
+					"updated_at {{ts}} not null, "+
+					"completed_at {{ts}}, "+
+					"primary key(source)"+
+					")",
+			),
+		)
+	}
+
+	// Time series written by the "postgres" series sink (GHA2DB_SERIES_SINKS), can be converted to TimescaleDB hypertable
+	if ctx.Table {
+		ExecSQLWithErr(c, ctx, "drop table if exists gha_series_points")
+		ExecSQLWithErr(
+			c,
+			ctx,
+			CreateTable(
+				"gha_series_points("+
+					"series varchar(200) not null, "+
+					"time {{ts}} not null, "+
+					"tags jsonb not null, "+
+					"fields jsonb not null, "+
+					"primary key(series, time, tags)"+
+					")",
+			),
+		)
+	}
+
 	// Scripts to run on a given database
 	if ctx.Table {
 		ExecSQLWithErr(c, ctx, "drop table if exists gha_postprocess_scripts")
@@ -0,0 +1,144 @@
+package devstats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyncPhaseDef - single phase of `gha2db_sync` pipeline, defined in `sync.yaml` or per project in `projects.yaml` (`sync_phases:`)
+// Name - unique phase name, phases without Command and SQL are built-in (gha2db, get_repos, db2influx, ...)
+// Needs - list of phases that must run before this phase
+// Command - custom phase: command and its arguments to execute
+// SQL - custom phase: SQL file to execute on project's Postgres database using `runq`
+// Env - additional environment for phase's command(s)
+// Skip - do not run this phase (phases that need it still run)
+type SyncPhaseDef struct {
+	Name    string            `yaml:"name"`
+	Needs   []string          `yaml:"needs"`
+	Command []string          `yaml:"command"`
+	SQL     string            `yaml:"sql"`
+	Env     map[string]string `yaml:"env"`
+	Skip    bool              `yaml:"skip"`
+}
+
+// SyncPipeline - holds all phases of `gha2db_sync` (`sync.yaml` file)
+type SyncPipeline struct {
+	Phases []SyncPhaseDef `yaml:"phases"`
+}
+
+// Builtin - is this a built-in phase (implemented by `gha2db_sync` itself)?
+func (p *SyncPhaseDef) Builtin() bool {
+	return len(p.Command) == 0 && p.SQL == ""
+}
+
+// Merge - applies project's phases: phases with already defined names are modified (only given fields are changed)
+// phases with new names are added at the end
+func (p *SyncPipeline) Merge(phases []SyncPhaseDef) {
+	for _, phase := range phases {
+		found := false
+		for i := range p.Phases {
+			base := &p.Phases[i]
+			if base.Name != phase.Name {
+				continue
+			}
+			found = true
+			if phase.Needs != nil {
+				base.Needs = phase.Needs
+			}
+			if len(phase.Command) > 0 {
+				base.Command = phase.Command
+				base.SQL = ""
+			}
+			if phase.SQL != "" {
+				base.SQL = phase.SQL
+				base.Command = nil
+			}
+			if base.Env == nil && len(phase.Env) > 0 {
+				base.Env = make(map[string]string)
+			}
+			for k, v := range phase.Env {
+				base.Env[k] = v
+			}
+			if phase.Skip {
+				base.Skip = true
+			}
+			break
+		}
+		if !found {
+			p.Phases = append(p.Phases, phase)
+		}
+	}
+}
+
+// Order - returns phases in execution order: each phase after all phases it needs
+// Phases that don't depend on each other are kept in their definition order
+// Returns error for duplicate or empty names, unknown needed phases and dependency cycles
+func (p *SyncPipeline) Order() ([]SyncPhaseDef, error) {
+	index := make(map[string]int)
+	for i, phase := range p.Phases {
+		if phase.Name == "" {
+			return nil, fmt.Errorf("sync phase #%d has no name", i+1)
+		}
+		if _, ok := index[phase.Name]; ok {
+			return nil, fmt.Errorf("sync phase '%s' defined more than once", phase.Name)
+		}
+		if len(phase.Command) > 0 && phase.SQL != "" {
+			return nil, fmt.Errorf("sync phase '%s' can have either command or sql, not both", phase.Name)
+		}
+		index[phase.Name] = i
+	}
+	for _, phase := range p.Phases {
+		for _, need := range phase.Needs {
+			if _, ok := index[need]; !ok {
+				return nil, fmt.Errorf("sync phase '%s' needs unknown phase '%s'", phase.Name, need)
+			}
+		}
+	}
+	done := make(map[string]struct{})
+	var ordered []SyncPhaseDef
+	for len(ordered) < len(p.Phases) {
+		added := false
+		for _, phase := range p.Phases {
+			if _, ok := done[phase.Name]; ok {
+				continue
+			}
+			ready := true
+			for _, need := range phase.Needs {
+				if _, ok := done[need]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, phase)
+				done[phase.Name] = struct{}{}
+				added = true
+				break
+			}
+		}
+		if !added {
+			var left []string
+			for _, phase := range p.Phases {
+				if _, ok := done[phase.Name]; !ok {
+					left = append(left, phase.Name)
+				}
+			}
+			return nil, fmt.Errorf("sync phases dependency cycle: %s", strings.Join(left, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// SyncPhasesFrom - returns ordered phases starting from a given phase (used to restart failed sync from its failed phase)
+// Empty `from` returns all phases
+func SyncPhasesFrom(phases []SyncPhaseDef, from string) ([]SyncPhaseDef, error) {
+	if from == "" {
+		return phases, nil
+	}
+	for i, phase := range phases {
+		if phase.Name == from {
+			return phases[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("sync phase '%s' not found", from)
+}
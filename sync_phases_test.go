@@ -0,0 +1,161 @@
+package devstats
+
+import (
+	"reflect"
+	"testing"
+
+	lib "devstats"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// phaseNames - returns names of phases
+func phaseNames(phases []lib.SyncPhaseDef) (names []string) {
+	for _, phase := range phases {
+		names = append(names, phase.Name)
+	}
+	return
+}
+
+func TestSyncPipelineOrder(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		phases   []lib.SyncPhaseDef
+		expected []string
+	}{
+		{phases: []lib.SyncPhaseDef{}, expected: nil},
+		{
+			phases:   []lib.SyncPhaseDef{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			phases:   []lib.SyncPhaseDef{{Name: "a", Needs: []string{"c"}}, {Name: "b"}, {Name: "c", Needs: []string{"b"}}},
+			expected: []string{"b", "c", "a"},
+		},
+		{
+			phases: []lib.SyncPhaseDef{
+				{Name: "metrics", Needs: []string{"structure", "tags"}},
+				{Name: "gha2db"},
+				{Name: "tags", Needs: []string{"structure"}},
+				{Name: "structure", Needs: []string{"gha2db"}},
+				{Name: "export", Needs: []string{"gha2db"}},
+			},
+			expected: []string{"gha2db", "structure", "tags", "metrics", "export"},
+		},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		pipeline := lib.SyncPipeline{Phases: test.phases}
+		got, err := pipeline.Order()
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(phaseNames(got), test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, phaseNames(got))
+		}
+	}
+}
+
+func TestSyncPipelineOrderErrors(t *testing.T) {
+	var testCases = [][]lib.SyncPhaseDef{
+		{{Name: ""}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Needs: []string{"b"}}},
+		{{Name: "a", Needs: []string{"b"}}, {Name: "b", Needs: []string{"a"}}},
+		{{Name: "a", Needs: []string{"a"}}},
+		{{Name: "a", Command: []string{"ls"}, SQL: "a.sql"}},
+	}
+	// Execute test cases
+	for index, phases := range testCases {
+		pipeline := lib.SyncPipeline{Phases: phases}
+		_, err := pipeline.Order()
+		if err == nil {
+			t.Errorf("test number %d, expected error, got nil, test case: %+v", index+1, phases)
+		}
+	}
+}
+
+func TestSyncPipelineMerge(t *testing.T) {
+	pipeline := lib.SyncPipeline{
+		Phases: []lib.SyncPhaseDef{
+			{Name: "gha2db"},
+			{Name: "ghapi2db", Needs: []string{"gha2db"}},
+			{Name: "db2influx", Needs: []string{"ghapi2db"}, Env: map[string]string{"A": "1"}},
+		},
+	}
+	pipeline.Merge(
+		[]lib.SyncPhaseDef{
+			{Name: "ghapi2db", Skip: true},
+			{Name: "db2influx", Env: map[string]string{"B": "2"}},
+			{Name: "extra", SQL: "metrics/proj/extra.sql", Needs: []string{"gha2db"}},
+			{Name: "gha2db", Command: []string{"./my_import.sh"}},
+		},
+	)
+	expected := []lib.SyncPhaseDef{
+		{Name: "gha2db", Command: []string{"./my_import.sh"}},
+		{Name: "ghapi2db", Needs: []string{"gha2db"}, Skip: true},
+		{Name: "db2influx", Needs: []string{"ghapi2db"}, Env: map[string]string{"A": "1", "B": "2"}},
+		{Name: "extra", SQL: "metrics/proj/extra.sql", Needs: []string{"gha2db"}},
+	}
+	if !reflect.DeepEqual(pipeline.Phases, expected) {
+		t.Errorf("expected %+v, got %+v", expected, pipeline.Phases)
+	}
+	if pipeline.Phases[0].Builtin() || !pipeline.Phases[1].Builtin() || pipeline.Phases[3].Builtin() {
+		t.Errorf("unexpected built-in phases: %+v", pipeline.Phases)
+	}
+}
+
+func TestSyncPhasesFrom(t *testing.T) {
+	phases := []lib.SyncPhaseDef{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	// Test cases
+	var testCases = []struct {
+		from     string
+		expected []string
+	}{
+		{from: "", expected: []string{"a", "b", "c"}},
+		{from: "a", expected: []string{"a", "b", "c"}},
+		{from: "b", expected: []string{"b", "c"}},
+		{from: "c", expected: []string{"c"}},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got, err := lib.SyncPhasesFrom(phases, test.from)
+		if err != nil {
+			t.Errorf("test number %d, unexpected error: %v", index+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(phaseNames(got), test.expected) {
+			t.Errorf("test number %d, expected %v, got %v", index+1, test.expected, phaseNames(got))
+		}
+	}
+	_, err := lib.SyncPhasesFrom(phases, "d")
+	if err == nil {
+		t.Errorf("expected error for unknown phase")
+	}
+}
+
+func TestSyncYAML(t *testing.T) {
+	var pipeline lib.SyncPipeline
+	data, err := lib.ReadFile(&lib.Ctx{}, "sync.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = yaml.Unmarshal(data, &pipeline)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	phases, err := pipeline.Order()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"heal", "gha2db", "get_repos", "ghapi2db", "structure", "idb_tags", "annotations", "gaps", "db2influx"}
+	if !reflect.DeepEqual(phaseNames(phases), expected) {
+		t.Errorf("expected %v, got %v", expected, phaseNames(phases))
+	}
+	for _, phase := range phases {
+		if !phase.Builtin() {
+			t.Errorf("expected only built-in phases, got %+v", phase)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package devstats
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncRunning - sync run or phase is in progress (or the program crashed)
+const SyncRunning string = "running"
+
+// SyncOK - sync run or phase finished successfully
+const SyncOK string = "ok"
+
+// SyncFailed - sync run or phase failed
+const SyncFailed string = "failed"
+
+// SyncRun - single `devstats` or `gha2db_sync` run, recorded in `gha_sync_runs` table in `devstats` database
+// Run phases (gha2db, get_repos, db2influx for every metric, ...) are recorded in `gha_sync_phases` table
+// Recording is best effort (like logging to `gha_logs`): DB errors are reported on stderr and never stop the sync
+// Nil or disabled (GHA2DB_SKIPLOG) run can be used, it records nothing
+type SyncRun struct {
+	ID       int
+	mtx      sync.Mutex
+	ctx      Ctx
+	con      *sql.DB
+	proj     string
+	open     map[*SyncPhase]struct{}
+	finished bool
+}
+
+// SyncPhase - single phase of a sync run
+type SyncPhase struct {
+	run *SyncRun
+	id  int
+}
+
+// NewSyncRun - records start of program's sync run
+// When GHA2DB_SYNC_RUN_ID is set (by `devstats` calling `gha2db_sync`), it is saved as a parent run
+func NewSyncRun(ctx *Ctx, prog string) *SyncRun {
+	run := &SyncRun{proj: ctx.Project, open: make(map[*SyncPhase]struct{})}
+	if !ctx.LogToDB {
+		return run
+	}
+	run.ctx = *ctx
+	run.ctx.PgDB = Devstats
+	run.ctx.QOut = false
+	run.con = PgConn(&run.ctx)
+	var parentID interface{}
+	if ctx.SyncRunID > 0 {
+		parentID = ctx.SyncRunID
+	}
+	err := QueryRowSQL(
+		run.con,
+		&run.ctx,
+		"insert into gha_sync_runs(prog, proj, parent_id, started_at, status) "+NValues(5)+" returning id",
+		prog,
+		run.proj,
+		parentID,
+		time.Now(),
+		SyncRunning,
+	).Scan(&run.ID)
+	if err != nil {
+		run.disable(err)
+	}
+	return run
+}
+
+// disable - stops recording run after DB error
+func (r *SyncRun) disable(err error) {
+	fmt.Fprintf(os.Stderr, "%v: sync run history disabled: %v\n", time.Now(), err)
+	_ = r.con.Close()
+	r.con = nil
+}
+
+// exec - executes run history query, disables recording on error
+func (r *SyncRun) exec(query string, args ...interface{}) {
+	if r.con == nil {
+		return
+	}
+	_, err := ExecSQL(r.con, &r.ctx, query, args...)
+	if err != nil {
+		r.disable(err)
+	}
+}
+
+// StartPhase - records start of a run phase, `detail` is for example metric name for "db2influx" phase
+func (r *SyncRun) StartPhase(phase, detail string) *SyncPhase {
+	p := &SyncPhase{run: r}
+	if r == nil {
+		return p
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.open[p] = struct{}{}
+	if r.con == nil {
+		return p
+	}
+	err := QueryRowSQL(
+		r.con,
+		&r.ctx,
+		"insert into gha_sync_phases(run_id, proj, phase, detail, started_at, status) "+NValues(6)+" returning id",
+		r.ID,
+		r.proj,
+		phase,
+		TruncToBytes(detail, 200),
+		time.Now(),
+		SyncRunning,
+	).Scan(&p.id)
+	if err != nil {
+		r.disable(err)
+	}
+	return p
+}
+
+// Finish - records end of the phase, failed if err is not nil
+func (p *SyncPhase) Finish(err error) {
+	r := p.run
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.open[p]; !ok {
+		return
+	}
+	delete(r.open, p)
+	p.finish(err)
+}
+
+// finish - updates phase status, must be called with run's mutex locked
+func (p *SyncPhase) finish(err error) {
+	status, msg := syncStatus(err)
+	p.run.exec(
+		"update gha_sync_phases set finished_at = $1, status = $2, error = $3 where id = $4",
+		time.Now(),
+		status,
+		msg,
+		p.id,
+	)
+}
+
+// Finish - records end of the run, failed if err is not nil
+// All phases that are not finished yet are marked as failed with the same error
+// Only the first call has effect
+func (r *SyncRun) Finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.finished {
+		return
+	}
+	r.finished = true
+	if err == nil && len(r.open) > 0 {
+		err = fmt.Errorf("run finished with %d phase(s) not finished", len(r.open))
+	}
+	for p := range r.open {
+		p.finish(err)
+	}
+	r.open = make(map[*SyncPhase]struct{})
+	status, msg := syncStatus(err)
+	r.exec(
+		"update gha_sync_runs set finished_at = $1, status = $2, error = $3 where id = $4",
+		time.Now(),
+		status,
+		msg,
+		r.ID,
+	)
+	if r.con != nil {
+		_ = r.con.Close()
+		r.con = nil
+	}
+}
+
+// syncStatus - returns status and error message (or nil) to record
+func syncStatus(err error) (string, interface{}) {
+	if err != nil {
+		return SyncFailed, TruncToBytes(err.Error(), 0xffff)
+	}
+	return SyncOK, nil
+}
+
+// LastFailedSyncPhase - returns name of the first not finished phase of program's last run for ctx.Project
+// Returns "" when the last run was successful, when it failed outside of any phase or when there are no runs recorded
+// Runs left in "running" state (killed, for example by `devstats` project's timeout) are treated as failed
+// Must be called before NewSyncRun, so the current run is not taken into account
+func LastFailedSyncPhase(ctx *Ctx, prog string) string {
+	if !ctx.LogToDB {
+		return ""
+	}
+	dCtx := *ctx
+	dCtx.PgDB = Devstats
+	dCtx.QOut = false
+	con := PgConn(&dCtx)
+	defer func() { _ = con.Close() }()
+	var phase string
+	err := QueryRowSQL(
+		con,
+		&dCtx,
+		"select p.phase from gha_sync_phases p, "+
+			"(select id, status from gha_sync_runs where prog = $1 and proj = $2 order by id desc limit 1) r "+
+			"where p.run_id = r.id and r.status != $3 and p.status != $4 order by p.id limit 1",
+		prog,
+		ctx.Project,
+		SyncOK,
+		SyncOK,
+	).Scan(&phase)
+	if err == sql.ErrNoRows {
+		return ""
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: cannot get last failed sync phase: %v\n", time.Now(), err)
+		return ""
+	}
+	return phase
+}
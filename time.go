@@ -274,57 +274,51 @@ func ToYMDHDate(dt time.Time) string {
 
 // DescriblePeriodInHours - return string description of a time period given in hours
 func DescriblePeriodInHours(hrs float64) (desc string) {
+	return DescribePeriodInHoursWithPrecision(hrs, 1)
+}
+
+// periodUnits - units used to describe time periods, from the longest
+var periodUnits = []struct {
+	name string
+	secs int
+}{
+	{"week", 604800},
+	{"day", 86400},
+	{"hour", 3600},
+	{"minute", 60},
+	{"second", 1},
+}
+
+// DescribePeriodInHoursWithPrecision - return string description of a time period given in hours
+// Period is rounded to `precision` seconds, which should be one of units: 604800 (week), 86400 (day), 3600 (hour), 60 (minute) or 1 (second)
+func DescribePeriodInHoursWithPrecision(hrs float64, precision int) (desc string) {
 	secs := int((hrs * 3600.0) + 0.5)
 	if secs < 0 {
-		return "- " + DescriblePeriodInHours(-hrs)
+		return "- " + DescribePeriodInHoursWithPrecision(-hrs, precision)
 	}
+	secs = ((secs + precision/2) / precision) * precision
 	if secs == 0 {
-		return "zero"
-	}
-	weeks := secs / 604800
-	if weeks > 0 {
-		if weeks > 1 {
-			desc += strconv.Itoa(weeks) + " weeks "
-		} else {
-			desc += "1 week "
-		}
-		secs -= weeks * 604800
-	}
-	days := secs / 86400
-	if days > 0 {
-		if days > 1 {
-			desc += strconv.Itoa(days) + " days "
-		} else {
-			desc += "1 day "
+		if hrs > 0 && precision > 1 {
+			for _, unit := range periodUnits {
+				if unit.secs == precision {
+					return "less than 1 " + unit.name
+				}
+			}
 		}
-		secs -= days * 86400
+		return "zero"
 	}
-	hours := secs / 3600
-	if hours > 0 {
-		if hours > 1 {
-			desc += strconv.Itoa(hours) + " hours "
-		} else {
-			desc += "1 hour "
+	for _, unit := range periodUnits {
+		if unit.secs < precision {
+			break
 		}
-		secs -= hours * 3600
-	}
-	minutes := secs / 60
-	if minutes > 0 {
-		if minutes > 1 {
-			desc += strconv.Itoa(minutes) + " minutes "
-		} else {
-			desc += "1 minute "
+		n := secs / unit.secs
+		if n > 1 {
+			desc += strconv.Itoa(n) + " " + unit.name + "s "
+		} else if n == 1 {
+			desc += "1 " + unit.name + " "
 		}
-		secs -= minutes * 60
+		secs -= n * unit.secs
 	}
-	if secs > 0 {
-		if secs > 1 {
-			desc += strconv.Itoa(secs) + " seconds "
-		} else {
-			desc += "1 second "
-		}
-	}
-
 	return strings.TrimSpace(desc)
 }
 
@@ -205,6 +205,36 @@ func TestDescriblePeriodInHours(t *testing.T) {
 	}
 }
 
+func TestDescribePeriodInHoursWithPrecision(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		hours     float64
+		precision int
+		expected  string
+	}{
+		{hours: 0.3, precision: 60, expected: "18 minutes"},
+		{hours: 1.51, precision: 60, expected: "1 hour 31 minutes"},
+		{hours: 100.4, precision: 3600, expected: "4 days 4 hours"},
+		{hours: 0.3, precision: 3600, expected: "less than 1 hour"},
+		{hours: 100.6, precision: 86400, expected: "4 days"},
+		{hours: -30, precision: 86400, expected: "- 1 day"},
+		{hours: 0, precision: 86400, expected: "zero"},
+		{hours: 200, precision: 604800, expected: "1 week"},
+		{hours: 335.99, precision: 1, expected: "1 week 6 days 23 hours 59 minutes 24 seconds"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		expected := test.expected
+		got := lib.DescribePeriodInHoursWithPrecision(test.hours, test.precision)
+		if got != expected {
+			t.Errorf(
+				"test number %d, expected '%v' from %v hours (precision %d), got '%v'",
+				index+1, expected, test.hours, test.precision, got,
+			)
+		}
+	}
+}
+
 func TestHourStart(t *testing.T) {
 	// Test cases
 	ft := testlib.YMDHMS
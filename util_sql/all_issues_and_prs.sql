@@ -0,0 +1,25 @@
+select
+  sub.dup_repo_name,
+  sub.number,
+  sub.id,
+  sub.is_pull_request
+from (
+  select distinct on (i.id)
+    i.dup_repo_name,
+    i.number,
+    i.id,
+    i.is_pull_request
+  from
+    gha_issues i
+  where
+    i.id > {{after}}
+  order by
+    i.id,
+    i.updated_at desc,
+    i.event_id desc
+  ) sub
+order by
+  sub.id
+limit
+  {{lim}}
+;
@@ -0,0 +1,8 @@
+CREATE TABLE gha_backfill_cursors (
+  source character varying(16) NOT NULL,
+  last_id bigint NOT NULL,
+  updated_at timestamp without time zone NOT NULL,
+  completed_at timestamp without time zone
+);
+ALTER TABLE gha_backfill_cursors OWNER TO gha_admin;
+ALTER TABLE ONLY gha_backfill_cursors ADD CONSTRAINT gha_backfill_cursors_pkey PRIMARY KEY (source);
@@ -0,0 +1,32 @@
+CREATE TABLE gha_commits_files_lines (
+  sha character varying(40) NOT NULL,
+  path text NOT NULL,
+  old_path text,
+  status character varying(1) NOT NULL,
+  added integer,
+  removed integer,
+  is_binary boolean NOT NULL,
+  dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_commits_files_lines OWNER TO gha_admin;
+ALTER TABLE ONLY gha_commits_files_lines ADD CONSTRAINT gha_commits_files_lines_pkey PRIMARY KEY (sha, path);
+CREATE INDEX commits_files_lines_sha_idx ON gha_commits_files_lines USING btree (sha);
+CREATE INDEX commits_files_lines_path_idx ON gha_commits_files_lines USING btree (path);
+CREATE INDEX commits_files_lines_status_idx ON gha_commits_files_lines USING btree (status);
+CREATE INDEX commits_files_lines_dt_idx ON gha_commits_files_lines USING btree (dt);
+
+CREATE TABLE gha_commits_identities (
+  sha character varying(40) NOT NULL,
+  author_name character varying(160) NOT NULL,
+  author_email character varying(160) NOT NULL,
+  author_date timestamp without time zone NOT NULL,
+  committer_name character varying(160) NOT NULL,
+  committer_email character varying(160) NOT NULL,
+  committer_date timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_commits_identities OWNER TO gha_admin;
+ALTER TABLE ONLY gha_commits_identities ADD CONSTRAINT gha_commits_identities_pkey PRIMARY KEY (sha);
+CREATE INDEX commits_identities_author_email_idx ON gha_commits_identities USING btree (author_email);
+CREATE INDEX commits_identities_author_date_idx ON gha_commits_identities USING btree (author_date);
+CREATE INDEX commits_identities_committer_email_idx ON gha_commits_identities USING btree (committer_email);
+CREATE INDEX commits_identities_committer_date_idx ON gha_commits_identities USING btree (committer_date);
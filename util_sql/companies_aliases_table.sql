@@ -0,0 +1,8 @@
+CREATE TABLE gha_companies_aliases (
+  alias character varying(160) NOT NULL,
+  company_name character varying(160) NOT NULL
+);
+ALTER TABLE gha_companies_aliases OWNER TO gha_admin;
+ALTER TABLE ONLY gha_companies_aliases ADD CONSTRAINT gha_companies_aliases_pkey PRIMARY KEY (alias);
+CREATE INDEX companies_aliases_company_name_idx ON gha_companies_aliases USING btree (company_name);
+ALTER TABLE gha_actors_affiliations ADD COLUMN original_company_name character varying(160);
@@ -0,0 +1,72 @@
+CREATE TABLE gha_events_companies (
+  event_id bigint NOT NULL,
+  actor_id bigint NOT NULL,
+  company_name character varying(160) NOT NULL,
+  created_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_events_companies OWNER TO gha_admin;
+ALTER TABLE ONLY gha_events_companies ADD CONSTRAINT gha_events_companies_pkey PRIMARY KEY (event_id, company_name);
+CREATE INDEX events_companies_event_id_idx ON gha_events_companies USING btree (event_id);
+CREATE INDEX events_companies_actor_id_idx ON gha_events_companies USING btree (actor_id);
+CREATE INDEX events_companies_company_name_idx ON gha_events_companies USING btree (company_name);
+CREATE INDEX events_companies_created_at_idx ON gha_events_companies USING btree (created_at);
+
+CREATE TABLE gha_events_companies_hours (
+  dt timestamp without time zone NOT NULL,
+  imported_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_events_companies_hours OWNER TO gha_admin;
+ALTER TABLE ONLY gha_events_companies_hours ADD CONSTRAINT gha_events_companies_hours_pkey PRIMARY KEY (dt);
+
+CREATE TABLE gha_commits_companies (
+  sha character varying(40) NOT NULL,
+  actor_id bigint NOT NULL,
+  author_email character varying(160) NOT NULL,
+  company_name character varying(160) NOT NULL,
+  dt timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_commits_companies OWNER TO gha_admin;
+ALTER TABLE ONLY gha_commits_companies ADD CONSTRAINT gha_commits_companies_pkey PRIMARY KEY (sha, actor_id, company_name);
+CREATE INDEX commits_companies_sha_idx ON gha_commits_companies USING btree (sha);
+CREATE INDEX commits_companies_actor_id_idx ON gha_commits_companies USING btree (actor_id);
+CREATE INDEX commits_companies_author_email_idx ON gha_commits_companies USING btree (author_email);
+CREATE INDEX commits_companies_company_name_idx ON gha_commits_companies USING btree (company_name);
+CREATE INDEX commits_companies_dt_idx ON gha_commits_companies USING btree (dt);
+
+insert into gha_events_companies(event_id, actor_id, company_name, created_at)
+select
+  ev.id, ev.actor_id, aa.company_name, ev.created_at
+from
+  gha_events ev,
+  gha_actors_affiliations aa
+where
+  ev.actor_id = aa.actor_id
+  and aa.dt_from <= ev.created_at
+  and aa.dt_to > ev.created_at
+  and ev.type != 'ArtificialEvent'
+on conflict do nothing;
+
+insert into gha_events_companies_hours(dt, imported_at)
+select
+  dt, updated_at
+from
+  gha_imported_hours
+where
+  status in ('ok', 'failed')
+on conflict do nothing;
+
+insert into gha_postprocess_scripts(ord, path) select 4, 'util_sql/postprocess_companies.sql' on conflict do nothing;
+
+insert into gha_commits_companies(sha, actor_id, author_email, company_name, dt)
+select
+  ci.sha, ae.actor_id, ci.author_email, aa.company_name, ci.author_date
+from
+  gha_commits_identities ci,
+  gha_actors_emails ae,
+  gha_actors_affiliations aa
+where
+  ci.author_email = ae.email
+  and ae.actor_id = aa.actor_id
+  and aa.dt_from <= ci.author_date
+  and aa.dt_to > ci.author_date
+on conflict do nothing;
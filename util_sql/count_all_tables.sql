@@ -6,9 +6,15 @@ select 'branches' as name, count(*) as count_value from gha_branches union
 select 'comments' as name, count(*) as count_value from gha_comments union
 select 'commits' as name, count(*) as count_value from gha_commits union
 select 'commits files' as name, count(*) as count_value from gha_commits_files union
+select 'commits files lines' as name, count(*) as count_value from gha_commits_files_lines union
+select 'commits identities' as name, count(*) as count_value from gha_commits_identities union
+select 'commits companies' as name, count(*) as count_value from gha_commits_companies union
 select 'companies' as name, count(*) as count_value from gha_companies union
+select 'companies aliases' as name, count(*) as count_value from gha_companies_aliases union
 select 'events' as name, count(*) as count_value from gha_events union
 select 'events commits file' as name, count(*) as count_value from gha_events_commits_files union
+select 'events companies' as name, count(*) as count_value from gha_events_companies union
+select 'events companies hours' as name, count(*) as count_value from gha_events_companies_hours union
 select 'forkees' as name, count(*) as count_value from gha_forkees union
 select 'issues' as name, count(*) as count_value from gha_issues union
 select 'issue assignees' as name, count(*) as count_value from gha_issues_assignees union
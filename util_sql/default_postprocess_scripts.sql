@@ -1,3 +1,4 @@
 insert into gha_postprocess_scripts(ord, path) select 1, 'util_sql/postprocess_texts.sql' on conflict do nothing;
 insert into gha_postprocess_scripts(ord, path) select 2, 'util_sql/postprocess_labels.sql' on conflict do nothing;
 insert into gha_postprocess_scripts(ord, path) select 3, 'util_sql/postprocess_issues_prs.sql' on conflict do nothing;
+insert into gha_postprocess_scripts(ord, path) select 4, 'util_sql/postprocess_companies.sql' on conflict do nothing;
@@ -1,4 +1,7 @@
 delete from gha_issues_labels where event_id > 281474976710656;
 delete from gha_issues where event_id > 281474976710656;
+delete from gha_pull_requests_requested_reviewers where event_id > 281474976710656;
+delete from gha_pull_requests_assignees where event_id > 281474976710656;
+delete from gha_pull_requests where event_id > 281474976710656;
 delete from gha_payloads where event_id > 281474976710656;
 delete from gha_events where id > 281474976710656;
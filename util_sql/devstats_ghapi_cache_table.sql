@@ -0,0 +1,11 @@
+CREATE TABLE gha_ghapi_cache (
+    key character varying(40) NOT NULL,
+    url text NOT NULL,
+    etag text NOT NULL,
+    last_modified text NOT NULL,
+    header text NOT NULL,
+    body bytea NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_ghapi_cache OWNER TO gha_admin;
+ALTER TABLE ONLY gha_ghapi_cache ADD CONSTRAINT gha_ghapi_cache_pkey PRIMARY KEY (key);
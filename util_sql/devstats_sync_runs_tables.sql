@@ -0,0 +1,52 @@
+CREATE TABLE gha_sync_runs (
+    id integer NOT NULL,
+    prog character varying(32) NOT NULL,
+    proj character varying(32) NOT NULL,
+    parent_id integer,
+    started_at timestamp without time zone NOT NULL,
+    finished_at timestamp without time zone,
+    status character varying(16) NOT NULL,
+    error text
+);
+ALTER TABLE gha_sync_runs OWNER TO gha_admin;
+CREATE SEQUENCE gha_sync_runs_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+ALTER TABLE gha_sync_runs_id_seq OWNER TO gha_admin;
+ALTER SEQUENCE gha_sync_runs_id_seq OWNED BY gha_sync_runs.id;
+ALTER TABLE ONLY gha_sync_runs ALTER COLUMN id SET DEFAULT nextval('gha_sync_runs_id_seq'::regclass);
+ALTER TABLE ONLY gha_sync_runs ADD CONSTRAINT gha_sync_runs_pkey PRIMARY KEY (id);
+CREATE INDEX sync_runs_proj_idx ON gha_sync_runs USING btree (proj);
+CREATE INDEX sync_runs_parent_id_idx ON gha_sync_runs USING btree (parent_id);
+CREATE INDEX sync_runs_started_at_idx ON gha_sync_runs USING btree (started_at);
+CREATE INDEX sync_runs_status_idx ON gha_sync_runs USING btree (status);
+CREATE TABLE gha_sync_phases (
+    id integer NOT NULL,
+    run_id integer NOT NULL,
+    proj character varying(32) NOT NULL,
+    phase character varying(32) NOT NULL,
+    detail character varying(200) NOT NULL,
+    started_at timestamp without time zone NOT NULL,
+    finished_at timestamp without time zone,
+    status character varying(16) NOT NULL,
+    error text
+);
+ALTER TABLE gha_sync_phases OWNER TO gha_admin;
+CREATE SEQUENCE gha_sync_phases_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+ALTER TABLE gha_sync_phases_id_seq OWNER TO gha_admin;
+ALTER SEQUENCE gha_sync_phases_id_seq OWNED BY gha_sync_phases.id;
+ALTER TABLE ONLY gha_sync_phases ALTER COLUMN id SET DEFAULT nextval('gha_sync_phases_id_seq'::regclass);
+ALTER TABLE ONLY gha_sync_phases ADD CONSTRAINT gha_sync_phases_pkey PRIMARY KEY (id);
+CREATE INDEX sync_phases_run_id_idx ON gha_sync_phases USING btree (run_id);
+CREATE INDEX sync_phases_proj_idx ON gha_sync_phases USING btree (proj);
+CREATE INDEX sync_phases_phase_idx ON gha_sync_phases USING btree (phase);
+CREATE INDEX sync_phases_started_at_idx ON gha_sync_phases USING btree (started_at);
+CREATE INDEX sync_phases_status_idx ON gha_sync_phases USING btree (status);
@@ -0,0 +1,13 @@
+CREATE TABLE gha_dirty_ranges (
+  id integer NOT NULL,
+  source character varying(16) NOT NULL,
+  dt_from timestamp without time zone NOT NULL,
+  dt_to timestamp without time zone NOT NULL,
+  recorded_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_dirty_ranges OWNER TO gha_admin;
+CREATE SEQUENCE gha_dirty_ranges_id_seq START WITH 1 INCREMENT BY 1 NO MINVALUE NO MAXVALUE CACHE 1;
+ALTER TABLE gha_dirty_ranges_id_seq OWNER TO gha_admin;
+ALTER SEQUENCE gha_dirty_ranges_id_seq OWNED BY gha_dirty_ranges.id;
+ALTER TABLE ONLY gha_dirty_ranges ALTER COLUMN id SET DEFAULT nextval('gha_dirty_ranges_id_seq'::regclass);
+ALTER TABLE ONLY gha_dirty_ranges ADD CONSTRAINT gha_dirty_ranges_pkey PRIMARY KEY (id);
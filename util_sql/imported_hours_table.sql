@@ -0,0 +1,11 @@
+CREATE TABLE gha_imported_hours (
+  dt timestamp without time zone NOT NULL,
+  status character varying(16) NOT NULL,
+  jsons integer NOT NULL,
+  found integer NOT NULL,
+  events integer NOT NULL,
+  updated_at timestamp without time zone NOT NULL
+);
+ALTER TABLE gha_imported_hours OWNER TO gha_admin;
+ALTER TABLE ONLY gha_imported_hours ADD CONSTRAINT gha_imported_hours_pkey PRIMARY KEY (dt);
+CREATE INDEX imported_hours_status_idx ON gha_imported_hours USING btree (status);
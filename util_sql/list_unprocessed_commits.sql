@@ -13,10 +13,10 @@ from (
   union select distinct merge_commit_sha as sha, dup_repo_name as repo from gha_pull_requests where merge_commit_sha is not null
   ) sub
 left join gha_skip_commits sc on sub.sha = sc.sha
-left join gha_commits_files cf on sub.sha = cf.sha
+left join gha_commits_identities ci on sub.sha = ci.sha
 where
   sc.sha is null
-  and cf.sha is null
+  and ci.sha is null
   and sub.sha is not null
   and sub.sha <> ''
   and sub.repo like '%/%'
@@ -0,0 +1,39 @@
+with hours as (
+  select
+    ih.dt,
+    ih.updated_at
+  from
+    gha_imported_hours ih
+  left join
+    gha_events_companies_hours ch
+  on
+    ch.dt = ih.dt
+  where
+    ih.status in ('ok', 'failed')
+    and (ch.dt is null or ch.imported_at != ih.updated_at)
+), attributed as (
+  insert into gha_events_companies(
+    event_id, actor_id, company_name, created_at
+  )
+  select
+    ev.id, ev.actor_id, aa.company_name, ev.created_at
+  from
+    hours h,
+    gha_events ev,
+    gha_actors_affiliations aa
+  where
+    ev.created_at >= h.dt
+    and ev.created_at < h.dt + '1 hour'::interval
+    and ev.actor_id = aa.actor_id
+    and aa.dt_from <= ev.created_at
+    and aa.dt_to > ev.created_at
+    and ev.type != 'ArtificialEvent'
+  on conflict do nothing
+)
+insert into gha_events_companies_hours(dt, imported_at)
+select
+  dt, updated_at
+from
+  hours
+on conflict(dt) do update set imported_at = excluded.imported_at
+;
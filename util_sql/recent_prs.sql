@@ -0,0 +1,26 @@
+select distinct on (pr.id)
+  pr.dup_repo_name,
+  pr.number,
+  pr.id
+from
+  gha_pull_requests pr
+where
+  pr.id in (
+    select inn.id
+    from
+      gha_pull_requests inn
+    where
+      inn.updated_at >= now() - '{{period}}'::interval
+    union select ipr.pull_request_id
+    from
+      gha_issues_pull_requests ipr,
+      gha_issues i
+    where
+      ipr.issue_id = i.id
+      and i.updated_at >= now() - '{{period}}'::interval
+  )
+order by
+  pr.id,
+  pr.updated_at desc,
+  pr.event_id desc
+;
@@ -0,0 +1,50 @@
+CREATE TABLE gha_reviews (
+  id bigint NOT NULL,
+  pull_request_id bigint NOT NULL,
+  number integer NOT NULL,
+  user_id bigint,
+  state character varying(20) NOT NULL,
+  body text,
+  commit_id character varying(40),
+  submitted_at timestamp without time zone,
+  synced_at timestamp without time zone NOT NULL,
+  dup_repo_id bigint NOT NULL,
+  dup_repo_name character varying(160) NOT NULL,
+  dup_user_login character varying(120) NOT NULL
+);
+ALTER TABLE gha_reviews OWNER TO gha_admin;
+ALTER TABLE ONLY gha_reviews ADD CONSTRAINT gha_reviews_pkey PRIMARY KEY (id);
+CREATE INDEX reviews_pull_request_id_idx ON gha_reviews USING btree (pull_request_id);
+CREATE INDEX reviews_user_id_idx ON gha_reviews USING btree (user_id);
+CREATE INDEX reviews_state_idx ON gha_reviews USING btree (state);
+CREATE INDEX reviews_submitted_at_idx ON gha_reviews USING btree (submitted_at);
+CREATE INDEX reviews_dup_repo_id_idx ON gha_reviews USING btree (dup_repo_id);
+CREATE INDEX reviews_dup_repo_name_idx ON gha_reviews USING btree (dup_repo_name);
+CREATE INDEX reviews_dup_user_login_idx ON gha_reviews USING btree (dup_user_login);
+CREATE TABLE gha_reviews_comments (
+  id bigint NOT NULL,
+  pull_request_id bigint NOT NULL,
+  number integer NOT NULL,
+  user_id bigint,
+  body text,
+  path text,
+  "position" integer,
+  original_position integer,
+  commit_id character varying(40),
+  original_commit_id character varying(40),
+  in_reply_to bigint,
+  created_at timestamp without time zone NOT NULL,
+  updated_at timestamp without time zone NOT NULL,
+  synced_at timestamp without time zone NOT NULL,
+  dup_repo_id bigint NOT NULL,
+  dup_repo_name character varying(160) NOT NULL,
+  dup_user_login character varying(120) NOT NULL
+);
+ALTER TABLE gha_reviews_comments OWNER TO gha_admin;
+ALTER TABLE ONLY gha_reviews_comments ADD CONSTRAINT gha_reviews_comments_pkey PRIMARY KEY (id);
+CREATE INDEX reviews_comments_pull_request_id_idx ON gha_reviews_comments USING btree (pull_request_id);
+CREATE INDEX reviews_comments_user_id_idx ON gha_reviews_comments USING btree (user_id);
+CREATE INDEX reviews_comments_created_at_idx ON gha_reviews_comments USING btree (created_at);
+CREATE INDEX reviews_comments_dup_repo_id_idx ON gha_reviews_comments USING btree (dup_repo_id);
+CREATE INDEX reviews_comments_dup_repo_name_idx ON gha_reviews_comments USING btree (dup_repo_name);
+CREATE INDEX reviews_comments_dup_user_login_idx ON gha_reviews_comments USING btree (dup_user_login);
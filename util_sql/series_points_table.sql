@@ -0,0 +1,10 @@
+CREATE TABLE gha_series_points (
+  series character varying(200) NOT NULL,
+  "time" timestamp without time zone NOT NULL,
+  tags jsonb NOT NULL,
+  fields jsonb NOT NULL
+);
+ALTER TABLE gha_series_points OWNER TO gha_admin;
+ALTER TABLE ONLY gha_series_points ADD CONSTRAINT gha_series_points_pkey PRIMARY KEY (series, "time", tags);
+-- With TimescaleDB extension installed (create extension if not exists timescaledb) the table can be converted to a hypertable:
+-- select create_hypertable('gha_series_points', 'time', migrate_data => true);
@@ -0,0 +1,36 @@
+select
+  to_char(p.started_at, 'YYYY-MM-DD HH24:MI:SS') as started_at,
+  p.proj,
+  r.prog,
+  p.phase,
+  p.detail,
+  case when p.finished_at is null then '' else round(extract(epoch from p.finished_at - p.started_at)::numeric, 1)::text end as seconds,
+  coalesce(left(p.error, 160), '') as error
+from
+  gha_sync_phases p,
+  gha_sync_runs r
+where
+  p.run_id = r.id
+  and p.status = 'failed'
+  and p.started_at >= now() - '{{days}} days'::interval
+  and ('{{project}}' = '' or p.proj = '{{project}}')
+union select
+  to_char(r.started_at, 'YYYY-MM-DD HH24:MI:SS') as started_at,
+  r.proj,
+  r.prog,
+  '' as phase,
+  '' as detail,
+  case when r.finished_at is null then '' else round(extract(epoch from r.finished_at - r.started_at)::numeric, 1)::text end as seconds,
+  coalesce(left(r.error, 160), '') as error
+from
+  gha_sync_runs r
+where
+  r.status = 'failed'
+  and r.started_at >= now() - '{{days}} days'::interval
+  and ('{{project}}' = '' or r.proj = '{{project}}')
+  and not exists (select 1 from gha_sync_phases p where p.run_id = r.id and p.status = 'failed')
+order by
+  started_at desc,
+  proj asc
+limit 50
+;
@@ -0,0 +1,23 @@
+select
+  proj,
+  detail as metric,
+  count(*) as runs,
+  round(avg(extract(epoch from finished_at - started_at))::numeric, 1) as avg_seconds,
+  round(max(extract(epoch from finished_at - started_at))::numeric, 1) as max_seconds,
+  round(sum(extract(epoch from finished_at - started_at))::numeric, 1) as total_seconds
+from
+  gha_sync_phases
+where
+  phase = 'db2influx'
+  and status = 'ok'
+  and started_at >= now() - '{{days}} days'::interval
+  and ('{{project}}' = '' or proj = '{{project}}')
+group by
+  proj,
+  detail
+order by
+  avg_seconds desc,
+  proj asc,
+  metric asc
+limit 50
+;
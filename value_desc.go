@@ -0,0 +1,149 @@
+package devstats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValueDescFunc - returns string description of a metric value, stored in "descr" field next to "value"
+type ValueDescFunc func(value float64) string
+
+// Value description functions (`desc:` in metrics.yaml)
+const (
+	TimeDiffAsString string = "time_diff_as_string"
+	TimeDiffMinutes  string = "time_diff_minutes"
+	TimeDiffHours    string = "time_diff_hours"
+	TimeDiffDays     string = "time_diff_days"
+	TimeDiffWeeks    string = "time_diff_weeks"
+	Percent          string = "percent"
+	RatioAsPercent   string = "ratio_as_percent"
+	FileSize         string = "file_size"
+	SICount          string = "si_count"
+	Ordinal          string = "ordinal"
+)
+
+// ValueDescFuncs - all value description functions
+var ValueDescFuncs = map[string]ValueDescFunc{
+	TimeDiffAsString: DescriblePeriodInHours,
+	TimeDiffMinutes:  func(hrs float64) string { return DescribePeriodInHoursWithPrecision(hrs, 60) },
+	TimeDiffHours:    func(hrs float64) string { return DescribePeriodInHoursWithPrecision(hrs, 3600) },
+	TimeDiffDays:     func(hrs float64) string { return DescribePeriodInHoursWithPrecision(hrs, 86400) },
+	TimeDiffWeeks:    func(hrs float64) string { return DescribePeriodInHoursWithPrecision(hrs, 604800) },
+	Percent:          DescribePercent,
+	RatioAsPercent:   func(ratio float64) string { return DescribePercent(ratio * 100.0) },
+	FileSize:         DescribeFileSize,
+	SICount:          DescribeSICount,
+	Ordinal:          DescribeOrdinal,
+}
+
+// ValueDescs - parses `desc:` value from metrics.yaml
+// Single function describes all value columns, functions separated by ";" describe consecutive value columns
+// Empty function means that column has no description, for example "ordinal;;time_diff_days"
+func ValueDescs(desc string) (descs []ValueDescFunc, err error) {
+	if desc == "" {
+		return
+	}
+	errs := []string{}
+	for _, name := range strings.Split(desc, ";") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			descs = append(descs, nil)
+			continue
+		}
+		fun, ok := ValueDescFuncs[name]
+		if !ok {
+			errs = append(errs, "unknown value description function '"+name+"'")
+		}
+		descs = append(descs, fun)
+	}
+	if len(errs) > 0 {
+		err = fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return
+}
+
+// DescribeValue - returns description of value from value column `column` (0 based), false if column has no description
+func DescribeValue(descs []ValueDescFunc, column int, value float64) (string, bool) {
+	if len(descs) == 1 {
+		column = 0
+	}
+	if column >= len(descs) || descs[column] == nil {
+		return "", false
+	}
+	return descs[column](value), true
+}
+
+// formatSignificant - formats value with 3 significant digits (at least all integer digits), without trailing zeros
+func formatSignificant(value float64) string {
+	decimals := 0
+	abs := math.Abs(value)
+	if abs < 10.0 {
+		decimals = 2
+	} else if abs < 100.0 {
+		decimals = 1
+	}
+	str := strconv.FormatFloat(value, 'f', decimals, 64)
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+	}
+	if str == "-0" {
+		str = "0"
+	}
+	return str
+}
+
+// describeWithUnits - scales value by `base` and adds unit suffix, like 1500 -> 1.5k
+func describeWithUnits(value, base float64, units []string) string {
+	if value < 0 {
+		return "-" + describeWithUnits(-value, base, units)
+	}
+	i := 0
+	for value >= base && i < len(units)-1 {
+		value /= base
+		i++
+	}
+	// Rounding can reach the base, like 999.9 -> "1000"
+	if value >= base-0.5 && i < len(units)-1 {
+		value /= base
+		i++
+	}
+	return formatSignificant(value) + units[i]
+}
+
+// DescribePercent - returns percent description, like 12.345 -> "12.3%"
+func DescribePercent(value float64) string {
+	return formatSignificant(value) + "%"
+}
+
+// DescribeFileSize - returns size in bytes description using binary units, like 1536 -> "1.5 KiB"
+func DescribeFileSize(value float64) string {
+	return describeWithUnits(value, 1024.0, []string{" B", " KiB", " MiB", " GiB", " TiB", " PiB"})
+}
+
+// DescribeSICount - returns count description using SI suffixes, like 1234567 -> "1.23M"
+func DescribeSICount(value float64) string {
+	return describeWithUnits(value, 1000.0, []string{"", "k", "M", "G", "T", "P"})
+}
+
+// DescribeOrdinal - returns rank description, value is rounded, like 1 -> "1st", 12 -> "12th", 23 -> "23rd"
+func DescribeOrdinal(value float64) string {
+	n := int64(math.Floor(value + 0.5))
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	suffix := "th"
+	if abs%100 < 11 || abs%100 > 13 {
+		switch abs % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.FormatInt(n, 10) + suffix
+}
@@ -0,0 +1,99 @@
+package devstats
+
+import (
+	"testing"
+
+	lib "devstats"
+)
+
+func TestValueDescFuncs(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		desc     string
+		value    float64
+		expected string
+	}{
+		{desc: lib.TimeDiffAsString, value: 100, expected: "4 days 4 hours"},
+		{desc: lib.TimeDiffMinutes, value: 1.51, expected: "1 hour 31 minutes"},
+		{desc: lib.TimeDiffHours, value: 100.4, expected: "4 days 4 hours"},
+		{desc: lib.TimeDiffHours, value: 0.3, expected: "less than 1 hour"},
+		{desc: lib.TimeDiffDays, value: 100.6, expected: "4 days"},
+		{desc: lib.TimeDiffWeeks, value: 200, expected: "1 week"},
+		{desc: lib.Percent, value: 12.345, expected: "12.3%"},
+		{desc: lib.Percent, value: 0.1234, expected: "0.12%"},
+		{desc: lib.Percent, value: 99.99, expected: "100%"},
+		{desc: lib.Percent, value: 0, expected: "0%"},
+		{desc: lib.RatioAsPercent, value: 0.5, expected: "50%"},
+		{desc: lib.RatioAsPercent, value: 0.12345, expected: "12.3%"},
+		{desc: lib.FileSize, value: 0, expected: "0 B"},
+		{desc: lib.FileSize, value: 500, expected: "500 B"},
+		{desc: lib.FileSize, value: 1536, expected: "1.5 KiB"},
+		{desc: lib.FileSize, value: 1023.9, expected: "1 KiB"},
+		{desc: lib.FileSize, value: 1048576, expected: "1 MiB"},
+		{desc: lib.FileSize, value: 5.5 * 1024 * 1024 * 1024, expected: "5.5 GiB"},
+		{desc: lib.SICount, value: 999, expected: "999"},
+		{desc: lib.SICount, value: 1000, expected: "1k"},
+		{desc: lib.SICount, value: 1234, expected: "1.23k"},
+		{desc: lib.SICount, value: 15300, expected: "15.3k"},
+		{desc: lib.SICount, value: 999999, expected: "1M"},
+		{desc: lib.SICount, value: 1234567, expected: "1.23M"},
+		{desc: lib.SICount, value: -2500, expected: "-2.5k"},
+		{desc: lib.SICount, value: 0.5, expected: "0.5"},
+		{desc: lib.Ordinal, value: 0, expected: "0th"},
+		{desc: lib.Ordinal, value: 1, expected: "1st"},
+		{desc: lib.Ordinal, value: 2, expected: "2nd"},
+		{desc: lib.Ordinal, value: 2.6, expected: "3rd"},
+		{desc: lib.Ordinal, value: 4, expected: "4th"},
+		{desc: lib.Ordinal, value: 11, expected: "11th"},
+		{desc: lib.Ordinal, value: 12, expected: "12th"},
+		{desc: lib.Ordinal, value: 13, expected: "13th"},
+		{desc: lib.Ordinal, value: 21, expected: "21st"},
+		{desc: lib.Ordinal, value: 22, expected: "22nd"},
+		{desc: lib.Ordinal, value: 101, expected: "101st"},
+		{desc: lib.Ordinal, value: 111, expected: "111th"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		got := lib.ValueDescFuncs[test.desc](test.value)
+		if got != test.expected {
+			t.Errorf("test number %d, expected '%v' from %s(%v), got '%v'", index+1, test.expected, test.desc, test.value, got)
+		}
+	}
+}
+
+func TestValueDescs(t *testing.T) {
+	// Test cases
+	var testCases = []struct {
+		desc     string
+		values   []float64
+		expected []string
+		err      string
+	}{
+		{desc: "", values: []float64{1, 2}, expected: []string{"-", "-"}},
+		{desc: "ordinal", values: []float64{1, 2, 3}, expected: []string{"1st", "2nd", "3rd"}},
+		{desc: "ordinal;;percent", values: []float64{1, 2, 3, 4}, expected: []string{"1st", "-", "3%", "-"}},
+		{desc: " si_count ; file_size", values: []float64{1500, 1536}, expected: []string{"1.5k", "1.5 KiB"}},
+		{desc: "ordinal;hours;x", err: "unknown value description function 'hours', unknown value description function 'x'"},
+	}
+	// Execute test cases
+	for index, test := range testCases {
+		descs, err := lib.ValueDescs(test.desc)
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != test.err {
+			t.Errorf("test number %d, expected error '%v', got '%v'", index+1, test.err, gotErr)
+			continue
+		}
+		for column, value := range test.values {
+			got, ok := lib.DescribeValue(descs, column, value)
+			if !ok {
+				got = "-"
+			}
+			if got != test.expected[column] {
+				t.Errorf("test number %d, column %d, expected '%v', got '%v'", index+1, column, test.expected[column], got)
+			}
+		}
+	}
+}